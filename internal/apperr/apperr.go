@@ -0,0 +1,87 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalid              = errors.New("invalid input")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrUnprocessable        = errors.New("unprocessable")
+	ErrUnsupportedType      = errors.New("unsupported media type")
+	ErrTooLarge             = errors.New("payload too large")
+	ErrPrecondition         = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrFailedDependency     = errors.New("failed dependency")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrUnavailable          = errors.New("unavailable")
+	ErrTimeout              = errors.New("timeout")
+	ErrInternal             = errors.New("internal error")
+)
+
+// Error is a domain error carrying a stable machine-readable code. Kind is
+// one of the sentinel errors above and decides how the error is reported.
+type Error struct {
+	Kind   error
+	Code   string
+	Title  string
+	Detail string
+	Fields map[string]string
+	Err    error
+}
+
+func New(kind error, code, title, detail string) *Error {
+	return &Error{Kind: kind, Code: code, Title: title, Detail: detail}
+}
+
+func Wrap(kind error, code, title string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Title: title, Detail: err.Error(), Err: err}
+}
+
+func Validation(fields map[string]string) *Error {
+	return &Error{
+		Kind:   ErrInvalid,
+		Code:   CodeValidationFailed,
+		Title:  "Validation failed",
+		Detail: "one or more fields are invalid",
+		Fields: fields,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Is matches errors by code so that sentinel values such as
+// ErrTaskNotFound can be compared against freshly built instances.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail returns a copy of e with a request-specific detail message.
+func (e *Error) WithDetail(detail string) *Error {
+	cp := *e
+	cp.Detail = detail
+	return &cp
+}
+
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
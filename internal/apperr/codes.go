@@ -0,0 +1,88 @@
+package apperr
+
+// Stable error codes exposed to clients in the "code" member of problem
+// responses. Never rename an existing code; add a new one instead.
+const (
+	CodeInvalidRequestBody    = "invalid_request_body"
+	CodeValidationFailed      = "validation_failed"
+	CodeInvalidTaskID         = "invalid_task_id"
+	CodeInvalidPatch          = "invalid_patch"
+	CodePatchTestFailed       = "patch_test_failed"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodeInvalidIdempotencyKey = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+	CodeIdempotencyInProgress = "idempotency_request_in_progress"
+	CodeRequestTooLarge       = "request_too_large"
+	CodeBatchAborted          = "batch_aborted"
+	CodeTaskNotFound          = "task_not_found"
+	CodeInvalidRevision       = "invalid_revision"
+	CodeRevisionNotFound      = "revision_not_found"
+	CodeUndoTokenInvalid      = "undo_token_invalid"
+	CodeUndoConflict          = "undo_conflict"
+	CodeInvalidLabelID        = "invalid_label_id"
+	CodeLabelNotFound         = "label_not_found"
+	CodeLabelExists           = "label_exists"
+	CodeUnknownLabel          = "unknown_label"
+	CodeInvalidProjectID      = "invalid_project_id"
+	CodeProjectNotFound       = "project_not_found"
+	CodeUnknownProject        = "unknown_project"
+	CodeProjectArchived       = "project_archived"
+	CodeProjectExists         = "project_exists"
+	CodeInboxProtected        = "inbox_protected"
+	CodeUnknownParent         = "unknown_parent"
+	CodeParentCycle           = "parent_cycle"
+	CodeOpenSubtasks          = "open_subtasks"
+	CodeUnknownDependency     = "unknown_dependency"
+	CodeDependencyCycle       = "dependency_cycle"
+	CodeNotRecurring          = "not_recurring"
+	CodeInvalidScope          = "invalid_scope"
+	CodeInvalidCount          = "invalid_count"
+	CodeUnknownAnchor         = "unknown_anchor"
+	CodeOtherList             = "different_list"
+	CodeInvalidChecklistItem  = "invalid_checklist_item_id"
+	CodeChecklistItemNotFound = "checklist_item_not_found"
+	CodeInvalidCommentID      = "invalid_comment_id"
+	CodeCommentNotFound       = "comment_not_found"
+	CodeCommentForbidden      = "comment_forbidden"
+	CodeInvalidPagination     = "invalid_pagination"
+	CodeInvalidAttachmentID   = "invalid_attachment_id"
+	CodeAttachmentNotFound    = "attachment_not_found"
+	CodeAttachmentTooLarge    = "attachment_too_large"
+	CodeAttachmentType        = "unsupported_attachment_type"
+	CodeAttachmentsDisabled   = "attachments_disabled"
+	CodeInvalidFieldID        = "invalid_custom_field_id"
+	CodeFieldNotFound         = "custom_field_not_found"
+	CodeFieldExists           = "custom_field_exists"
+	CodeFieldTypeChange       = "custom_field_type_change"
+	CodeUnknownField          = "unknown_custom_field"
+	CodeInvalidSort           = "invalid_sort"
+	CodeInvalidTemplateID     = "invalid_template_id"
+	CodeTemplateNotFound      = "template_not_found"
+	CodeTemplateExists        = "template_exists"
+	CodeInvalidViewID         = "invalid_view_id"
+	CodeViewNotFound          = "view_not_found"
+	CodeViewExists            = "view_exists"
+	CodeInvalidQuery          = "invalid_query"
+	CodeInvalidBoardID        = "invalid_board_id"
+	CodeBoardNotFound         = "board_not_found"
+	CodeBoardExists           = "board_exists"
+	CodeColumnNotFound        = "board_column_not_found"
+	CodeCardNotOnBoard        = "card_not_on_board"
+	CodeColumnUnreachable     = "board_column_unreachable"
+	CodeWIPLimitExceeded      = "wip_limit_exceeded"
+	CodeTaskConflict          = "task_conflict"
+	CodeTaskForbidden         = "task_forbidden"
+	CodePreconditionFailed    = "precondition_failed"
+	CodePreconditionRequired  = "precondition_required"
+	CodeStorageFailure        = "storage_failure"
+	CodeUserIDMissing         = "user_id_missing"
+	CodeInvalidTokenFormat    = "invalid_token_format"
+	CodeInvalidToken          = "invalid_token"
+	CodeInvalidTokenClaims    = "invalid_token_claims"
+	CodeTokenGenerationFailed = "token_generation_failed"
+	CodeRouteNotFound         = "route_not_found"
+	CodeRateLimited           = "rate_limited"
+	CodeRequestTimeout        = "request_timeout"
+	CodeRequestCanceled       = "request_canceled"
+	CodeInternal              = "internal_error"
+)
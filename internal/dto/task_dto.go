@@ -1,19 +1,227 @@
 package dto
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/quickadd"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
 
 type CreateTaskRequest struct {
-	Title       string `json:"title" validate:"required,min=5,max=100"`
-	Description string `json:"description" validate:"required,min=8,max=250"`
+	Title        string         `json:"title" validate:"required,min=5,max=100"`
+	Description  string         `json:"description" validate:"required,min=8,max=250"`
+	Priority     string         `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	Project      uint64         `json:"project"`
+	Parent       uint64         `json:"parent"`
+	Labels       []uint64       `json:"labels" validate:"max=20"`
+	CustomFields map[uint64]any `json:"custom_fields" validate:"max=50"`
+	DependsOn    []uint64       `json:"depends_on" validate:"max=50"`
+	Completed    bool           `json:"completed"`
+	DueAt        *time.Time     `json:"due_at" validate:"required_with=RRule"`
+	RRule        string         `json:"rrule" validate:"max=200"`
+	TimeZone     string         `json:"timezone" validate:"omitempty,timezone"`
 }
 
+// UpdateTaskRequest is the complete writable representation of a task. PUT
+// replaces a task with it and PATCH documents are applied to it.
 type UpdateTaskRequest struct {
-	Title       *string `json:"title,omitempty" validate:"omitempty,min=5,max=100"`
-	Description *string `json:"description,omitempty" validate:"omitempty,min=8,max=250"`
+	Title        string         `json:"title" validate:"required,min=5,max=100"`
+	Description  string         `json:"description" validate:"required,min=8,max=250"`
+	Priority     string         `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	Project      uint64         `json:"project"`
+	Parent       uint64         `json:"parent"`
+	Labels       []uint64       `json:"labels" validate:"max=20"`
+	CustomFields map[uint64]any `json:"custom_fields" validate:"max=50"`
+	DependsOn    []uint64       `json:"depends_on" validate:"max=50"`
+	Completed    bool           `json:"completed"`
+	DueAt        *time.Time     `json:"due_at" validate:"required_with=RRule"`
+	RRule        string         `json:"rrule" validate:"max=200"`
+	TimeZone     string         `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// QuickAddRequest creates a task from a single line of text. Relative dates
+// in Text are resolved in TimeZone; Description defaults to Text.
+type QuickAddRequest struct {
+	Text        string `json:"text" validate:"required,max=500"`
+	Description string `json:"description" validate:"max=250"`
+	TimeZone    string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// MoveTaskRequest places a task right before or right after another task
+// of the same list. Exactly one of the two must be set.
+type MoveTaskRequest struct {
+	Before uint64 `json:"before" validate:"required_without=After,excluded_with=After"`
+	After  uint64 `json:"after" validate:"required_without=Before,excluded_with=Before"`
+}
+
+type ChecklistItemRequest struct {
+	Text    string `json:"text" validate:"required,min=1,max=100"`
+	Checked bool   `json:"checked"`
+}
+
+// ChecklistMoveRequest moves an item to a 0-based position in the list.
+type ChecklistMoveRequest struct {
+	Position int `json:"position" validate:"min=0"`
+}
+
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,min=1,max=2000"`
+}
+
+type LabelRequest struct {
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+// CustomFieldRequest defines a custom field. Options lists the values of a
+// select field and must be empty for other types.
+type CustomFieldRequest struct {
+	Name    string   `json:"name" validate:"required,max=50"`
+	Type    string   `json:"type" validate:"required,oneof=text number date select checkbox"`
+	Options []string `json:"options" validate:"required_if=Type select,excluded_unless=Type select,max=50,unique,dive,required,max=50"`
+}
+
+// TemplateTaskRequest describes a task created from a template. It is due
+// DueOffset days after the anchor date of an instantiation, at DueTime or
+// at the end of that day.
+type TemplateTaskRequest struct {
+	Title       string   `json:"title" validate:"required,min=5,max=100"`
+	Description string   `json:"description" validate:"required,min=8,max=250"`
+	Labels      []uint64 `json:"labels" validate:"max=20"`
+	Checklist   []string `json:"checklist" validate:"max=50,dive,required,max=100"`
+	DueOffset   *int     `json:"due_offset" validate:"omitempty,min=-365,max=365"`
+	DueTime     string   `json:"due_time" validate:"omitempty,datetime=15:04"`
+}
+
+type TemplateRequest struct {
+	Name        string                `json:"name" validate:"required,max=100"`
+	Title       string                `json:"title" validate:"required,min=5,max=100"`
+	Description string                `json:"description" validate:"required,min=8,max=250"`
+	Labels      []uint64              `json:"labels" validate:"max=20"`
+	Checklist   []string              `json:"checklist" validate:"max=50,dive,required,max=100"`
+	DueOffset   *int                  `json:"due_offset" validate:"omitempty,min=-365,max=365"`
+	DueTime     string                `json:"due_time" validate:"omitempty,datetime=15:04"`
+	Subtasks    []TemplateTaskRequest `json:"subtasks" validate:"max=50,dive"`
+}
+
+// InstantiateTemplateRequest creates a template's tasks in Project. Due
+// dates are counted in days from Anchor ("2006-01-02") in TimeZone.
+type InstantiateTemplateRequest struct {
+	Anchor   string `json:"anchor" validate:"required,datetime=2006-01-02"`
+	TimeZone string `json:"timezone" validate:"omitempty,timezone"`
+	Project  uint64 `json:"project"`
+}
+
+// ViewRequest saves a task query. Its fields work like the parameters of
+// the task listing of the same names.
+type ViewRequest struct {
+	Name       string   `json:"name" validate:"required,max=100"`
+	Project    uint64   `json:"project"`
+	Labels     []uint64 `json:"labels" validate:"max=20"`
+	LabelMatch string   `json:"label_match" validate:"omitempty,oneof=all any"`
+	Status     string   `json:"status" validate:"omitempty,oneof=open completed"`
+	Due        string   `json:"due" validate:"omitempty,oneof=overdue today next_7_days none"`
+	Query      string   `json:"q" validate:"max=500"`
+	TimeZone   string   `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// BoardColumnRequest describes a column of a board. A column that sends
+// the id of one of the board's current columns keeps it.
+type BoardColumnRequest struct {
+	ID       uint64 `json:"id"`
+	Name     string `json:"name" validate:"required,max=50"`
+	Status   string `json:"status" validate:"omitempty,oneof=open completed"`
+	State    string `json:"state" validate:"max=100"`
+	WIPLimit int    `json:"wip_limit" validate:"min=0,max=1000"`
+}
+
+type BoardRequest struct {
+	Name       string               `json:"name" validate:"required,max=100"`
+	Project    uint64               `json:"project"`
+	StateField uint64               `json:"state_field"`
+	Columns    []BoardColumnRequest `json:"columns" validate:"required,min=1,max=20,dive"`
+}
+
+// MoveCardRequest moves a card into a column and, optionally, right before
+// or right after another card of the same list.
+type MoveCardRequest struct {
+	Column uint64 `json:"column" validate:"required"`
+	Before uint64 `json:"before" validate:"excluded_with=After"`
+	After  uint64 `json:"after" validate:"excluded_with=Before"`
+}
+
+type ProjectRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type BatchOperation struct {
+	Op      string             `json:"op"`
+	ID      uint64             `json:"id,omitempty"`
+	IfMatch uint64             `json:"if_match,omitempty"`
+	Task    *UpdateTaskRequest `json:"task,omitempty"`
+}
+
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchResult struct {
+	Index  int `json:"index"`
+	Status int `json:"status"`
+	Task   any `json:"task,omitempty"`
+	Error  any `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// CommentPage is one page of a task's comments. NextCursor is passed as
+// ?after= to fetch the next page and is omitted on the last one.
+type CommentPage struct {
+	Comments   []models.Comment `json:"comments"`
+	NextCursor uint64           `json:"next_cursor,omitempty"`
+}
+
+// BoardColumnCards is a board column with the tasks shown in it, in list
+// order.
+type BoardColumnCards struct {
+	Column models.BoardColumn `json:"column"`
+	Tasks  []models.Task      `json:"tasks"`
+}
+
+type BoardResponse struct {
+	Board   models.Board       `json:"board"`
+	Columns []BoardColumnCards `json:"columns"`
+}
+
+// QuickAddResponse reports what was parsed from a quick-add entry and the
+// task created from it, which is omitted for dry runs.
+type QuickAddResponse struct {
+	Parsed quickadd.Result `json:"parsed"`
+	Task   *models.Task    `json:"task,omitempty"`
 }
 
 type TaskResponse struct {
@@ -22,34 +230,122 @@ type TaskResponse struct {
 	Description string `json:"description"`
 }
 
-func (r *CreateTaskRequest) Validate() map[string]string {
-	err := validate.Struct(r)
+func (r *CreateTaskRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *UpdateTaskRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *QuickAddRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *MoveTaskRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *ChecklistItemRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *ChecklistMoveRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *CommentRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *LabelRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *CustomFieldRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *TemplateRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *InstantiateTemplateRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *ViewRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *BoardRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *MoveCardRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func (r *ProjectRequest) Validate() error {
+	return validateStruct(r)
+}
+
+func validateStruct(s any) error {
+	err := validate.Struct(s)
 	if err == nil {
 		return nil
 	}
-	errors := make(map[string]string)
-	for _, e := range err.(validator.ValidationErrors) {
-		field := e.Field()
-		switch field {
-		case "Title":
-			switch e.Tag() {
-			case "required":
-				errors["title"] = "Title is required"
-			case "min":
-				errors["title"] = "Title must be at least 5 characters"
-			case "max":
-				errors["title"] = "Title must not exceed 100 characters"
-			}
-		case "Description":
-			switch e.Tag() {
-			case "required":
-				errors["description"] = "Description is required"
-			case "min":
-				errors["description"] = "Description must be at least 8 characters"
-			case "max":
-				errors["description"] = "Description must not exceed 250 characters"
-			}
-		}
+	validationErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	// Fields of nested structs are keyed by their path, e.g. "subtasks[0].title".
+	fields := make(map[string]string)
+	for _, e := range validationErrs {
+		_, path, _ := strings.Cut(e.Namespace(), ".")
+		fields[path] = fieldMessage(e)
+	}
+	return apperr.Validation(fields)
+}
+
+func fieldMessage(e validator.FieldError) string {
+	label := strings.ToUpper(e.Field()[:1]) + e.Field()[1:]
+	unit := " characters"
+	switch e.Kind() {
+	case reflect.Slice, reflect.Map:
+		unit = " items"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		unit = ""
+	}
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", label)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", label, e.Param(), unit)
+	case "max":
+		return fmt.Sprintf("%s must not exceed %s%s", label, e.Param(), unit)
+	case "hexcolor":
+		return fmt.Sprintf("%s must be a hex color such as #ff8800", label)
+	case "required_with":
+		return fmt.Sprintf("%s is required for recurring tasks", label)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", label, strings.ReplaceAll(e.Param(), " ", ", "))
+	case "required_without":
+		return fmt.Sprintf("%s is required unless %s is set", label, e.Param())
+	case "excluded_with":
+		return fmt.Sprintf("%s cannot be combined with %s", label, e.Param())
+	case "required_if":
+		return fmt.Sprintf("%s is required for %s fields", label, strings.Fields(e.Param())[1])
+	case "excluded_unless":
+		return fmt.Sprintf("%s is only allowed for %s fields", label, strings.Fields(e.Param())[1])
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicates", label)
+	case "datetime":
+		return fmt.Sprintf("%s must have the format %s", label, e.Param())
+	case "timezone":
+		return fmt.Sprintf("%s must be an IANA time zone such as Europe/Berlin", label)
+	default:
+		return fmt.Sprintf("%s is invalid", label)
 	}
-	return errors
 }
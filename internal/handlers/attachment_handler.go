@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/res"
+	"task-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is how far an upload body may exceed the attachment
+// size limit to make room for multipart headers and boundaries.
+const multipartOverhead = 64 << 10
+
+func attachmentIDParam(c *gin.Context) (uint64, error) {
+	attachmentID, err := strconv.ParseUint(c.Param("attachment"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidAttachmentID, "Invalid attachment ID", err)
+	}
+	return attachmentID, nil
+}
+
+// UploadAttachment accepts a multipart/form-data body with the file in the
+// "file" field.
+func (h *TaskHandler) UploadAttachment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.TaskService.MaxAttachmentSize+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(services.ErrAttachmentTooLarge)
+			return
+		}
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.TaskService.AddAttachment(c.Request.Context(), userID, taskID, header.Filename, header.Size, file)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Attachment uploaded",
+		Data:    attachment,
+	})
+}
+
+func (h *TaskHandler) GetAttachments(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	attachments, err := h.TaskService.ListAttachments(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Attachments retrieved",
+		Data:    attachments,
+	})
+}
+
+// DownloadAttachment streams an attachment's content. Range and conditional
+// requests are handled by http.ServeContent.
+func (h *TaskHandler) DownloadAttachment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	attachmentID, err := attachmentIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	attachment, content, err := h.TaskService.OpenAttachment(c.Request.Context(), userID, taskID, attachmentID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, attachment.Name, attachment.CreatedAt, content)
+}
+
+func (h *TaskHandler) DeleteAttachment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	attachmentID, err := attachmentIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteAttachment(c.Request.Context(), userID, taskID, attachmentID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Attachment deleted",
+	})
+}
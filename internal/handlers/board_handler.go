@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func boardIDParam(c *gin.Context) (uint64, error) {
+	boardID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidBoardID, "Invalid board ID", err)
+	}
+	return boardID, nil
+}
+
+func (h *TaskHandler) GetBoards(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	boards, err := h.TaskService.GetBoards(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "All boards retrieved",
+		Data:    boards,
+	})
+}
+
+func (h *TaskHandler) GetBoardByID(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	boardID, err := boardIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	board, err := h.TaskService.GetBoard(c.Request.Context(), userID, boardID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Board retrieved",
+		Data:    board,
+	})
+}
+
+func (h *TaskHandler) CreateBoard(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.BoardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	created, err := h.TaskService.CreateBoard(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Board created",
+		Data:    created,
+	})
+}
+
+func (h *TaskHandler) UpdateBoard(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	boardID, err := boardIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.BoardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	updated, err := h.TaskService.UpdateBoard(c.Request.Context(), userID, boardID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Board updated",
+		Data:    updated,
+	})
+}
+
+func (h *TaskHandler) DeleteBoard(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	boardID, err := boardIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteBoard(c.Request.Context(), userID, boardID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Board deleted",
+		Data:    nil,
+	})
+}
+
+func (h *TaskHandler) MoveCard(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	boardID, err := boardIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := strconv.ParseUint(c.Param("task"), 10, 64)
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidTaskID, "Invalid task ID", err))
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.MoveCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	moved, undoToken, err := h.TaskService.MoveCard(c.Request.Context(), userID, boardID, taskID, ifMatch, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(moved.Version))
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Card moved",
+		Data:    moved,
+	})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func checklistItemParam(c *gin.Context) (uint64, error) {
+	itemID, err := strconv.ParseUint(c.Param("item"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidChecklistItem, "Invalid checklist item ID", err)
+	}
+	return itemID, nil
+}
+
+type checklistWrite func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error)
+
+// writeChecklist resolves the user, task and If-Match header of a checklist
+// request, runs write and responds with the updated task.
+func (h *TaskHandler) writeChecklist(c *gin.Context, status int, message string, write checklistWrite) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	task, undoToken, err := write(c.Request.Context(), userID, taskID, ifMatch)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(task.Version))
+	c.JSON(status, res.SuccessResponse{
+		Message: message,
+		Data:    task,
+	})
+}
+
+func (h *TaskHandler) AddChecklistItem(c *gin.Context) {
+	var req dto.ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	h.writeChecklist(c, http.StatusCreated, "Checklist item added", func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error) {
+		return h.TaskService.AddChecklistItem(ctx, userID, taskID, ifMatch, req)
+	})
+}
+
+func (h *TaskHandler) UpdateChecklistItem(c *gin.Context) {
+	itemID, err := checklistItemParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.ChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	h.writeChecklist(c, http.StatusOK, "Checklist item updated", func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error) {
+		return h.TaskService.UpdateChecklistItem(ctx, userID, taskID, ifMatch, itemID, req)
+	})
+}
+
+func (h *TaskHandler) ToggleChecklistItem(c *gin.Context) {
+	itemID, err := checklistItemParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.writeChecklist(c, http.StatusOK, "Checklist item toggled", func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error) {
+		return h.TaskService.ToggleChecklistItem(ctx, userID, taskID, ifMatch, itemID)
+	})
+}
+
+func (h *TaskHandler) MoveChecklistItem(c *gin.Context) {
+	itemID, err := checklistItemParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.ChecklistMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	h.writeChecklist(c, http.StatusOK, "Checklist item moved", func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error) {
+		return h.TaskService.MoveChecklistItem(ctx, userID, taskID, ifMatch, itemID, req)
+	})
+}
+
+func (h *TaskHandler) RemoveChecklistItem(c *gin.Context) {
+	itemID, err := checklistItemParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.writeChecklist(c, http.StatusOK, "Checklist item removed", func(ctx context.Context, userID string, taskID, ifMatch uint64) (models.Task, string, error) {
+		return h.TaskService.RemoveChecklistItem(ctx, userID, taskID, ifMatch, itemID)
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+	"task-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func commentIDParam(c *gin.Context) (uint64, error) {
+	commentID, err := strconv.ParseUint(c.Param("comment"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidCommentID, "Invalid comment ID", err)
+	}
+	return commentID, nil
+}
+
+func (h *TaskHandler) GetComments(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultCommentPageSize)))
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidPagination, "Invalid limit", err))
+		return
+	}
+	after, err := strconv.ParseUint(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidPagination, "Invalid cursor", err))
+		return
+	}
+
+	page, err := h.TaskService.ListComments(c.Request.Context(), userID, taskID, after, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Comments retrieved",
+		Data:    page,
+	})
+}
+
+func (h *TaskHandler) CreateComment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	comment, err := h.TaskService.AddComment(c.Request.Context(), userID, taskID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Comment added",
+		Data:    comment,
+	})
+}
+
+func (h *TaskHandler) UpdateComment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	commentID, err := commentIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	comment, err := h.TaskService.UpdateComment(c.Request.Context(), userID, taskID, commentID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Comment updated",
+		Data:    comment,
+	})
+}
+
+func (h *TaskHandler) DeleteComment(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	commentID, err := commentIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteComment(c.Request.Context(), userID, taskID, commentID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Comment deleted",
+	})
+}
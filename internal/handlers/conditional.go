@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"task-backend/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errPreconditionFailed = apperr.New(apperr.ErrPrecondition, apperr.CodePreconditionFailed, "Precondition failed",
+	"If-Match does not match the task's current ETag")
+
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// ifMatchVersion returns the task version named by the If-Match header, or
+// 0 when the header is absent or "*". If-Match uses strong comparison, so
+// weak or malformed tags can never match and fail the precondition. When the
+// header lists several tags, the one naming the task's current version is
+// returned; the write still checks it, so a change in between fails it.
+func (h *TaskHandler) ifMatchVersion(c *gin.Context, userID string, taskID uint64) (uint64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if h.RequireIfMatch {
+			return 0, apperr.New(apperr.ErrPreconditionRequired, apperr.CodePreconditionRequired, "Precondition required",
+				"send an If-Match header with the task's current ETag")
+		}
+		return 0, nil
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	var versions []uint64
+	for _, tag := range strings.Split(header, ",") {
+		unquoted, err := strconv.Unquote(strings.TrimSpace(tag))
+		if err != nil {
+			continue
+		}
+		if version, err := strconv.ParseUint(unquoted, 10, 64); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, errPreconditionFailed
+	case 1:
+		return versions[0], nil
+	}
+
+	task, err := h.TaskService.GetTaskByID(c.Request.Context(), userID, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if slices.Contains(versions, task.Version) {
+		return task.Version, nil
+	}
+	return 0, errPreconditionFailed
+}
+
+// notModified reports whether the If-None-Match header matches version.
+// If-None-Match uses weak comparison and may list several tags.
+func notModified(c *gin.Context, version uint64) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fieldIDParam(c *gin.Context) (uint64, error) {
+	fieldID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidFieldID, "Invalid custom field ID", err)
+	}
+	return fieldID, nil
+}
+
+func (h *TaskHandler) GetCustomFields(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	fields, err := h.TaskService.GetCustomFields(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "All custom fields retrieved",
+		Data:    fields,
+	})
+}
+
+func (h *TaskHandler) GetCustomFieldByID(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	fieldID, err := fieldIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	field, err := h.TaskService.GetCustomField(c.Request.Context(), userID, fieldID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Custom field retrieved",
+		Data:    field,
+	})
+}
+
+func (h *TaskHandler) CreateCustomField(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	created, err := h.TaskService.CreateCustomField(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Custom field created",
+		Data:    created,
+	})
+}
+
+func (h *TaskHandler) UpdateCustomField(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	fieldID, err := fieldIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	updated, err := h.TaskService.UpdateCustomField(c.Request.Context(), userID, fieldID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Custom field updated",
+		Data:    updated,
+	})
+}
+
+func (h *TaskHandler) DeleteCustomField(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	fieldID, err := fieldIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteCustomField(c.Request.Context(), userID, fieldID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Custom field deleted",
+		Data:    nil,
+	})
+}
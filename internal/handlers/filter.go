@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskFilter reads the task listing query parameters:
+//
+//	?project=ID           only tasks of this project
+//	?label=1,2            tasks carrying all of these labels (repeatable)
+//	?label_match=any      tasks carrying any of the labels instead
+//	?status=open          open tasks, or completed ones with status=completed
+//	?q=query              tasks matching a search query, see package query
+//	?due=today            tasks due in a window: overdue, today, next_7_days or none
+//	?timezone=Zone        the time zone days of the due window start in (UTC by default)
+//	?field[ID]=value      tasks whose custom field has this value (repeatable per field)
+//	?sort=field:ID        order by a custom field, -field:ID for descending
+func taskFilter(c *gin.Context) (models.TaskFilter, error) {
+	var filter models.TaskFilter
+
+	if raw := c.Query("project"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidProjectID, "Invalid project ID", err)
+		}
+		filter.ProjectID = id
+	}
+
+	for _, value := range c.QueryArray("label") {
+		for _, raw := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+			if err != nil {
+				return filter, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidLabelID, "Invalid label ID", err)
+			}
+			filter.LabelIDs = append(filter.LabelIDs, id)
+		}
+	}
+
+	switch c.DefaultQuery("label_match", "all") {
+	case "all":
+	case "any":
+		filter.AnyLabel = true
+	default:
+		return filter, apperr.Validation(map[string]string{"label_match": "Label_match must be one of all, any"})
+	}
+
+	switch status := models.TaskStatus(c.Query("status")); status {
+	case "", models.StatusOpen, models.StatusCompleted:
+		filter.Status = status
+	default:
+		return filter, apperr.Validation(map[string]string{"status": "Status must be one of open, completed"})
+	}
+
+	filter.Query = c.Query("q")
+	filter.Due = models.DueWindow(c.Query("due"))
+	filter.TimeZone = c.Query("timezone")
+
+	for raw, value := range c.QueryMap("field") {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidFieldID, "Invalid custom field ID", err)
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[uint64]any)
+		}
+		filter.Fields[id] = value
+	}
+
+	if raw := c.Query("sort"); raw != "" {
+		desc := strings.HasPrefix(raw, "-")
+		rest, ok := strings.CutPrefix(strings.TrimPrefix(raw, "-"), "field:")
+		id, err := strconv.ParseUint(rest, 10, 64)
+		if !ok || err != nil {
+			return filter, apperr.New(apperr.ErrInvalid, apperr.CodeInvalidSort, "Invalid sort", "sort must be field:ID or -field:ID")
+		}
+		filter.SortField = id
+		filter.SortDesc = desc
+	}
+	return filter, nil
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func labelIDParam(c *gin.Context) (uint64, error) {
+	labelID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidLabelID, "Invalid label ID", err)
+	}
+	return labelID, nil
+}
+
+func (h *TaskHandler) GetLabels(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	labels, err := h.TaskService.GetLabels(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "All labels retrieved",
+		Data:    labels,
+	})
+}
+
+func (h *TaskHandler) GetLabelByID(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	labelID, err := labelIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	label, err := h.TaskService.GetLabel(c.Request.Context(), userID, labelID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Label retrieved",
+		Data:    label,
+	})
+}
+
+func (h *TaskHandler) CreateLabel(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	created, err := h.TaskService.CreateLabel(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Label created",
+		Data:    created,
+	})
+}
+
+func (h *TaskHandler) UpdateLabel(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	labelID, err := labelIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	updated, err := h.TaskService.UpdateLabel(c.Request.Context(), userID, labelID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Label updated",
+		Data:    updated,
+	})
+}
+
+func (h *TaskHandler) DeleteLabel(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	labelID, err := labelIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteLabel(c.Request.Context(), userID, labelID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Label deleted",
+		Data:    nil,
+	})
+}
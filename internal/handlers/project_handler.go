@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func projectIDParam(c *gin.Context) (uint64, error) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidProjectID, "Invalid project ID", err)
+	}
+	return projectID, nil
+}
+
+func (h *TaskHandler) GetProjects(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projects, err := h.TaskService.GetProjects(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "All projects retrieved",
+		Data:    projects,
+	})
+}
+
+func (h *TaskHandler) GetProjectByID(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	project, err := h.TaskService.GetProject(c.Request.Context(), userID, projectID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Project retrieved",
+		Data:    project,
+	})
+}
+
+func (h *TaskHandler) GetProjectTasks(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	filter, err := taskFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.GetProjectTasks(c.Request.Context(), userID, projectID, filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Project tasks retrieved",
+		Data:    tasks,
+	})
+}
+
+func (h *TaskHandler) CreateProject(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	created, err := h.TaskService.CreateProject(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Project created",
+		Data:    created,
+	})
+}
+
+func (h *TaskHandler) UpdateProject(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	updated, err := h.TaskService.RenameProject(c.Request.Context(), userID, projectID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Project updated",
+		Data:    updated,
+	})
+}
+
+func (h *TaskHandler) ArchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, true)
+}
+
+func (h *TaskHandler) UnarchiveProject(c *gin.Context) {
+	h.setProjectArchived(c, false)
+}
+
+func (h *TaskHandler) setProjectArchived(c *gin.Context, archived bool) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	project, err := h.TaskService.ArchiveProject(c.Request.Context(), userID, projectID, archived)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	message := "Project unarchived"
+	if archived {
+		message = "Project archived"
+	}
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: message,
+		Data:    project,
+	})
+}
+
+// DeleteProject deletes a project. ?tasks=move (the default) moves its tasks
+// to the Inbox, ?tasks=trash moves them to the trash.
+func (h *TaskHandler) DeleteProject(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var cascade bool
+	switch c.DefaultQuery("tasks", "move") {
+	case "move":
+	case "trash":
+		cascade = true
+	default:
+		_ = c.Error(apperr.Validation(map[string]string{"tasks": "Tasks must be one of move, trash"}))
+		return
+	}
+
+	if err := h.TaskService.DeleteProject(c.Request.Context(), userID, projectID, cascade); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Project deleted",
+		Data:    nil,
+	})
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"task-backend/internal/apperr"
 	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	"task-backend/internal/patch"
 	"task-backend/internal/res"
 	"task-backend/internal/services"
 
@@ -12,29 +15,77 @@ import (
 
 type TaskHandler struct {
 	TaskService *services.TaskService
+	// RequireIfMatch rejects writes to existing tasks that don't carry an
+	// If-Match header with 428 Precondition Required.
+	RequireIfMatch bool
 }
 
-func (h *TaskHandler) GetAllTasks(c *gin.Context) {
-	ctx := c.Request.Context()
+func currentUserID(c *gin.Context) (string, error) {
 	userIDRaw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Failed to retrieve user ID",
-			Error:   "invalid id",
-		})
-		return
+		return "", apperr.New(apperr.ErrInternal, apperr.CodeUserIDMissing, "Failed to retrieve user ID", "user id missing from request context")
 	}
 
 	userID, ok := userIDRaw.(string)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Invalid user ID type",
-			Error:   "type assertion failed",
-		})
+		return "", apperr.New(apperr.ErrInternal, apperr.CodeUserIDMissing, "Failed to retrieve user ID", "user id has an unexpected type")
+	}
+	return userID, nil
+}
+
+func taskIDParam(c *gin.Context) (uint64, error) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidTaskID, "Invalid task ID", err)
+	}
+	return taskID, nil
+}
+
+// setUndoToken exposes the token that undoes the current request's change.
+func setUndoToken(c *gin.Context, token string) {
+	if token != "" {
+		c.Header("Undo-Token", token)
+	}
+}
+
+// editScope reads the occurrences of a recurring task an edit applies to
+// from ?scope=this|future.
+func editScope(c *gin.Context) (models.EditScope, error) {
+	switch scope := models.EditScope(c.DefaultQuery("scope", string(models.ScopeFuture))); scope {
+	case models.ScopeThis, models.ScopeFuture:
+		return scope, nil
+	default:
+		return "", apperr.New(apperr.ErrInvalid, apperr.CodeInvalidScope, "Invalid scope", "scope must be this or future")
+	}
+}
+
+func revisionParam(c *gin.Context) (uint64, error) {
+	rev, err := strconv.ParseUint(c.Param("rev"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRevision, "Invalid revision", err)
+	}
+	return rev, nil
+}
+
+func (h *TaskHandler) GetAllTasks(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	filter, err := taskFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.ListTasks(c.Request.Context(), userID, filter)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	tasks := h.TaskService.GetAllTasks(ctx, userID)
 	c.JSON(http.StatusOK, res.SuccessResponse{
 		Message: "All tasks retrieved",
 		Data:    tasks,
@@ -42,32 +93,27 @@ func (h *TaskHandler) GetAllTasks(c *gin.Context) {
 }
 
 func (h *TaskHandler) GetTaskByID(c *gin.Context) {
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Failed to retrieve user ID",
-			Error:   "invalid id",
-		})
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	userID := userIDRaw.(string)
 
-	taskID := c.Param("id")
-	taskIDUint, err := strconv.ParseUint(taskID, 10, 64)
+	taskID, err := taskIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Invalid task ID",
-			Error:   err.Error(),
-		})
+		_ = c.Error(err)
 		return
 	}
 
-	task, found := h.TaskService.GetTaskByID(c, userID, taskIDUint)
-	if !found {
-		c.JSON(http.StatusNotFound, res.ErrorResponse{
-			Message: "Task not found",
-			Error:   "invalid id",
-		})
+	task, err := h.TaskService.GetTaskByID(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	if notModified(c, task.Version) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -81,116 +127,570 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var newTask dto.CreateTaskRequest
 
 	if err := c.ShouldBindJSON(&newTask); err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Invalid request body",
-			Error:   err.Error(),
-		})
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
 		return
 	}
 
-	if err := newTask.Validate(); err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Validation failed",
-			Error:   err,
-		})
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Failed to retrieve user ID",
-			Error:   "invalid id",
-		})
+	created, err := h.TaskService.CreateTask(c.Request.Context(), userID, newTask)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	userID := userIDRaw.(string)
-
-	created := h.TaskService.CreateTask(c, userID, newTask)
 
+	c.Header("ETag", etag(created.Version))
 	c.JSON(http.StatusCreated, res.SuccessResponse{
 		Message: "Task created",
 		Data:    created,
 	})
 }
 
-func (h *TaskHandler) UpdateTask(c *gin.Context) {
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Failed to retrieve user ID",
-			Error:   "invalid id",
-		})
+// QuickAddTask creates a task from a line such as "Pay rent tomorrow 9am
+// #home !high". With ?dry_run=true it only reports what would be created.
+func (h *TaskHandler) QuickAddTask(c *gin.Context) {
+	var req dto.QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	userID := userIDRaw.(string)
 
-	taskID := c.Param("id")
-	taskIDUint, err := strconv.ParseUint(taskID, 10, 64)
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Invalid task ID",
-			Error:   err.Error(),
+		_ = c.Error(apperr.Validation(map[string]string{"dry_run": "Dry_run must be true or false"}))
+		return
+	}
+
+	result, err := h.TaskService.QuickAddTask(c.Request.Context(), userID, req, dryRun)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, res.SuccessResponse{
+			Message: "Task parsed",
+			Data:    result,
 		})
 		return
 	}
+	c.Header("ETag", etag(result.Task.Version))
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Task created",
+		Data:    result,
+	})
+}
+
+func (h *TaskHandler) UpdateTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scope, err := editScope(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
 	var updateData dto.UpdateTaskRequest
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Invalid request body",
-			Error:   err.Error(),
-		})
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
 		return
 	}
 
-	updated, ok := h.TaskService.UpdateTask(c, userID, taskIDUint, updateData)
-	if !ok {
-		c.JSON(http.StatusNotFound, res.ErrorResponse{
-			Message: "Task not found",
-			Error:   "invalid id",
-		})
+	updated, undoToken, err := h.TaskService.UpdateTask(c.Request.Context(), userID, taskID, ifMatch, scope, updateData)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(updated.Version))
 	c.JSON(http.StatusOK, res.SuccessResponse{
 		Message: "Task updated",
 		Data:    updated,
 	})
 }
 
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	scope, err := editScope(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	var p patch.Patch
+	switch c.ContentType() {
+	case patch.MIMEMergePatch:
+		p = patch.MergePatch(body)
+	case patch.MIMEJSONPatch:
+		p = patch.JSONPatch(body)
+	default:
+		c.Header("Accept-Patch", patch.MIMEMergePatch+", "+patch.MIMEJSONPatch)
+		_ = c.Error(apperr.New(apperr.ErrUnsupportedType, apperr.CodeUnsupportedMediaType, "Unsupported media type",
+			"PATCH accepts "+patch.MIMEMergePatch+" or "+patch.MIMEJSONPatch))
+		return
+	}
+
+	patched, undoToken, err := h.TaskService.PatchTask(c.Request.Context(), userID, taskID, ifMatch, scope, p)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(patched.Version))
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task updated",
+		Data:    patched,
+	})
+}
+
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, res.ErrorResponse{
-			Message: "Failed to retrieve user ID",
-			Error:   "invalid id",
-		})
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	userID := userIDRaw.(string)
 
-	taskID := c.Param("id")
-	taskIDUint, err := strconv.ParseUint(taskID, 10, 64)
+	taskID, err := taskIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, res.ErrorResponse{
-			Message: "Invalid task ID",
-			Error:   err.Error(),
-		})
+		_ = c.Error(err)
 		return
 	}
 
-	ok := h.TaskService.DeleteTask(c, userID, taskIDUint)
-	if !ok {
-		c.JSON(http.StatusNotFound, res.ErrorResponse{
-			Message: "Task not found",
-			Error:   "invalid id",
-		})
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
+	undoToken, err := h.TaskService.DeleteTask(c.Request.Context(), userID, taskID, ifMatch)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
 	c.JSON(http.StatusOK, res.SuccessResponse{
 		Message: "Task deleted",
 		Data:    nil,
 	})
 }
+
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	moved, undoToken, err := h.TaskService.MoveTask(c.Request.Context(), userID, taskID, ifMatch, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(moved.Version))
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task moved",
+		Data:    moved,
+	})
+}
+
+func (h *TaskHandler) BatchTasks(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	results, committed, undoToken, err := h.TaskService.BatchTasks(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := dto.BatchResponse{
+		Atomic:    req.Atomic,
+		Committed: committed,
+		Results:   make([]dto.BatchResult, len(results)),
+	}
+	status := http.StatusOK
+	for i, r := range results {
+		if r.Err != nil {
+			problem := res.NewProblem(c, r.Err)
+			resp.Results[i] = dto.BatchResult{Index: i, Status: problem.Status, Error: problem}
+			status = http.StatusMultiStatus
+			continue
+		}
+
+		opStatus := http.StatusOK
+		if req.Operations[i].Op == string(models.BatchCreate) {
+			opStatus = http.StatusCreated
+		}
+		resp.Results[i] = dto.BatchResult{Index: i, Status: opStatus, Task: r.Task}
+	}
+
+	setUndoToken(c, undoToken)
+	c.JSON(status, res.SuccessResponse{
+		Message: "Batch processed",
+		Data:    resp,
+	})
+}
+
+func (h *TaskHandler) GetTrash(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.GetTrash(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Trashed tasks retrieved",
+		Data:    tasks,
+	})
+}
+
+func (h *TaskHandler) GetOccurrences(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(services.DefaultPreviewOccurrences)))
+	if err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidCount, "Invalid count", err))
+		return
+	}
+
+	occurrences, err := h.TaskService.PreviewOccurrences(c.Request.Context(), userID, taskID, count)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Occurrences retrieved",
+		Data:    occurrences,
+	})
+}
+
+func (h *TaskHandler) GetReadyTasks(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.GetReadyTasks(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Ready tasks retrieved",
+		Data:    tasks,
+	})
+}
+
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	restored, err := h.TaskService.RestoreTask(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(restored.Version))
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task restored",
+		Data:    restored,
+	})
+}
+
+func (h *TaskHandler) PurgeTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.PurgeTask(c.Request.Context(), userID, taskID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task permanently deleted",
+		Data:    nil,
+	})
+}
+
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	revisions, err := h.TaskService.GetTaskHistory(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task history retrieved",
+		Data:    revisions,
+	})
+}
+
+func (h *TaskHandler) GetTaskRevision(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	rev, err := revisionParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	revision, err := h.TaskService.GetTaskRevision(c.Request.Context(), userID, taskID, rev)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task revision retrieved",
+		Data:    revision,
+	})
+}
+
+func (h *TaskHandler) RevertTask(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	rev, err := revisionParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ifMatch, err := h.ifMatchVersion(c, userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	reverted, undoToken, err := h.TaskService.RevertTask(c.Request.Context(), userID, taskID, rev, ifMatch)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, undoToken)
+	c.Header("ETag", etag(reverted.Version))
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task reverted",
+		Data:    reverted,
+	})
+}
+
+func (h *TaskHandler) Undo(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.Undo(c.Request.Context(), userID, c.Param("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Change undone",
+		Data:    tasks,
+	})
+}
+
+func (h *TaskHandler) GetSubtasks(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tasks, err := h.TaskService.GetSubtasks(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Subtasks retrieved",
+		Data:    tasks,
+	})
+}
+
+func (h *TaskHandler) GetTaskTree(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	taskID, err := taskIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	tree, err := h.TaskService.GetTaskTree(c.Request.Context(), userID, taskID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Task tree retrieved",
+		Data:    tree,
+	})
+}
@@ -4,21 +4,35 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"task-backend/internal/apperr"
 	"task-backend/internal/dto"
 	"task-backend/internal/handlers"
+	"task-backend/internal/middlewares"
 	"task-backend/internal/models"
+	"task-backend/internal/patch"
 	"task-backend/internal/res"
+	"task-backend/internal/router"
 	"task-backend/internal/services"
+	"task-backend/internal/storage"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockTaskRepository struct {
+	services.TaskRepository
 	tasks  map[string]map[uint64]models.Task
 	nextID uint64
 }
@@ -30,51 +44,82 @@ func NewMockTaskRepository() *MockTaskRepository {
 	}
 }
 
-func (m *MockTaskRepository) Create(userID string, task models.Task) models.Task {
+func (m *MockTaskRepository) Create(ctx context.Context, userID string, task models.Task) (models.Task, error) {
 	if m.tasks[userID] == nil {
 		m.tasks[userID] = make(map[uint64]models.Task)
 	}
 	task.ID = m.nextID
 	m.nextID++
 	task.UserID = userID
+	task.Version = 1
 	m.tasks[userID][task.ID] = task
-	return task
+	return task, nil
 }
 
-func (m *MockTaskRepository) GetAll(userID string) []models.Task {
+func (m *MockTaskRepository) GetAll(ctx context.Context, userID string) ([]models.Task, error) {
 	tasksMap := m.tasks[userID]
 	tasks := make([]models.Task, 0, len(tasksMap))
 	for _, t := range tasksMap {
 		tasks = append(tasks, t)
 	}
-	return tasks
+	return tasks, nil
 }
 
-func (m *MockTaskRepository) GetByID(userID string, taskID uint64) (models.Task, bool) {
+func (m *MockTaskRepository) Query(ctx context.Context, userID string, match func(models.Task) bool) ([]models.Task, error) {
+	tasks := make([]models.Task, 0, len(m.tasks[userID]))
+	for _, t := range m.tasks[userID] {
+		if match(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MockTaskRepository) GetByID(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
 	task, ok := m.tasks[userID][taskID]
-	return task, ok
+	if !ok {
+		return models.Task{}, apperr.ErrNotFound
+	}
+	return task, nil
 }
 
-func (m *MockTaskRepository) Update(userID string, taskID uint64, updated models.Task) bool {
+func (m *MockTaskRepository) Update(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64) (models.Task, error) {
 	if m.tasks[userID] == nil {
-		return false
+		return models.Task{}, apperr.ErrNotFound
 	}
-	if _, ok := m.tasks[userID][taskID]; !ok {
-		return false
+	current, ok := m.tasks[userID][taskID]
+	if !ok {
+		return models.Task{}, apperr.ErrNotFound
 	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return models.Task{}, apperr.ErrConflict
+	}
+	updated.Version = current.Version + 1
 	m.tasks[userID][taskID] = updated
-	return true
+	return updated, nil
 }
 
-func (m *MockTaskRepository) Delete(userID string, taskID uint64) bool {
+func (m *MockTaskRepository) Delete(ctx context.Context, userID string, taskID uint64, expectedVersion uint64) error {
 	if m.tasks[userID] == nil {
-		return false
+		return apperr.ErrNotFound
+	}
+	current, ok := m.tasks[userID][taskID]
+	if !ok {
+		return apperr.ErrNotFound
 	}
-	if _, ok := m.tasks[userID][taskID]; !ok {
-		return false
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return apperr.ErrConflict
 	}
 	delete(m.tasks[userID], taskID)
-	return true
+	return nil
+}
+
+func (m *MockTaskRepository) GetProjects(ctx context.Context, userID string) ([]models.Project, error) {
+	return nil, nil
+}
+
+func (m *MockTaskRepository) SaveUndo(ctx context.Context, token string, entry models.UndoEntry) error {
+	return nil
 }
 
 func setupHandler() *handlers.TaskHandler {
@@ -83,24 +128,55 @@ func setupHandler() *handlers.TaskHandler {
 	return &handlers.TaskHandler{TaskService: service}
 }
 
-func TestTaskHandler_GetAllTasks(t *testing.T) {
+// newTestRouter serves the task routes behind the error middleware and
+// authenticates every request as userID when userID is not empty.
+func newTestRouter(handler *handlers.TaskHandler, userID string) *gin.Engine {
 	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("userID", userID)
+		}
+	})
+	idempotency := middlewares.Idempotency(storage.NewIdempotencyStore(), time.Hour)
+	router.RegisterTaskRoutes(r.Group("/tasks"), handler, idempotency)
+	router.RegisterUndoRoutes(r.Group("/undo"), handler)
+	router.RegisterLabelRoutes(r.Group("/labels"), handler)
+	router.RegisterProjectRoutes(r.Group("/projects"), handler)
+	router.RegisterFieldRoutes(r.Group("/fields"), handler)
+	router.RegisterTemplateRoutes(r.Group("/templates"), handler, idempotency)
+	router.RegisterViewRoutes(r.Group("/views"), handler)
+	router.RegisterBoardRoutes(r.Group("/boards"), handler)
+	return r
+}
+
+func performRequest(handler *handlers.TaskHandler, userID string, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	newTestRouter(handler, userID).ServeHTTP(w, req)
+	return w
+}
+
+func newJSONRequest(method, path string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestTaskHandler_GetAllTasks(t *testing.T) {
 	handler := setupHandler()
 
-	handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+	_, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
 		Title:       "Test Task",
 		Description: "Description",
 	})
+	assert.NoError(t, err)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
-	handler.GetAllTasks(c)
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodGet, "/tasks", nil))
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp res.SuccessResponse
-	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
 
 	tasks, ok := resp.Data.([]interface{})
@@ -109,24 +185,19 @@ func TestTaskHandler_GetAllTasks(t *testing.T) {
 }
 
 func TestTaskHandler_GetTaskByID(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	created := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+	created, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
 		Title:       "Sample Task",
-		Description: "Desc",
+		Description: "Sample Description",
 	})
+	assert.NoError(t, err)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Params = gin.Params{{Key: "id", Value: fmt.Sprintf("%d", created.ID)}}
-
-	handler.GetTaskByID(c)
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", created.ID), nil))
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var resp res.SuccessResponse
-	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
 
 	data, ok := resp.Data.(map[string]interface{})
@@ -136,7 +207,6 @@ func TestTaskHandler_GetTaskByID(t *testing.T) {
 }
 
 func TestTaskHandler_CreateTask(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
 	newTask := dto.CreateTaskRequest{
@@ -145,13 +215,7 @@ func TestTaskHandler_CreateTask(t *testing.T) {
 	}
 	body, _ := json.Marshal(newTask)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Request, _ = http.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
-	c.Request.Header.Set("Content-Type", "application/json")
-
-	handler.CreateTask(c)
+	w := performRequest(handler, "user1", newJSONRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body)))
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 
@@ -166,14 +230,9 @@ func TestTaskHandler_CreateTask(t *testing.T) {
 }
 
 func TestTaskHandler_GetAllTasks_NoUserID(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
-
-	handler.GetAllTasks(c)
+	w := performRequest(handler, "", httptest.NewRequest(http.MethodGet, "/tasks", nil))
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 
@@ -184,15 +243,9 @@ func TestTaskHandler_GetAllTasks_NoUserID(t *testing.T) {
 }
 
 func TestTaskHandler_GetTaskByID_NoUserID(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Params = gin.Params{{Key: "id", Value: "1"}}
-	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
-
-	handler.GetTaskByID(c)
+	w := performRequest(handler, "", httptest.NewRequest(http.MethodGet, "/tasks/1", nil))
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	var resp res.ErrorResponse
@@ -202,16 +255,9 @@ func TestTaskHandler_GetTaskByID_NoUserID(t *testing.T) {
 }
 
 func TestTaskHandler_GetTaskByID_InvalidID(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Params = gin.Params{{Key: "id", Value: "not-a-number"}}
-	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
-
-	handler.GetTaskByID(c)
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodGet, "/tasks/not-a-number", nil))
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	var resp res.ErrorResponse
@@ -221,16 +267,9 @@ func TestTaskHandler_GetTaskByID_InvalidID(t *testing.T) {
 }
 
 func TestTaskHandler_GetTaskByID_NotFound(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Params = gin.Params{{Key: "id", Value: "999"}}
-	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
-
-	handler.GetTaskByID(c)
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodGet, "/tasks/999", nil))
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	var resp res.ErrorResponse
@@ -240,16 +279,9 @@ func TestTaskHandler_GetTaskByID_NotFound(t *testing.T) {
 }
 
 func TestTaskHandler_CreateTask_InvalidJSON(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Request, _ = http.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer([]byte("{invalid-json")))
-	c.Request.Header.Set("Content-Type", "application/json")
-
-	handler.CreateTask(c)
+	w := performRequest(handler, "user1", newJSONRequest(http.MethodPost, "/tasks", bytes.NewBuffer([]byte("{invalid-json"))))
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
@@ -260,7 +292,6 @@ func TestTaskHandler_CreateTask_InvalidJSON(t *testing.T) {
 }
 
 func TestTaskHandler_CreateTask_ValidationFail(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
 	invalidTask := dto.CreateTaskRequest{
@@ -269,13 +300,7 @@ func TestTaskHandler_CreateTask_ValidationFail(t *testing.T) {
 	}
 	body, _ := json.Marshal(invalidTask)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Set("userID", "user1")
-	c.Request, _ = http.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
-	c.Request.Header.Set("Content-Type", "application/json")
-
-	handler.CreateTask(c)
+	w := performRequest(handler, "user1", newJSONRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body)))
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	var resp res.ErrorResponse
@@ -285,7 +310,6 @@ func TestTaskHandler_CreateTask_ValidationFail(t *testing.T) {
 }
 
 func TestTaskHandler_CreateTask_NoUserID(t *testing.T) {
-	gin.SetMode(gin.TestMode)
 	handler := setupHandler()
 
 	newTask := dto.CreateTaskRequest{
@@ -294,13 +318,7 @@ func TestTaskHandler_CreateTask_NoUserID(t *testing.T) {
 	}
 	body, _ := json.Marshal(newTask)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-
-	c.Request, _ = http.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
-	c.Request.Header.Set("Content-Type", "application/json")
-
-	handler.CreateTask(c)
+	w := performRequest(handler, "", newJSONRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body)))
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	var resp res.ErrorResponse
@@ -308,3 +326,1478 @@ func TestTaskHandler_CreateTask_NoUserID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, resp.Message, "Failed to retrieve user ID")
 }
+
+func TestTaskHandler_GetTaskByID_NotFound_ProblemJSON(t *testing.T) {
+	handler := setupHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/999", nil)
+	req.Header.Set("Accept", res.MIMEProblemJSON)
+	w := performRequest(handler, "user1", req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, res.MIMEProblemJSON, w.Header().Get("Content-Type"))
+	var problem res.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.NoError(t, err)
+	assert.Equal(t, "task_not_found", problem.Code)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "Task not found", problem.Title)
+}
+
+func TestTaskHandler_CreateTask_ValidationFail_ProblemJSON(t *testing.T) {
+	handler := setupHandler()
+
+	body, _ := json.Marshal(dto.CreateTaskRequest{Title: "abc", Description: "long enough"})
+
+	req := newJSONRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Accept", res.MIMEProblemJSON)
+	w := performRequest(handler, "user1", req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var problem res.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.NoError(t, err)
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, "Title must be at least 5 characters", problem.Errors["title"])
+	assert.NotContains(t, problem.Errors, "description")
+}
+
+func TestTaskHandler_UpdateTask_NotFound(t *testing.T) {
+	handler := setupHandler()
+
+	body := []byte(`{"title": "Updated title", "description": "Updated description"}`)
+	w := performRequest(handler, "user1", newJSONRequest(http.MethodPut, "/tasks/999", bytes.NewBuffer(body)))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var resp res.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Contains(t, resp.Message, "Task not found")
+}
+
+func TestTaskHandler_GetAllTasks_DeadlineExceeded(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil).WithContext(ctx)
+	req.Header.Set("Accept", res.MIMEProblemJSON)
+
+	w := performRequest(handler, "user1", req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	var problem res.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.NoError(t, err)
+	assert.Equal(t, "request_timeout", problem.Code)
+}
+
+func TestTaskHandler_UpdateTask_PartialDocument(t *testing.T) {
+	handler := setupHandler()
+
+	created, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Sample Task",
+		Description: "Sample Description",
+	})
+	assert.NoError(t, err)
+
+	body := []byte(`{"title": "Updated title"}`)
+	w := performRequest(handler, "user1", newJSONRequest(http.MethodPut, fmt.Sprintf("/tasks/%d", created.ID), bytes.NewBuffer(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp res.ErrorResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Contains(t, resp.Message, "Validation failed")
+}
+
+func TestTaskHandler_PatchTask(t *testing.T) {
+	handler := setupHandler()
+
+	created, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Sample Task",
+		Description: "Sample Description",
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", created.ID)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantTitle   string
+	}{
+		{"merge patch", patch.MIMEMergePatch, `{"title":"Merged title"}`, http.StatusOK, "Merged title"},
+		{"json patch", patch.MIMEJSONPatch, `[{"op":"test","path":"/title","value":"Merged title"},{"op":"replace","path":"/title","value":"Patched title"}]`, http.StatusOK, "Patched title"},
+		{"json patch test fails", patch.MIMEJSONPatch, `[{"op":"test","path":"/title","value":"Merged title"}]`, http.StatusConflict, ""},
+		{"invalid result", patch.MIMEMergePatch, `{"title":"abc"}`, http.StatusBadRequest, ""},
+		{"plain json", "application/json", `{"title":"Plain title"}`, http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := performRequest(handler, "user1", req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp res.SuccessResponse
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			data, ok := resp.Data.(map[string]interface{})
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantTitle, data["Title"])
+			assert.Equal(t, "Sample Description", data["Description"])
+		})
+	}
+}
+
+func TestTaskHandler_ConditionalRequests(t *testing.T) {
+	handler := setupHandler()
+
+	created, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Sample Task",
+		Description: "Sample Description",
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", created.ID)
+
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, tag)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", tag)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	body := []byte(`{"title": "Updated title", "description": "Updated description"}`)
+	req = newJSONRequest(http.MethodPut, path, bytes.NewBuffer(body))
+	req.Header.Set("If-Match", tag)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	req = httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"title":"Second tab title"}`))
+	req.Header.Set("Content-Type", patch.MIMEMergePatch)
+	req.Header.Set("If-Match", tag)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", `W/"2"`)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", `"5", W/"2"`)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", `"1", "2"`)
+	w = performRequest(handler, "user1", req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTaskHandler_RequireIfMatch(t *testing.T) {
+	handler := setupHandler()
+	handler.RequireIfMatch = true
+
+	created, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Sample Task",
+		Description: "Sample Description",
+	})
+	assert.NoError(t, err)
+
+	w := performRequest(handler, "user1", httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/tasks/%d", created.ID), nil))
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+}
+
+func TestTaskHandler_CreateTask_IdempotencyKey(t *testing.T) {
+	handler := setupHandler()
+	r := newTestRouter(handler, "user1")
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := newJSONRequest(http.MethodPost, "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	body := `{"title": "Pay the rent", "description": "Transfer before the 1st"}`
+
+	first := send("key-1", body)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := send("key-1", body)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+
+	reused := send("key-1", `{"title": "Something else", "description": "Another description"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+
+	other := send("key-2", body)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get("Idempotent-Replayed"))
+
+	tasks, err := handler.TaskService.GetAllTasks(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	invalid := send("key-3", `{"title": "abc"}`)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+	fixed := send("key-3", body)
+	assert.Equal(t, http.StatusCreated, fixed.Code)
+}
+
+func TestTaskHandler_BatchTasks(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	existing, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Existing task",
+		Description: "Existing description",
+	})
+	assert.NoError(t, err)
+
+	send := func(body string) (int, dto.BatchResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(http.MethodPost, "/tasks/batch", bytes.NewBufferString(body)))
+
+		var resp struct {
+			Data dto.BatchResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp.Data
+	}
+
+	ops := fmt.Sprintf(`[
+		{"op": "create", "task": {"title": "Batch created", "description": "Created in a batch"}},
+		{"op": "update", "id": %d, "task": {"title": "Batch updated", "description": "Updated in a batch"}},
+		{"op": "delete", "id": 999}
+	]`, existing.ID)
+
+	status, resp := send(`{"atomic": true, "operations": ` + ops + `}`)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.False(t, resp.Committed)
+	assert.Equal(t, http.StatusFailedDependency, resp.Results[0].Status)
+	assert.Equal(t, http.StatusFailedDependency, resp.Results[1].Status)
+	assert.Equal(t, http.StatusNotFound, resp.Results[2].Status)
+
+	tasks, _ := handler.TaskService.GetAllTasks(context.Background(), "user1")
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "Existing task", tasks[0].Title)
+
+	status, resp = send(`{"atomic": false, "operations": ` + ops + `}`)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.True(t, resp.Committed)
+	assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+	assert.Equal(t, http.StatusOK, resp.Results[1].Status)
+	assert.Equal(t, http.StatusNotFound, resp.Results[2].Status)
+
+	tasks, _ = handler.TaskService.GetAllTasks(context.Background(), "user1")
+	assert.Len(t, tasks, 2)
+
+	status, _ = send(`{"operations": [{"op": "delete", "id": ` + fmt.Sprint(existing.ID) + `}]}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	replay := func() *httptest.ResponseRecorder {
+		req := newJSONRequest(http.MethodPost, "/tasks/batch", bytes.NewBufferString(`{"operations": [{"op": "create", "task": {"title": "Replayed task", "description": "Created once only"}}]}`))
+		req.Header.Set("Idempotency-Key", "batch-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	first, retry := replay(), replay()
+	assert.NotEmpty(t, first.Header().Get("Undo-Token"))
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Header().Get("Undo-Token"), retry.Header().Get("Undo-Token"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newJSONRequest(http.MethodPost, "/tasks/batch", bytes.NewBufferString(`{"operations": []}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTaskHandler_Trash(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	task, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Trash me",
+		Description: "Task headed for the trash",
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, path).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path).Code)
+
+	w := send(http.MethodGet, "/tasks/trash")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var trash struct {
+		Data []map[string]any `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &trash))
+	assert.Len(t, trash.Data, 1)
+	assert.NotNil(t, trash.Data[0]["DeletedAt"])
+
+	w = send(http.MethodPost, path+"/restore")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, path).Code)
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/tasks/trash/"+fmt.Sprint(task.ID)).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, path).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/tasks/trash/"+fmt.Sprint(task.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, path+"/restore").Code)
+}
+
+func TestTaskHandler_History(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	task, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Original title",
+		Description: "Original description",
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := send(http.MethodPut, path, `{"title": "Original title", "description": "Overwritten by mistake"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send(http.MethodGet, path+"/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Data []models.Revision `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Len(t, history.Data, 2)
+	assert.Equal(t, task.ID, history.Data[1].TaskID)
+	assert.Equal(t, []models.FieldChange{{Field: "description", Old: "Original description", New: "Overwritten by mistake"}}, history.Data[1].Changes)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, path+"/history/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path+"/history/9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, path+"/history/abc", "").Code)
+
+	w = send(http.MethodPost, path+"/revert/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	restored, err := handler.TaskService.GetTaskByID(context.Background(), "user1", task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Original description", restored.Description)
+
+	revs, _ := handler.TaskService.GetTaskHistory(context.Background(), "user1", task.ID)
+	assert.Len(t, revs, 3)
+}
+
+func TestTaskHandler_RevertPriorityAndLabels(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+	ctx := context.Background()
+
+	label, _ := handler.TaskService.CreateLabel(ctx, "user1", dto.LabelRequest{Name: "work"})
+	task, err := handler.TaskService.CreateTask(ctx, "user1", dto.CreateTaskRequest{
+		Title:       "Prioritized task",
+		Description: "Priority and labels change",
+		Priority:    "low",
+		Labels:      []uint64{label.ID},
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := send(http.MethodPut, path, `{"title": "Prioritized task", "description": "Priority and labels change", "priority": "urgent", "labels": []}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	revs, err := handler.TaskService.GetTaskHistory(ctx, "user1", task.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revs, 2)
+	assert.Equal(t, []models.FieldChange{
+		{Field: "priority", Old: "low", New: "urgent"},
+		{Field: "labels", Old: fmt.Sprint(label.ID), New: ""},
+	}, revs[1].Changes)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, path+"/revert/1", "").Code)
+	restored, err := handler.TaskService.GetTaskByID(ctx, "user1", task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PriorityLow, restored.Priority)
+	assert.Equal(t, []uint64{label.ID}, restored.LabelIDs)
+}
+
+func TestTaskHandler_Undo(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	task, err := handler.TaskService.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{
+		Title:       "Undo me",
+		Description: "Task for undo tests",
+	})
+	assert.NoError(t, err)
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := send(http.MethodDelete, path, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	token := w.Header().Get("Undo-Token")
+	assert.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/undo/"+token, "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, path, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/undo/"+token, "").Code)
+
+	w = send(http.MethodPut, path, `{"title": "Changed title", "description": "Task for undo tests"}`)
+	token = w.Header().Get("Undo-Token")
+	send(http.MethodPut, path, `{"title": "Changed again", "description": "Task for undo tests"}`)
+
+	w = send(http.MethodPost, "/undo/"+token, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	current, _ := handler.TaskService.GetTaskByID(context.Background(), "user1", task.ID)
+	assert.Equal(t, "Changed again", current.Title)
+
+	w = send(http.MethodPost, "/tasks/batch", `{"operations": [{"op": "create", "task": {"title": "Batch created", "description": "Created in a batch"}}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/undo/"+w.Header().Get("Undo-Token"), "").Code)
+	tasks, _ := handler.TaskService.GetAllTasks(context.Background(), "user1")
+	assert.Len(t, tasks, 1)
+}
+
+func TestTaskHandler_Labels(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	createLabel := func(name string) uint64 {
+		w := send(http.MethodPost, "/labels", `{"name": "`+name+`", "color": "#ff8800"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.Label `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.ID
+	}
+	listTitles := func(query string) []string {
+		w := send(http.MethodGet, "/tasks"+query, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.Task `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		titles := make([]string, 0, len(resp.Data))
+		for _, task := range resp.Data {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	work, urgent := createLabel("work"), createLabel("urgent")
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/labels", `{"name": "Work"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/labels", `{"name": "Bad", "color": "orange"}`).Code)
+
+	send(http.MethodPost, "/tasks", fmt.Sprintf(`{"title": "Work only", "description": "Labelled work", "labels": [%d]}`, work))
+	send(http.MethodPost, "/tasks", fmt.Sprintf(`{"title": "Urgent work", "description": "Labelled both", "labels": [%d, %d]}`, work, urgent))
+	send(http.MethodPost, "/tasks", `{"title": "Unlabelled", "description": "No labels at all"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/tasks", `{"title": "Bad label", "description": "Unknown label id", "labels": [12345]}`).Code)
+
+	assert.ElementsMatch(t, []string{"Work only", "Urgent work"}, listTitles(fmt.Sprintf("?label=%d", work)))
+	assert.ElementsMatch(t, []string{"Urgent work"}, listTitles(fmt.Sprintf("?label=%d,%d", work, urgent)))
+	assert.ElementsMatch(t, []string{"Work only", "Urgent work"}, listTitles(fmt.Sprintf("?label=%d&label=%d&label_match=any", work, urgent)))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/tasks?label=abc", "").Code)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/labels/%d", work), "").Code)
+	assert.Empty(t, listTitles(fmt.Sprintf("?label=%d", work)))
+	assert.ElementsMatch(t, []string{"Urgent work"}, listTitles(fmt.Sprintf("?label=%d", urgent)))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, fmt.Sprintf("/labels/%d", work), "").Code)
+}
+
+func TestTaskHandler_Projects(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	listTitles := func(path string) []string {
+		w := send(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.Task `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		titles := make([]string, 0, len(resp.Data))
+		for _, task := range resp.Data {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	w := send(http.MethodPost, "/projects", `{"name": "Work"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Project `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	projectPath := fmt.Sprintf("/projects/%d", created.Data.ID)
+
+	send(http.MethodPost, "/tasks", fmt.Sprintf(`{"title": "Work task", "description": "Belongs to work", "project": %d}`, created.Data.ID))
+	w = send(http.MethodPost, "/tasks", `{"title": "Inbox task", "description": "Belongs to the inbox"}`)
+	var inboxTask struct {
+		Data models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &inboxTask))
+
+	assert.Equal(t, []string{"Work task"}, listTitles(projectPath+"/tasks"))
+	assert.Equal(t, []string{"Inbox task"}, listTitles(fmt.Sprintf("/tasks?project=%d", inboxTask.Data.ProjectID)))
+
+	req := newJSONRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", inboxTask.Data.ID), bytes.NewBufferString(fmt.Sprintf(`{"project": %d}`, created.Data.ID)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, []string{"Work task", "Inbox task"}, listTitles(projectPath+"/tasks"))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, projectPath+"/archive", "").Code)
+	assert.Empty(t, listTitles("/tasks"))
+	assert.Len(t, listTitles(projectPath+"/tasks"), 2)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, projectPath+"/unarchive", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, projectPath+"?tasks=keep", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, projectPath, "").Code)
+	assert.Len(t, listTitles("/tasks"), 2)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, projectPath+"/tasks", "").Code)
+
+	w = send(http.MethodGet, "/projects", "")
+	var projects struct {
+		Data []models.Project `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &projects))
+	assert.Len(t, projects.Data, 1)
+	assert.True(t, projects.Data[0].Inbox)
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, fmt.Sprintf("/projects/%d", projects.Data[0].ID), "").Code)
+}
+
+func TestTaskHandler_Subtasks(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	create := func(body string) models.Task {
+		w := send(http.MethodPost, "/tasks", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.Task `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	parent := create(`{"title": "Big task", "description": "Broken into steps"}`)
+	step := create(fmt.Sprintf(`{"title": "First step", "description": "Part of the big task", "parent": %d}`, parent.ID))
+	create(fmt.Sprintf(`{"title": "Sub step", "description": "Part of the first step", "parent": %d}`, step.ID))
+	assert.Equal(t, parent.ID, step.ParentID)
+
+	w := send(http.MethodGet, fmt.Sprintf("/tasks/%d/children", parent.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var children struct {
+		Data []models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &children))
+	assert.Len(t, children.Data, 1)
+
+	w = send(http.MethodGet, fmt.Sprintf("/tasks/%d/subtree", parent.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tree struct {
+		Data models.TaskNode `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tree))
+	assert.Equal(t, "Big task", tree.Data.Title)
+	assert.Equal(t, "Sub step", tree.Data.Children[0].Children[0].Title)
+
+	req := newJSONRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", parent.ID), bytes.NewBufferString(`{"completed": true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req = newJSONRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", parent.ID), bytes.NewBufferString(fmt.Sprintf(`{"parent": %d}`, step.ID)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestTaskHandler_Dependencies(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	create := func(body string) models.Task {
+		w := send(http.MethodPost, "/tasks", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.Task `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	first := create(`{"title": "Pour concrete", "description": "The foundation first"}`)
+	second := create(fmt.Sprintf(`{"title": "Build walls", "description": "On top of the foundation", "depends_on": [%d]}`, first.ID))
+	assert.True(t, second.Blocked)
+	assert.Equal(t, []uint64{first.ID}, second.BlockedBy)
+
+	w := send(http.MethodGet, "/tasks/ready", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ready struct {
+		Data []models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ready))
+	assert.Len(t, ready.Data, 1)
+	assert.Equal(t, first.ID, ready.Data[0].ID)
+
+	req := newJSONRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", first.ID), bytes.NewBufferString(fmt.Sprintf(`{"depends_on": [%d]}`, second.ID)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Dependency cycle")
+
+	// Completing the blocker changes how the dependent reads, so a cached
+	// copy of it must not be revalidated.
+	secondPath := fmt.Sprintf("/tasks/%d", second.ID)
+	w = send(http.MethodGet, secondPath, "")
+	tag := w.Header().Get("ETag")
+	req = newJSONRequest(http.MethodPatch, fmt.Sprintf("/tasks/%d", first.ID), bytes.NewBufferString(`{"completed": true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, secondPath, nil)
+	req.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, tag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"blocked":false`)
+}
+
+func TestTaskHandler_RecurringTasks(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := newJSONRequest(method, path, bytes.NewBufferString(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/tasks", "", `{"title": "Take out trash", "description": "Every monday and thursday",
+		"due_at": "2025-01-06T07:00:00+01:00", "rrule": "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3", "timezone": "Europe/Berlin"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, created.Data.ID, created.Data.Recurrence.SeriesID)
+	taskPath := fmt.Sprintf("/tasks/%d", created.Data.ID)
+
+	w = send(http.MethodGet, taskPath+"/occurrences?count=5", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var preview struct {
+		Data []time.Time `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Len(t, preview.Data, 3)
+	assert.True(t, preview.Data[1].Equal(time.Date(2025, 1, 9, 6, 0, 0, 0, time.UTC)))
+
+	w = send(http.MethodPatch, taskPath+"?scope=this", "application/merge-patch+json", `{"rrule": "FREQ=DAILY"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send(http.MethodPatch, taskPath+"?scope=this", "application/merge-patch+json",
+		`{"title": "Take out trash early", "due_at": "2025-01-05T20:00:00+01:00"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send(http.MethodPatch, taskPath, "application/merge-patch+json", `{"completed": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send(http.MethodGet, "/tasks", "", "")
+	var list struct {
+		Data []models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+	for _, task := range list.Data {
+		if task.ID == created.Data.ID {
+			continue
+		}
+		assert.Equal(t, "Take out trash", task.Title)
+		assert.Equal(t, 2, task.Recurrence.Index)
+		assert.Equal(t, created.Data.ID, task.Recurrence.SeriesID)
+		assert.True(t, task.DueAt.Equal(time.Date(2025, 1, 9, 6, 0, 0, 0, time.UTC)))
+	}
+}
+
+func TestTaskHandler_MoveTask(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	create := func(body string) models.Task {
+		w := send(http.MethodPost, "/tasks", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.Task `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	w := send(http.MethodPost, "/tasks", `{"title": "Pay bills", "description": "Before the deadline", "priority": "asap"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	first := create(`{"title": "Pay bills", "description": "Before the deadline", "priority": "urgent"}`)
+	second := create(`{"title": "Water plants", "description": "The ones on the balcony"}`)
+	assert.Equal(t, models.PriorityUrgent, first.Priority)
+	assert.Equal(t, models.PriorityNone, second.Priority)
+
+	w = send(http.MethodPost, fmt.Sprintf("/tasks/%d/move", second.ID), fmt.Sprintf(`{"before": %d, "after": %d}`, first.ID, first.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send(http.MethodPost, fmt.Sprintf("/tasks/%d/move", second.ID), fmt.Sprintf(`{"before": %d}`, first.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Undo-Token"))
+
+	w = send(http.MethodGet, "/tasks", "")
+	var list struct {
+		Data []models.Task `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []uint64{second.ID, first.ID}, []uint64{list.Data[0].ID, list.Data[1].ID})
+}
+
+func TestTaskHandler_Checklist(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) (*httptest.ResponseRecorder, models.Task) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		var resp struct {
+			Data models.Task `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	_, task := send(http.MethodPost, "/tasks", `{"title": "Pack for trip", "description": "Everything for the weekend"}`)
+	checklist := fmt.Sprintf("/tasks/%d/checklist", task.ID)
+
+	w, _ := send(http.MethodPost, checklist, `{"text": ""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = send(http.MethodPost, checklist, `{"text": "Passport"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	_, _ = send(http.MethodPost, checklist, `{"text": "Charger"}`)
+	_, task = send(http.MethodPost, checklist, `{"text": "Toothbrush", "checked": true}`)
+	assert.Equal(t, models.ChecklistProgress{Done: 1, Total: 3}, task.Progress)
+
+	_, task = send(http.MethodPost, checklist+"/1/toggle", "")
+	assert.Equal(t, 2, task.Progress.Done)
+
+	_, task = send(http.MethodPost, checklist+"/3/move", `{"position": 0}`)
+	assert.Equal(t, []string{"Toothbrush", "Passport", "Charger"}, []string{task.Checklist[0].Text, task.Checklist[1].Text, task.Checklist[2].Text})
+
+	_, task = send(http.MethodPut, checklist+"/2", `{"text": "Phone charger", "checked": true}`)
+	assert.Equal(t, "Phone charger", task.Checklist[2].Text)
+
+	w, task = send(http.MethodDelete, checklist+"/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ChecklistProgress{Done: 2, Total: 2}, task.Progress)
+
+	w, _ = send(http.MethodDelete, checklist+"/1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTaskHandler_Comments(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := send(http.MethodPost, "/tasks", `{"title": "Write release notes", "description": "For the next version"}`)
+	var created struct {
+		Data models.Task `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	comments := fmt.Sprintf("/tasks/%d/comments", created.Data.ID)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, comments, `{"body": ""}`).Code)
+	var comment struct {
+		Data models.Comment `json:"data"`
+	}
+	w = send(http.MethodPost, comments, `{"body": "Draft is in the wiki"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &comment)
+	assert.Equal(t, "user1", comment.Data.AuthorID)
+	_ = send(http.MethodPost, comments, `{"body": "Looks good"}`)
+
+	var page struct {
+		Data dto.CommentPage `json:"data"`
+	}
+	w = send(http.MethodGet, comments+"?limit=1", "")
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data.Comments, 1)
+	assert.Equal(t, comment.Data.ID, page.Data.NextCursor)
+
+	w = send(http.MethodGet, fmt.Sprintf("%s?limit=1&after=%d", comments, page.Data.NextCursor), "")
+	page.Data = dto.CommentPage{}
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, "Looks good", page.Data.Comments[0].Body)
+	assert.Zero(t, page.Data.NextCursor)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, comments+"?limit=abc", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, comments+"?limit=500", "").Code)
+
+	item := fmt.Sprintf("%s/%d", comments, comment.Data.ID)
+	w = send(http.MethodPut, item, `{"body": "Draft is in the wiki, see the Releases page"}`)
+	_ = json.Unmarshal(w.Body.Bytes(), &comment)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, comment.Data.EditedAt)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, item, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, item, "").Code)
+}
+
+func TestTaskHandler_Attachments(t *testing.T) {
+	dir := t.TempDir()
+	blobs, err := storage.NewFileBlobStore(dir)
+	assert.NoError(t, err)
+	service := services.NewTaskService(storage.NewTaskStore())
+	service.Blobs = blobs
+	service.MaxAttachmentSize = 1024
+	r := newTestRouter(&handlers.TaskHandler{TaskService: service}, "user1")
+
+	upload := func(path, name string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", name)
+		_, _ = part.Write(content)
+		_ = form.Close()
+		req := httptest.NewRequest(http.MethodPost, path, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	blobCount := func() int {
+		entries, _ := os.ReadDir(filepath.Join(dir, "attachments"))
+		return len(entries)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newJSONRequest(http.MethodPost, "/tasks", bytes.NewBufferString(`{"title": "File taxes", "description": "Attach the forms"}`)))
+	var task struct {
+		Data models.Task `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &task)
+	attachments := fmt.Sprintf("/tasks/%d/attachments", task.Data.ID)
+
+	pdf := []byte("%PDF-1.7\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+	w = upload(attachments, "../forms/w2.pdf", pdf)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var attachment struct {
+		Data models.Attachment `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &attachment)
+	assert.Equal(t, "w2.pdf", attachment.Data.Name)
+	assert.Equal(t, "application/pdf", attachment.Data.ContentType)
+	assert.Equal(t, int64(len(pdf)), attachment.Data.Size)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload(attachments, "setup.pdf", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00")).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(attachments, "big.txt", bytes.Repeat([]byte("a"), 2048)).Code)
+	assert.Equal(t, 1, blobCount())
+
+	item := fmt.Sprintf("%s/%d", attachments, attachment.Data.ID)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, item, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=w2.pdf`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, pdf, w.Body.Bytes())
+
+	req := httptest.NewRequest(http.MethodGet, item, nil)
+	req.Header.Set("Range", "bytes=0-7")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "%PDF-1.7", w.Body.String())
+
+	_ = upload(attachments, "notes.txt", []byte("bring the receipts"))
+	assert.Equal(t, 2, blobCount())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, item, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, blobCount())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/tasks/%d", task.Data.ID), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, blobCount(), "trashed tasks keep their attachments")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/tasks/trash/%d", task.Data.ID), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, blobCount())
+}
+
+// failingBlobs fails deletes while fail is set.
+type failingBlobs struct {
+	services.BlobStore
+	fail bool
+}
+
+func (b *failingBlobs) Delete(ctx context.Context, key string) error {
+	if b.fail {
+		return errors.New("blob store unavailable")
+	}
+	return b.BlobStore.Delete(ctx, key)
+}
+
+func TestTaskHandler_AttachmentBlobRetry(t *testing.T) {
+	dir := t.TempDir()
+	files, err := storage.NewFileBlobStore(dir)
+	assert.NoError(t, err)
+	blobs := &failingBlobs{BlobStore: files}
+	service := services.NewTaskService(storage.NewTaskStore())
+	service.Blobs = blobs
+	r := newTestRouter(&handlers.TaskHandler{TaskService: service}, "user1")
+
+	task, err := service.CreateTask(context.Background(), "user1", dto.CreateTaskRequest{Title: "File taxes", Description: "Attach the forms"})
+	assert.NoError(t, err)
+	attachments := fmt.Sprintf("/tasks/%d/attachments", task.ID)
+	upload := func(name string) models.Attachment {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", name)
+		_, _ = part.Write([]byte("bring the receipts"))
+		_ = form.Close()
+		req := httptest.NewRequest(http.MethodPost, attachments, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.Attachment `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data
+	}
+	remove := func(attachment models.Attachment) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%d", attachments, attachment.ID), nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	blobCount := func() int {
+		entries, _ := os.ReadDir(filepath.Join(dir, "attachments"))
+		return len(entries)
+	}
+
+	first, second := upload("first.txt"), upload("second.txt")
+	blobs.fail = true
+	remove(first)
+	assert.Equal(t, 2, blobCount(), "the blob store failed")
+
+	blobs.fail = false
+	remove(second)
+	assert.Equal(t, 0, blobCount(), "the next collection retries the failed delete")
+}
+
+func TestTaskHandler_CustomFields(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	createField := func(body string) models.CustomField {
+		w := send(http.MethodPost, "/fields", "application/json", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp struct {
+			Data models.CustomField `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data
+	}
+	titles := func(path string) []string {
+		w := send(http.MethodGet, path, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.Task `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		var out []string
+		for _, task := range resp.Data {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/fields", "application/json", `{"name": "Stage", "type": "select"}`).Code)
+	points := createField(`{"name": "Points", "type": "number"}`)
+	customer := createField(`{"name": "Customer", "type": "text"}`)
+
+	for _, body := range []string{
+		fmt.Sprintf(`{"title": "Build login", "description": "Sign in with email", "custom_fields": {"%d": 5, "%d": "Acme"}}`, points.ID, customer.ID),
+		fmt.Sprintf(`{"title": "Build export", "description": "CSV export of tasks", "custom_fields": {"%d": 2, "%d": "Globex"}}`, points.ID, customer.ID),
+		fmt.Sprintf(`{"title": "Fix typo", "description": "Typo on the landing page", "custom_fields": {"%d": "acme"}}`, customer.ID),
+	} {
+		assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/tasks", "application/json", body).Code)
+	}
+	w := send(http.MethodPost, "/tasks", "application/json", fmt.Sprintf(`{"title": "Bad points", "description": "Points must be numbers", "custom_fields": {"%d": "lots"}}`, points.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Points must be a number")
+
+	assert.Equal(t, []string{"Build login", "Fix typo"}, titles(fmt.Sprintf("/tasks?field[%d]=ACME", customer.ID)))
+	assert.Equal(t, []string{"Build login"}, titles(fmt.Sprintf("/tasks?field[%d]=5", points.ID)))
+	assert.Equal(t, []string{"Build export", "Build login", "Fix typo"}, titles(fmt.Sprintf("/tasks?sort=field:%d", points.ID)))
+	assert.Equal(t, []string{"Build login", "Build export", "Fix typo"}, titles(fmt.Sprintf("/tasks?sort=-field:%d", points.ID)))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, fmt.Sprintf("/tasks?field[%d]=many", points.ID), "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/tasks?sort=title", "", "").Code)
+
+	w = send(http.MethodGet, "/tasks", "", "")
+	var list struct {
+		Data []models.Task `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	assert.Equal(t, 5.0, list.Data[0].CustomFields[points.ID])
+	w = send(http.MethodPatch, fmt.Sprintf("/tasks/%d", list.Data[0].ID), "application/merge-patch+json", fmt.Sprintf(`{"custom_fields": {"%d": null}}`, points.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, titles(fmt.Sprintf("/tasks?field[%d]=5", points.ID)))
+}
+
+func TestTaskHandler_QuickAdd(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(path, body string) (*httptest.ResponseRecorder, dto.QuickAddResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		var resp struct {
+			Data dto.QuickAddResponse `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	w, result := send("/tasks/quick?dry_run=true", `{"text": "Pay rent tomorrow 9am #home !high every month", "timezone": "Europe/Berlin"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, result.Task)
+	assert.Equal(t, "Pay rent", result.Parsed.Title)
+	assert.Equal(t, []string{"home"}, result.Parsed.Labels)
+	assert.Equal(t, 9, result.Parsed.DueAt.In(mustLoadLocation(t, "Europe/Berlin")).Hour())
+	labels, _ := handler.TaskService.GetLabels(context.Background(), "user1")
+	assert.Empty(t, labels, "dry runs must not create labels")
+
+	w, result = send("/tasks/quick", `{"text": "Pay rent tomorrow 9am #home !high every month", "timezone": "Europe/Berlin"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "Pay rent", result.Task.Title)
+	assert.Equal(t, models.PriorityHigh, result.Task.Priority)
+	assert.Equal(t, "FREQ=MONTHLY", result.Task.Recurrence.GetRule())
+	assert.Equal(t, "Europe/Berlin", result.Task.Recurrence.GetTimeZone())
+	assert.Len(t, result.Task.LabelIDs, 1)
+
+	_, again := send("/tasks/quick", `{"text": "Water plants #HOME", "description": "Both balconies"}`)
+	assert.Equal(t, result.Task.LabelIDs, again.Task.LabelIDs)
+	assert.Equal(t, "Both balconies", again.Task.Description)
+
+	w, _ = send("/tasks/quick", `{"text": "tomorrow #home"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Text must contain a title")
+
+	longLabel := strings.Repeat("x", 51)
+	w, _ = send("/tasks/quick?dry_run=true", `{"text": "Water plants #`+longLabel+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "dry runs must check label names")
+
+	longTitle := "Write the quarterly report for the board and circulate it to every department head"
+	longEntry := longTitle + strings.Repeat(" #team", 40)
+	tests := []struct {
+		name        string
+		text        string
+		title       string
+		description string
+	}{
+		{"short entry", "Laundry", "Laundry", "Quick add: Laundry"},
+		{"entry that fits", "Water plants tomorrow", "Water plants", "Water plants tomorrow"},
+		{"long entry", longEntry, longTitle, longEntry[:249] + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, result := send("/tasks/quick", fmt.Sprintf(`{"text": %q}`, tt.text))
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			if assert.NotNil(t, result.Task) {
+				assert.Equal(t, tt.title, result.Task.Title)
+				assert.Equal(t, tt.description, result.Task.Description)
+			}
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestTaskHandler_Templates(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	label, _ := handler.TaskService.CreateLabel(context.Background(), "user1", dto.LabelRequest{Name: "onboarding"})
+	body := fmt.Sprintf(`{
+		"name": "Weekly onboarding",
+		"title": "Onboard new hire",
+		"description": "Everything for a new hire",
+		"labels": [%d],
+		"due_offset": 4,
+		"subtasks": [
+			{"title": "Order laptop", "description": "Ship it to the hire", "due_offset": -3, "due_time": "09:30"},
+			{"title": "Create accounts", "description": "Email and chat accounts", "checklist": ["Email", "Chat"]}
+		]
+	}`, label.ID)
+	w := send(http.MethodPost, "/templates", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Template `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/templates/%d", created.Data.ID)
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/templates", body).Code)
+
+	w = send(http.MethodPost, "/templates", `{"name": "Broken", "title": "Broken template", "description": "Subtask is invalid", "subtasks": [{"title": "Bad"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "subtasks[0].title")
+
+	w = send(http.MethodPost, path+"/instantiate", `{"anchor": "2026-10-26", "timezone": "Europe/Berlin"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var instantiated struct {
+		Data []models.Task `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &instantiated)
+	tasks := instantiated.Data
+	if assert.Len(t, tasks, 3) {
+		berlin := mustLoadLocation(t, "Europe/Berlin")
+		assert.Equal(t, []uint64{label.ID}, tasks[0].LabelIDs)
+		assert.Equal(t, time.Date(2026, 10, 30, 23, 59, 0, 0, berlin), tasks[0].DueAt.In(berlin))
+		assert.Equal(t, tasks[0].ID, tasks[1].ParentID)
+		assert.Equal(t, time.Date(2026, 10, 23, 9, 30, 0, 0, berlin), tasks[1].DueAt.In(berlin))
+		assert.Nil(t, tasks[2].DueAt)
+		assert.Equal(t, models.ChecklistProgress{Total: 2}, tasks[2].Progress)
+	}
+
+	token := w.Header().Get("Undo-Token")
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/undo/"+token, "").Code)
+	all, _ := handler.TaskService.GetAllTasks(context.Background(), "user1")
+	assert.Empty(t, all)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, path+"/instantiate", `{"anchor": "26.10.2026"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/templates/999/instantiate", `{"anchor": "2026-10-26"}`).Code)
+
+	_ = handler.TaskService.DeleteLabel(context.Background(), "user1", label.ID)
+	w = send(http.MethodGet, path, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Empty(t, created.Data.LabelIDs)
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, path+"/instantiate", `{"anchor": "2026-10-26"}`).Code)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, path, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path, "").Code)
+}
+
+func TestTaskHandler_Views(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	titles := func(path string) []string {
+		w := send(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.Task `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		var out []string
+		for _, task := range resp.Data {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	ctx := context.Background()
+	work, _ := handler.TaskService.CreateLabel(ctx, "user1", dto.LabelRequest{Name: "work"})
+	now := time.Now().UTC()
+	yesterday, later := now.AddDate(0, 0, -1), now.AddDate(0, 0, 3)
+	for _, req := range []dto.CreateTaskRequest{
+		{Title: "Send the invoice", Description: "Invoice for October", Labels: []uint64{work.ID}, DueAt: &yesterday},
+		{Title: "Review budget", Description: "Quarterly budget review", Labels: []uint64{work.ID}, DueAt: &later},
+		{Title: "Paid the invoice", Description: "Already done", Labels: []uint64{work.ID}, DueAt: &yesterday, Completed: true},
+		{Title: "Water plants", Description: "Both balconies"},
+	} {
+		_, err := handler.TaskService.CreateTask(ctx, "user1", req)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"Send the invoice", "Review budget", "Water plants"}, titles("/tasks?status=open"))
+	assert.Equal(t, []string{"Send the invoice", "Paid the invoice"}, titles("/tasks?q=INVOICE"))
+	assert.Equal(t, []string{"Water plants"}, titles("/tasks?due=none"))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/tasks?status=done", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/tasks?due=soon", "").Code)
+
+	w := send(http.MethodPost, "/views", fmt.Sprintf(`{"name": "Overdue work", "labels": [%d], "due": "overdue"}`, work.ID))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.View `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/views/%d", created.Data.ID)
+	assert.Equal(t, []string{"Send the invoice"}, titles(path+"/tasks"))
+
+	w = send(http.MethodPut, path, fmt.Sprintf(`{"name": "Open work", "labels": [%d], "status": "open", "due": "next_7_days"}`, work.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Review budget"}, titles(path+"/tasks"))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, path, `{"name": "Broken", "due": "someday"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/views", `{"name": "Ghost", "labels": [999]}`).Code)
+
+	_ = handler.TaskService.DeleteLabel(ctx, "user1", work.ID)
+	assert.Equal(t, []string{"Review budget"}, titles(path+"/tasks"))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, path, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path+"/tasks", "").Code)
+}
+
+func TestTaskHandler_SearchQuery(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+	titles := func(path string) []string {
+		w := send(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data []models.Task `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		var out []string
+		for _, task := range resp.Data {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	ctx := context.Background()
+	work, _ := handler.TaskService.CreateLabel(ctx, "user1", dto.LabelRequest{Name: "work"})
+	due := time.Date(2026, 10, 30, 12, 0, 0, 0, time.UTC)
+	for _, req := range []dto.CreateTaskRequest{
+		{Title: "Send the invoice", Description: "Invoice for October", Labels: []uint64{work.ID}, DueAt: &due},
+		{Title: "Review budget", Description: "Quarterly budget review", Labels: []uint64{work.ID}, Priority: "high"},
+		{Title: "Water plants", Description: "Both balconies", DueAt: &due},
+	} {
+		_, err := handler.TaskService.CreateTask(ctx, "user1", req)
+		assert.NoError(t, err)
+	}
+
+	search := func(q string) []string { return titles("/tasks?q=" + url.QueryEscape(q)) }
+	assert.Equal(t, []string{"Send the invoice"}, search(`status:open label:work due<2026-11-01 "the invoice"`))
+	assert.Equal(t, []string{"Review budget", "Water plants"}, search("priority:high OR -label:work"))
+	assert.Equal(t, []string{"Send the invoice", "Review budget"}, search("invoice OR budget"))
+
+	w := send(http.MethodGet, "/tasks?q="+url.QueryEscape("label:work stauts:open"), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `column 12: unknown field \"stauts\"`)
+	w = send(http.MethodGet, "/tasks?q="+url.QueryEscape("label:home"), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `there is no label named \"home\"`)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/views", `{"name": "Broken", "q": "label:home"}`).Code)
+	w = send(http.MethodPost, "/views", `{"name": "Dated work", "q": "label:work due:2026-10-30"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.View `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/views/%d", created.Data.ID)
+	assert.Equal(t, []string{"Send the invoice"}, titles(path+"/tasks"))
+
+	_, _ = handler.TaskService.UpdateLabel(ctx, "user1", work.ID, dto.LabelRequest{Name: "office"})
+	assert.Equal(t, []string{"Send the invoice"}, titles(path+"/tasks"))
+	_ = handler.TaskService.DeleteLabel(ctx, "user1", work.ID)
+	assert.Empty(t, titles(path+"/tasks"))
+}
+
+func TestTaskHandler_Boards(t *testing.T) {
+	handler := &handlers.TaskHandler{TaskService: services.NewTaskService(storage.NewTaskStore())}
+	r := newTestRouter(handler, "user1")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newJSONRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	ctx := context.Background()
+	stage, _ := handler.TaskService.CreateCustomField(ctx, "user1", dto.CustomFieldRequest{Name: "Stage", Type: "select", Options: []string{"todo", "doing"}})
+	tasks := make(map[string]models.Task)
+	for _, req := range []dto.CreateTaskRequest{
+		{Title: "Write spec", Description: "Draft the API spec", CustomFields: map[uint64]any{stage.ID: "doing"}},
+		{Title: "Fix login", Description: "Session cookie expires"},
+		{Title: "Ship it", Description: "Release version two", Completed: true},
+		{Title: "Plan launch", Description: "Agree on a launch date", CustomFields: map[uint64]any{stage.ID: "todo"}},
+	} {
+		task, err := handler.TaskService.CreateTask(ctx, "user1", req)
+		assert.NoError(t, err)
+		tasks[task.Title] = task
+	}
+
+	w := send(http.MethodPost, "/boards", fmt.Sprintf(`{"name": "Sprint", "state_field": %d, "columns": [
+		{"name": "Doing", "status": "open", "state": "doing", "wip_limit": 1},
+		{"name": "Done", "status": "completed"},
+		{"name": "Backlog", "status": "open"}
+	]}`, stage.ID))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Board `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	path := fmt.Sprintf("/boards/%d", created.Data.ID)
+
+	columns := func() map[string][]string {
+		w := send(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data dto.BoardResponse `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		out := make(map[string][]string)
+		for _, c := range resp.Data.Columns {
+			out[c.Column.Name] = []string{}
+			for _, task := range c.Tasks {
+				out[c.Column.Name] = append(out[c.Column.Name], task.Title)
+			}
+		}
+		return out
+	}
+	assert.Equal(t, map[string][]string{
+		"Doing":   {"Write spec"},
+		"Done":    {"Ship it"},
+		"Backlog": {"Fix login", "Plan launch"},
+	}, columns())
+
+	move := func(title, body string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, fmt.Sprintf("%s/cards/%d/move", path, tasks[title].ID), body)
+	}
+	w = move("Fix login", `{"column": 1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "limit of 1 tasks")
+
+	w = move("Write spec", `{"column": 2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	undoToken := w.Header().Get("Undo-Token")
+	assert.NotEmpty(t, undoToken)
+	w = move("Fix login", `{"column": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = move("Plan launch", fmt.Sprintf(`{"column": 2, "before": %d}`, tasks["Ship it"].ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string][]string{
+		"Doing":   {"Fix login"},
+		"Done":    {"Write spec", "Plan launch", "Ship it"},
+		"Backlog": {},
+	}, columns())
+
+	var moved struct {
+		Data models.Task `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &moved)
+	assert.True(t, moved.Data.Completed)
+	assert.Equal(t, "todo", moved.Data.CustomFields[stage.ID])
+
+	assert.Equal(t, http.StatusNotFound, move("Fix login", `{"column": 9}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, move("Fix login", fmt.Sprintf(`{"column": 1, "after": %d}`, tasks["Ship it"].ID)).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/boards", `{"name": "Empty", "columns": []}`).Code)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, path, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path, "").Code)
+}
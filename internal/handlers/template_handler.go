@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+func templateIDParam(c *gin.Context) (uint64, error) {
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidTemplateID, "Invalid template ID", err)
+	}
+	return templateID, nil
+}
+
+func (h *TaskHandler) GetTemplates(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	templates, err := h.TaskService.GetTemplates(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "All templates retrieved",
+		Data:    templates,
+	})
+}
+
+func (h *TaskHandler) GetTemplateByID(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	templateID, err := templateIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	template, err := h.TaskService.GetTemplate(c.Request.Context(), userID, templateID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Template retrieved",
+		Data:    template,
+	})
+}
+
+func (h *TaskHandler) CreateTemplate(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	created, err := h.TaskService.CreateTemplate(c.Request.Context(), userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Template created",
+		Data:    created,
+	})
+}
+
+func (h *TaskHandler) UpdateTemplate(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	templateID, err := templateIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	updated, err := h.TaskService.UpdateTemplate(c.Request.Context(), userID, templateID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Template updated",
+		Data:    updated,
+	})
+}
+
+func (h *TaskHandler) DeleteTemplate(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	templateID, err := templateIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.TaskService.DeleteTemplate(c.Request.Context(), userID, templateID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res.SuccessResponse{
+		Message: "Template deleted",
+		Data:    nil,
+	})
+}
+
+// InstantiateTemplate creates the tasks of a template, with due dates
+// counted from the anchor date in the request body.
+func (h *TaskHandler) InstantiateTemplate(c *gin.Context) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	templateID, err := templateIDParam(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req dto.InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+		return
+	}
+
+	tasks, token, err := h.TaskService.InstantiateTemplate(c.Request.Context(), userID, templateID, req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setUndoToken(c, token)
+	c.JSON(http.StatusCreated, res.SuccessResponse{
+		Message: "Template instantiated",
+		Data:    tasks,
+	})
+}
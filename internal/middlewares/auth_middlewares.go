@@ -1,9 +1,8 @@
 package middlewares
 
 import (
-	"net/http"
 	"strings"
-	"task-backend/internal/res"
+	"task-backend/internal/apperr"
 	my_utils "task-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -18,10 +17,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			userID := uuid.New().String()
 			newToken, err := my_utils.GenerateJWT(userID)
 			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, res.ErrorResponse{
-					Message: "Failed to generate token",
-					Error:   err.Error(),
-				})
+				abortWithError(c, apperr.Wrap(apperr.ErrInternal, apperr.CodeTokenGenerationFailed, "Failed to generate token", err))
 				return
 			}
 
@@ -33,30 +29,21 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, res.ErrorResponse{
-				Message: "Unauthorized",
-				Error:   "invalid token format",
-			})
+			abortWithError(c, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidTokenFormat, "Unauthorized", "invalid token format"))
 			return
 		}
 
 		tokenString := tokenParts[1]
 		claims, err := my_utils.ValidateJWT(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, res.ErrorResponse{
-				Message: "Unauthorized",
-				Error:   err.Error(),
-			})
+			abortWithError(c, apperr.Wrap(apperr.ErrUnauthorized, apperr.CodeInvalidToken, "Unauthorized", err))
 			return
 		}
 		// extract user_id from JWT claims
 		if userID, ok := claims["user_id"].(string); ok {
 			c.Set("userID", userID)
 		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, res.ErrorResponse{
-				Message: "Invalid token claims",
-				Error:   "user_id claim missing or invalid",
-			})
+			abortWithError(c, apperr.New(apperr.ErrUnauthorized, apperr.CodeInvalidTokenClaims, "Invalid token claims", "user_id claim missing or invalid"))
 			return
 		}
 
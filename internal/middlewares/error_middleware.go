@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+	"task-backend/internal/res"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders the last error attached with c.Error as a problem
+// response. Handlers and middlewares only report errors; mapping them to
+// HTTP statuses happens here.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		if res.StatusFor(err) == http.StatusInternalServerError {
+			log.Printf("request %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		res.WriteError(c, err)
+	}
+}
+
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
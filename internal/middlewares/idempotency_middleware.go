@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodySize bounds the request bodies that are read to be
+// fingerprinted. Requests with idempotency keys carry JSON, not files.
+const maxIdempotentBodySize = 1 << 20
+
+// replayedHeaders are the response headers stored with an idempotent
+// response and sent again when it is replayed.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location", "Undo-Token"}
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, userID, key, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, userID, key string, rec models.IdempotencyRecord) error
+	Release(ctx context.Context, userID, key string) error
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency honors the Idempotency-Key header. It must run after
+// AuthMiddleware because keys are scoped per user. Only successful
+// responses are stored; a failed request releases its key so the client
+// can retry it.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperr.New(apperr.ErrInvalid, apperr.CodeInvalidIdempotencyKey, "Invalid idempotency key",
+				"Idempotency-Key must not exceed 255 characters"))
+			return
+		}
+
+		userID := c.GetString("userID")
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortWithError(c, apperr.New(apperr.ErrTooLarge, apperr.CodeRequestTooLarge, "Request too large",
+				"the request body exceeds 1 MiB"))
+			return
+		}
+		if err != nil {
+			abortWithError(c, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.FullPath()+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		ctx := c.Request.Context()
+		rec, reserved, err := store.Reserve(ctx, userID, key, fingerprint, ttl)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		if !reserved {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				abortWithError(c, apperr.New(apperr.ErrUnprocessable, apperr.CodeIdempotencyKeyReused, "Idempotency key reused",
+					"this Idempotency-Key was already used with a different request body"))
+			case !rec.Completed:
+				abortWithError(c, apperr.New(apperr.ErrConflict, apperr.CodeIdempotencyInProgress, "Request in progress",
+					"a request with this Idempotency-Key is still being processed"))
+			default:
+				replay(c, rec)
+			}
+			return
+		}
+
+		// The outcome must be recorded even if the client has gone away or
+		// the handler panics, otherwise the key would stay reserved until it
+		// expires.
+		ctx = context.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				_ = store.Release(ctx, userID, key)
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if len(c.Errors) > 0 || status < http.StatusOK || status >= http.StatusMultipleChoices {
+			_ = store.Release(ctx, userID, key)
+			return
+		}
+
+		header := make(map[string]string)
+		for _, h := range replayedHeaders {
+			if v := recorder.Header().Get(h); v != "" {
+				header[h] = v
+			}
+		}
+		_ = store.Complete(ctx, userID, key, models.IdempotencyRecord{
+			Status: status,
+			Header: header,
+			Body:   recorder.body.Bytes(),
+		})
+	}
+}
+
+func replay(c *gin.Context, rec models.IdempotencyRecord) {
+	for h, v := range rec.Header {
+		c.Header(h, v)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(rec.Status, rec.Header["Content-Type"], rec.Body)
+	c.Abort()
+}
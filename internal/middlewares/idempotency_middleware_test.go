@@ -0,0 +1,64 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-backend/internal/middlewares"
+	"task-backend/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newIdempotentRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(middlewares.ErrorHandler())
+	r.Use(func(c *gin.Context) { c.Set("userID", "user1") })
+	r.POST("/tasks", middlewares.Idempotency(storage.NewIdempotencyStore(), time.Hour), handler)
+	return r
+}
+
+func sendWithKey(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(body))
+	req.Header.Set("Idempotency-Key", "key-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReleasesKeyOnPanic(t *testing.T) {
+	panicking := true
+	r := newIdempotentRouter(func(c *gin.Context) {
+		if panicking {
+			panic("handler failed")
+		}
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	if w := sendWithKey(r, `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the panic to be recovered as 500, got %d", w.Code)
+	}
+	panicking = false
+	if w := sendWithKey(r, `{}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected the retry to run once the key is released, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestIdempotency_BodyLimit(t *testing.T) {
+	r := newIdempotentRouter(func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	body := `{"title": "` + strings.Repeat("a", 1<<20) + `"}`
+	if w := sendWithKey(r, body); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected oversized bodies to be rejected with 413, got %d", w.Code)
+	}
+	if w := sendWithKey(r, `{"title": "small"}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected a rejected body not to reserve the key, got %d", w.Code)
+	}
+}
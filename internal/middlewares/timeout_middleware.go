@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutConfig sets the deadline attached to each request context. Routes
+// are keyed by method and route pattern, e.g. "GET /tasks/:id".
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func (cfg TimeoutConfig) For(method, route string) time.Duration {
+	if d, ok := cfg.Routes[method+" "+route]; ok {
+		return d
+	}
+	return cfg.Default
+}
+
+// ParseRouteTimeouts parses a comma separated list of "METHOD /route=duration"
+// entries such as "GET /tasks=2s,POST /tasks=10s".
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	routes := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("route timeout %q: missing '='", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("route timeout %q: %w", entry, err)
+		}
+		routes[strings.Join(strings.Fields(route), " ")] = d
+	}
+	return routes, nil
+}
+
+func Timeout(cfg TimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := cfg.For(c.Request.Method, c.FullPath())
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
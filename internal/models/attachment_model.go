@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Attachment describes a file attached to a task. The content lives in a
+// blob store under BlobKey.
+type Attachment struct {
+	ID          uint64
+	TaskID      uint64
+	Name        string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+	BlobKey     string `json:"-"`
+}
@@ -0,0 +1,31 @@
+package models
+
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// BatchOp is a single write in a batch. TaskID and ExpectedVersion are
+// ignored for creates; a zero ExpectedVersion skips the version check.
+// ParentOp, when positive, makes a created task a subtask of the task
+// created by the earlier op at index ParentOp-1.
+type BatchOp struct {
+	Kind            BatchOpKind
+	TaskID          uint64
+	Task            Task
+	ExpectedVersion uint64
+	ParentOp        int
+}
+
+// BatchResult is the outcome of the BatchOp at the same index. Task holds
+// the created or updated task, or the deleted one for deletes, and Previous
+// the task as it was before an update or delete. Results of a batch that
+// was not committed describe what would have happened.
+type BatchResult struct {
+	Task     Task
+	Previous Task
+	Err      error
+}
@@ -0,0 +1,80 @@
+package models
+
+// Board shows the tasks of a project, or of all projects when ProjectID is
+// zero, as cards in ordered columns. A task is shown in the first column it
+// fits; tasks that fit no column are left off the board.
+type Board struct {
+	ID        uint64
+	UserID    string
+	Name      string
+	ProjectID uint64
+	// StateField is a select custom field whose options serve as the custom
+	// states columns are mapped to.
+	StateField uint64
+	Columns    []BoardColumn
+}
+
+// BoardColumn holds the tasks with Status and, when State is set, with the
+// value State in the board's state field. A column with neither set holds
+// every task. A positive WIPLimit caps how many tasks the column holds;
+// moves into a full column are rejected.
+type BoardColumn struct {
+	ID       uint64
+	Name     string
+	Status   TaskStatus
+	State    string
+	WIPLimit int
+}
+
+// Fits reports whether task belongs in the column of a board whose state
+// field is stateField.
+func (c BoardColumn) Fits(task Task, stateField uint64) bool {
+	switch c.Status {
+	case StatusOpen:
+		if task.Completed {
+			return false
+		}
+	case StatusCompleted:
+		if !task.Completed {
+			return false
+		}
+	}
+	if c.State != "" {
+		state, _ := task.CustomFields[stateField].(string)
+		return state == c.State
+	}
+	return true
+}
+
+// Column returns the column task is shown in.
+func (b Board) Column(task Task) (BoardColumn, bool) {
+	if b.ProjectID != 0 && task.ProjectID != b.ProjectID {
+		return BoardColumn{}, false
+	}
+	for _, c := range b.Columns {
+		if c.Fits(task, b.StateField) {
+			return c, true
+		}
+	}
+	return BoardColumn{}, false
+}
+
+// Place returns task changed to fit column: its completion follows the
+// column's status and its state field is set to the column's state.
+func (b Board) Place(task Task, column BoardColumn) Task {
+	switch column.Status {
+	case StatusOpen:
+		task.Completed = false
+	case StatusCompleted:
+		task.Completed = true
+	}
+	if column.State != "" {
+		values := make(map[uint64]any, len(task.CustomFields)+1)
+		for k, v := range task.CustomFields {
+			values[k] = v
+		}
+		values[b.StateField] = column.State
+		task.CustomFields = values
+	}
+	return task
+}
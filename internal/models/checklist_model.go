@@ -0,0 +1,14 @@
+package models
+
+// ChecklistItem is a lightweight step of a task. IDs are unique within the
+// task only.
+type ChecklistItem struct {
+	ID      uint64
+	Text    string
+	Checked bool
+}
+
+type ChecklistProgress struct {
+	Done  int
+	Total int
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID        uint64
+	TaskID    uint64
+	AuthorID  string
+	Body      string
+	CreatedAt time.Time
+	EditedAt  *time.Time
+}
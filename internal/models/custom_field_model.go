@@ -0,0 +1,134 @@
+package models
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type FieldType string
+
+const (
+	FieldText     FieldType = "text"
+	FieldNumber   FieldType = "number"
+	FieldDate     FieldType = "date"
+	FieldSelect   FieldType = "select"
+	FieldCheckbox FieldType = "checkbox"
+)
+
+// MaxFieldTextLength is the longest value a text field takes, in characters.
+const MaxFieldTextLength = 500
+
+// DateLayout is the format of date field values. Dates in this layout sort
+// lexicographically.
+const DateLayout = "2006-01-02"
+
+// CustomField is a user-defined task field. Tasks store its values in
+// CustomFields keyed by the field's ID: a string for text, date and select
+// fields, a float64 for numbers and a bool for checkboxes.
+type CustomField struct {
+	ID     uint64
+	UserID string
+	Name   string
+	Type   FieldType
+	// Options are the values a select field accepts.
+	Options []string
+}
+
+// Normalize checks that v, as decoded from JSON, is a valid value for the
+// field and returns it in its stored form.
+func (f CustomField) Normalize(v any) (any, error) {
+	switch f.Type {
+	case FieldText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be text", f.Name)
+		}
+		if utf8.RuneCountInString(s) > MaxFieldTextLength {
+			return nil, fmt.Errorf("%s must not exceed %d characters", f.Name, MaxFieldTextLength)
+		}
+		return s, nil
+	case FieldNumber:
+		n, ok := v.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("%s must be a number", f.Name)
+		}
+		return n, nil
+	case FieldDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a date", f.Name)
+		}
+		if _, err := time.Parse(DateLayout, s); err != nil {
+			return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", f.Name)
+		}
+		return s, nil
+	case FieldSelect:
+		s, ok := v.(string)
+		if !ok || !slices.Contains(f.Options, s) {
+			return nil, fmt.Errorf("%s must be one of %s", f.Name, strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	case FieldCheckbox:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", f.Name)
+		}
+		return b, nil
+	default:
+		return nil, errors.New("unknown field type")
+	}
+}
+
+// ParseValue parses a value given as text, such as a query parameter.
+func (f CustomField) ParseValue(s string) (any, error) {
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", f.Name)
+		}
+		return f.Normalize(n)
+	case FieldCheckbox:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", f.Name)
+		}
+		return b, nil
+	default:
+		return f.Normalize(s)
+	}
+}
+
+// CompareFieldValues orders two stored values of the same field. Missing
+// values sort after present ones.
+func CompareFieldValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch a := a.(type) {
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		if a {
+			return 1
+		}
+		return -1
+	default:
+		return strings.Compare(strings.ToLower(a.(string)), strings.ToLower(b.(string)))
+	}
+}
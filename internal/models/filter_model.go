@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+type TaskStatus string
+
+const (
+	StatusOpen      TaskStatus = "open"
+	StatusCompleted TaskStatus = "completed"
+)
+
+// DueWindow is a range of due dates relative to the time a listing is made.
+type DueWindow string
+
+const (
+	// DueOverdue selects open tasks whose due date has passed.
+	DueOverdue DueWindow = "overdue"
+	// DueToday selects tasks due on the current day.
+	DueToday DueWindow = "today"
+	// DueNext7Days selects tasks due from the start of the current day to
+	// the end of the sixth day after it.
+	DueNext7Days DueWindow = "next_7_days"
+	// DueNone selects tasks without a due date.
+	DueNone DueWindow = "none"
+)
+
+// Filter returns the filter selecting the tasks in the window, with days
+// starting at midnight in now's location. It reports false for unknown
+// windows.
+func (w DueWindow) Filter(now time.Time) (TaskFilter, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var f TaskFilter
+	switch w {
+	case DueOverdue:
+		f.DueBefore, f.Status = &now, StatusOpen
+	case DueToday:
+		end := today.AddDate(0, 0, 1)
+		f.DueAfter, f.DueBefore = &today, &end
+	case DueNext7Days:
+		end := today.AddDate(0, 0, 7)
+		f.DueAfter, f.DueBefore = &today, &end
+	case DueNone:
+		f.NoDueDate = true
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+// TaskFilter selects tasks in listings. The zero value matches every task.
+type TaskFilter struct {
+	ProjectID uint64
+	// LabelIDs restricts the listing to tasks carrying all of these labels,
+	// or any of them when AnyLabel is set.
+	LabelIDs []uint64
+	AnyLabel bool
+	Status   TaskStatus
+	// Query is a search in the language of package query that tasks must
+	// match as well. With IgnoreUnknownNames, labels and projects it names
+	// that don't exist match no task instead of failing the listing. Matches
+	// ignores it.
+	Query              string
+	IgnoreUnknownNames bool
+	// DueAfter and DueBefore restrict the listing to tasks due in
+	// [DueAfter, DueBefore); NoDueDate to tasks without a due date.
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	NoDueDate bool
+	// Due is a relative window that the service turns into the bounds above
+	// when the listing is made, with days starting in TimeZone. Matches
+	// ignores it.
+	Due      DueWindow
+	TimeZone string
+	// Fields restricts the listing to tasks with these custom field values.
+	// Text values match regardless of case.
+	Fields map[uint64]any
+	// SortField orders the matches by the values of a custom field,
+	// descending with SortDesc. Tasks without a value come last.
+	SortField uint64
+	SortDesc  bool
+}
+
+func (f TaskFilter) Matches(task Task) bool {
+	if f.ProjectID != 0 && task.ProjectID != f.ProjectID {
+		return false
+	}
+	if f.Status == StatusOpen && task.Completed || f.Status == StatusCompleted && !task.Completed {
+		return false
+	}
+	if !f.matchesDue(task.DueAt) {
+		return false
+	}
+	for id, want := range f.Fields {
+		got, ok := task.CustomFields[id]
+		if !ok || CompareFieldValues(got, want) != 0 {
+			return false
+		}
+	}
+	if len(f.LabelIDs) == 0 {
+		return true
+	}
+	for _, id := range f.LabelIDs {
+		if task.HasLabel(id) == f.AnyLabel {
+			return f.AnyLabel
+		}
+	}
+	return !f.AnyLabel
+}
+
+func (f TaskFilter) matchesDue(due *time.Time) bool {
+	if due == nil {
+		return f.DueAfter == nil && f.DueBefore == nil
+	}
+	if f.NoDueDate {
+		return false
+	}
+	if f.DueAfter != nil && due.Before(*f.DueAfter) {
+		return false
+	}
+	return f.DueBefore == nil || due.Before(*f.DueBefore)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request sent with an
+// Idempotency-Key so that retries can be answered with the same response.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Completed   bool
+	Status      int
+	Header      map[string]string
+	Body        []byte
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,8 @@
+package models
+
+type Label struct {
+	ID     uint64
+	UserID string
+	Name   string
+	Color  string
+}
@@ -0,0 +1,12 @@
+package models
+
+// Project is a list that tasks belong to. Every user has exactly one Inbox
+// project, which holds tasks created without a project and cannot be
+// archived or deleted.
+type Project struct {
+	ID       uint64
+	UserID   string
+	Name     string
+	Inbox    bool
+	Archived bool
+}
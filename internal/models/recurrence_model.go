@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Recurrence makes a task one occurrence of a series. Completing it creates
+// the next occurrence.
+type Recurrence struct {
+	// Rule is an RFC 5545 RRULE, evaluated in TimeZone.
+	Rule     string
+	TimeZone string
+	// Start is the due date the rule is anchored to.
+	Start time.Time
+	// SeriesID is the id of the first task of the series.
+	SeriesID uint64
+	// Index is the 1-based number of this occurrence within the series.
+	Index int
+	// Base holds the series values an occurrence had before it was edited on
+	// its own. The next occurrence is built from it instead of the task.
+	Base *OccurrenceBase
+}
+
+// GetRule and GetTimeZone return "" for tasks that don't recur.
+func (r *Recurrence) GetRule() string {
+	if r == nil {
+		return ""
+	}
+	return r.Rule
+}
+
+func (r *Recurrence) GetTimeZone() string {
+	if r == nil {
+		return ""
+	}
+	return r.TimeZone
+}
+
+type OccurrenceBase struct {
+	Title       string
+	Description string
+	DueAt       time.Time
+}
+
+// EditScope selects which occurrences of a recurring task an edit applies
+// to.
+type EditScope string
+
+const (
+	// ScopeThis changes only the edited occurrence.
+	ScopeThis EditScope = "this"
+	// ScopeFuture changes the edited occurrence and the ones after it. It is
+	// the default.
+	ScopeFuture EditScope = "future"
+)
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Revision records one change to the fields of a task that clients write.
+// Title, Description and Snapshot hold the task as it was after the change,
+// so any revision can be restored.
+type Revision struct {
+	Number      uint64
+	TaskID      uint64
+	UserID      string
+	Version     uint64
+	CreatedAt   time.Time
+	Changes     []FieldChange
+	Title       string
+	Description string
+	Snapshot    Task `json:"-"`
+}
@@ -1,8 +1,79 @@
 package models
 
+import "time"
+
 type Task struct {
 	ID          uint64
 	UserID      string
 	Title       string
 	Description string
+	Version     uint64
+	Priority    Priority
+	// Rank orders the task within its project's list. Clients reorder
+	// tasks through the move endpoint and never see it.
+	Rank      string `json:"-"`
+	ProjectID uint64
+	ParentID  uint64
+	LabelIDs  []uint64
+	// CustomFields holds the task's values of the user's custom fields,
+	// keyed by field ID.
+	CustomFields map[uint64]any
+	// DependsOn lists the tasks that must be completed before this one.
+	DependsOn []uint64
+	// Blocked and BlockedBy are computed on read from the open tasks in
+	// DependsOn.
+	Blocked   bool     `json:"blocked"`
+	BlockedBy []uint64 `json:"blocked_by"`
+	Checklist []ChecklistItem
+	// ChecklistCounter is the highest checklist item ID handed out so far.
+	// It never goes down, so the IDs of removed items are not reused.
+	ChecklistCounter uint64 `json:"-"`
+	// Progress counts the checked checklist items; it is computed for
+	// responses.
+	Progress    ChecklistProgress
+	Completed   bool
+	CompletedAt *time.Time
+	DueAt       *time.Time
+	Recurrence  *Recurrence
+	DeletedAt   *time.Time
+}
+
+// TaskNode is a task with its subtasks, as returned for task trees.
+type TaskNode struct {
+	Task
+	Children []TaskNode
+}
+
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// CompletionPolicy decides what happens when a task with open subtasks is
+// completed.
+type CompletionPolicy string
+
+const (
+	// CompleteBlocked rejects the completion. It is the default.
+	CompleteBlocked CompletionPolicy = "block"
+	// CompleteCascade completes the open subtasks along with the task.
+	CompleteCascade CompletionPolicy = "cascade"
+)
+
+func (t Task) Trashed() bool {
+	return t.DeletedAt != nil
+}
+
+func (t Task) HasLabel(labelID uint64) bool {
+	for _, id := range t.LabelIDs {
+		if id == labelID {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,24 @@
+package models
+
+// TemplateTask describes a task created from a template. DueOffset is the
+// number of days between the anchor date of an instantiation and the due
+// date, which falls at DueTime ("15:04") on that day or at its end when
+// DueTime is empty. Tasks without a DueOffset have no due date.
+type TemplateTask struct {
+	Title       string
+	Description string
+	LabelIDs    []uint64
+	Checklist   []string
+	DueOffset   *int
+	DueTime     string
+}
+
+// Template is a task with optional subtasks that can be created again and
+// again.
+type Template struct {
+	ID     uint64
+	UserID string
+	Name   string
+	TemplateTask
+	Subtasks []TemplateTask
+}
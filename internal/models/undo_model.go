@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// UndoStep reverts one change. Version is the version the change left the
+// task at; the step only applies while the task is still at that version.
+// Previous is the task before the change, or the zero Task when the change
+// created it. Undoing a step keeps the task's current place in its list
+// unless Reorder marks the step as having moved it there.
+type UndoStep struct {
+	TaskID   uint64
+	Version  uint64
+	Previous Task
+	Reorder  bool
+}
+
+type UndoEntry struct {
+	UserID    string
+	Steps     []UndoStep
+	ExpiresAt time.Time
+}
@@ -0,0 +1,32 @@
+package models
+
+// View is a saved task query. It is evaluated each time its tasks are
+// listed, so relative due windows follow the current date. Query is kept up
+// to date when the labels and projects it names are renamed.
+type View struct {
+	ID        uint64
+	UserID    string
+	Name      string
+	ProjectID uint64
+	LabelIDs  []uint64
+	AnyLabel  bool
+	Status    TaskStatus
+	Due       DueWindow
+	Query     string
+	TimeZone  string
+}
+
+// Filter returns the task filter the view stands for. Labels and projects
+// named in Query that have been deleted since match no task.
+func (v View) Filter() TaskFilter {
+	return TaskFilter{
+		ProjectID:          v.ProjectID,
+		LabelIDs:           v.LabelIDs,
+		AnyLabel:           v.AnyLabel,
+		Status:             v.Status,
+		Due:                v.Due,
+		Query:              v.Query,
+		IgnoreUnknownNames: true,
+		TimeZone:           v.TimeZone,
+	}
+}
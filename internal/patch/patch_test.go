@@ -0,0 +1,106 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested object", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"non object patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"null nested member creates object", `{"e":null}`, `{"a":{"bb":{"ccc":null}}}`, `{"e":null,"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch(tt.patch).Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Apply returned error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`, nil},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"append array element", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`, nil},
+		{"remove member", `{"foo":"bar","baz":"qux"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"replace member", `{"foo":"bar"}`, `[{"op":"replace","path":"/foo","value":"baz"}]`, `{"foo":"baz"}`, nil},
+		{"move member", `{"foo":{"bar":"baz"},"qux":{}}`, `[{"op":"move","from":"/foo/bar","path":"/qux/thud"}]`, `{"foo":{},"qux":{"thud":"baz"}}`, nil},
+		{"copy member", `{"foo":{"bar":"baz"}}`, `[{"op":"copy","from":"/foo","path":"/qux"}]`, `{"foo":{"bar":"baz"},"qux":{"bar":"baz"}}`, nil},
+		{"test passes", `{"foo":"bar"}`, `[{"op":"test","path":"/foo","value":"bar"},{"op":"replace","path":"/foo","value":"baz"}]`, `{"foo":"baz"}`, nil},
+		{"add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`, nil},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`, nil},
+		{"test fails", `{"foo":"bar"}`, `[{"op":"test","path":"/foo","value":"baz"}]`, "", ErrTestFailed},
+		{"replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"qux"}]`, "", ErrInvalidPatch},
+		{"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, "", ErrInvalidPatch},
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", ErrInvalidPatch},
+		{"index out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":"qux"}]`, "", ErrInvalidPatch},
+		{"leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, "", ErrInvalidPatch},
+		{"unknown op", `{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`, "", ErrInvalidPatch},
+		{"missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, "", ErrInvalidPatch},
+		{"move into own child", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, "", ErrInvalidPatch},
+		{"not an array", `{"foo":"bar"}`, `{"op":"add"}`, "", ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch(tt.patch).Apply([]byte(tt.doc))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply returned error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatch_FailedOperationLeavesDocumentUnchanged(t *testing.T) {
+	doc := []byte(`{"foo":"bar"}`)
+	_, err := JSONPatch(`[{"op":"replace","path":"/foo","value":"baz"},{"op":"test","path":"/foo","value":"bar"}]`).Apply(doc)
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("expected test failure, got %v", err)
+	}
+	assertJSONEqual(t, doc, `{"foo":"bar"}`)
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"task-backend/internal/models"
+	"time"
+)
+
+// Env is what evaluating a query needs besides the tasks.
+type Env struct {
+	// Labels and Projects map lower case names to IDs. Project names need
+	// not be unique; a name matches all projects that have it.
+	Labels   map[string]uint64
+	Projects map[string][]uint64
+	// Now is the time relative due dates are resolved against. Days start
+	// at midnight in its location.
+	Now time.Time
+	// IgnoreUnknown makes labels and projects that don't exist match no task
+	// instead of failing the query.
+	IgnoreUnknown bool
+}
+
+// Matcher reports whether a task matches a query.
+type Matcher func(models.Task) bool
+
+// Compile parses a query and resolves the names it uses.
+func Compile(src string, env Env) (Matcher, error) {
+	root, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{src: src, env: env}
+	return c.compile(root)
+}
+
+type compiler struct {
+	src string
+	env Env
+}
+
+func (c *compiler) compile(n Node) (Matcher, error) {
+	switch n := n.(type) {
+	case And:
+		ms, err := c.compileAll(n.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		return func(t models.Task) bool {
+			for _, m := range ms {
+				if !m(t) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case Or:
+		ms, err := c.compileAll(n.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		return func(t models.Task) bool {
+			for _, m := range ms {
+				if m(t) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case Not:
+		m, err := c.compile(n.Node)
+		if err != nil {
+			return nil, err
+		}
+		return func(t models.Task) bool { return !m(t) }, nil
+	case Text:
+		value := strings.ToLower(n.Value)
+		return func(t models.Task) bool {
+			return strings.Contains(strings.ToLower(t.Title), value) || strings.Contains(strings.ToLower(t.Description), value)
+		}, nil
+	case Term:
+		return c.compileTerm(n)
+	}
+	panic("query: unknown node")
+}
+
+func (c *compiler) compileAll(nodes []Node) ([]Matcher, error) {
+	ms := make([]Matcher, len(nodes))
+	for i, n := range nodes {
+		m, err := c.compile(n)
+		if err != nil {
+			return nil, err
+		}
+		ms[i] = m
+	}
+	return ms, nil
+}
+
+func (c *compiler) compileTerm(term Term) (Matcher, error) {
+	switch term.Field {
+	case FieldStatus:
+		completed := term.Value == "completed"
+		return func(t models.Task) bool { return t.Completed == completed }, nil
+	case FieldIs:
+		switch term.Value {
+		case "blocked":
+			return func(t models.Task) bool { return t.Blocked }, nil
+		case "recurring":
+			return func(t models.Task) bool { return t.Recurrence != nil }, nil
+		default:
+			return func(t models.Task) bool { return t.ParentID != 0 }, nil
+		}
+	case FieldLabel:
+		id, exists := c.env.Labels[strings.ToLower(term.Value)]
+		if !exists {
+			return never, c.unknown(term)
+		}
+		return func(t models.Task) bool { return t.HasLabel(id) }, nil
+	case FieldProject:
+		ids, exists := c.env.Projects[strings.ToLower(term.Value)]
+		if !exists {
+			return never, c.unknown(term)
+		}
+		return func(t models.Task) bool { return slices.Contains(ids, t.ProjectID) }, nil
+	case FieldPriority:
+		want := slices.Index(priorities, term.Value)
+		return func(t models.Task) bool {
+			return compare(priorityRank(t.Priority), want, term.Op)
+		}, nil
+	default:
+		return c.compileDue(term), nil
+	}
+}
+
+func never(models.Task) bool { return false }
+
+// unknown reports a term naming a label or project that doesn't exist,
+// unless the environment ignores those.
+func (c *compiler) unknown(term Term) error {
+	if c.env.IgnoreUnknown {
+		return nil
+	}
+	return errorAt(c.src, term.Pos, "there is no %s named %q", term.Field, term.Value)
+}
+
+func priorityRank(p models.Priority) int {
+	if p == "" {
+		return 0
+	}
+	return slices.Index(priorities, string(p))
+}
+
+func compare(got, want int, op Op) bool {
+	switch op {
+	case OpLt:
+		return got < want
+	case OpLe:
+		return got <= want
+	case OpGt:
+		return got > want
+	case OpGe:
+		return got >= want
+	}
+	return got == want
+}
+
+// compileDue matches due dates against a relative window or against the day
+// a date stands for: due<D is before the day starts, due>D after it ends.
+func (c *compiler) compileDue(term Term) Matcher {
+	if window, ok := models.DueWindow(term.Value).Filter(c.env.Now); ok {
+		return window.Matches
+	}
+
+	day, _ := time.ParseInLocation(dateLayout, term.Value, c.env.Now.Location())
+	start, end := day, day.AddDate(0, 0, 1)
+	var after, before *time.Time
+	switch term.Op {
+	case OpEq:
+		after, before = &start, &end
+	case OpLt:
+		before = &start
+	case OpLe:
+		before = &end
+	case OpGt:
+		after = &end
+	case OpGe:
+		after = &start
+	}
+	return models.TaskFilter{DueAfter: after, DueBefore: before}.Matches
+}
@@ -0,0 +1,324 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	statuses   = []string{"open", "completed"}
+	flags      = []string{"blocked", "recurring", "subtask"}
+	priorities = []string{"none", "low", "medium", "high", "urgent"}
+	dueWindows = []string{"today", "overdue", "next_7_days", "none"}
+)
+
+// dateLayout is the format of dates in queries.
+const dateLayout = "2006-01-02"
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokMinus
+	tokLParen
+	tokRParen
+)
+
+// token is a lexical token. text is the unquoted content of phrases and
+// of a quoted value that directly follows a word such as label:, in which
+// case quoted is set and valuePos marks its opening quote.
+type token struct {
+	kind     tokenKind
+	text     string
+	pos, end int
+	value    string
+	valuePos int
+	quoted   bool
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// Parse parses a query. Only its syntax and the fields and fixed values it
+// uses are checked; label and project names are left to Compile.
+func Parse(src string) (Node, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return And{}, nil
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokRParen {
+		return nil, errorAt(src, p.tok.pos, "unexpected \")\" without a matching \"(\"")
+	}
+	return n, nil
+}
+
+func (p *parser) isOr() bool {
+	return p.tok.kind == tokWord && p.tok.text == "OR" && !p.tok.quoted
+}
+
+func (p *parser) parseOr() (Node, error) {
+	var nodes []Node
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.isOr() {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+	for p.tok.kind != tokEOF && p.tok.kind != tokRParen && !p.isOr() {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	switch len(nodes) {
+	case 0:
+		if p.isOr() {
+			return nil, errorAt(p.src, p.tok.pos, "expected a term before OR")
+		}
+		return nil, errorAt(p.src, p.tok.pos, "expected a term")
+	case 1:
+		return nodes[0], nil
+	}
+	return And{Nodes: nodes}, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.tok.kind == tokMinus {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	switch tok.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, errorAt(p.src, tok.pos, "\"(\" is never closed")
+		}
+		return n, p.next()
+	case tokPhrase:
+		return Text{Value: tok.text, Phrase: true}, nil
+	default:
+		return p.parseWord(tok)
+	}
+}
+
+// parseWord turns a word into a term when it starts with a field name and an
+// operator, and into text otherwise. The bare word blocked is short for
+// is:blocked.
+func (p *parser) parseWord(tok token) (Node, error) {
+	i := strings.IndexAny(tok.text, ":<>")
+	if i < 0 && strings.EqualFold(tok.text, "blocked") {
+		return Term{Field: FieldIs, Op: OpEq, Value: "blocked", Pos: tok.pos, End: tok.end}, nil
+	}
+	if i < 0 {
+		return Text{Value: tok.text}, nil
+	}
+
+	field := Field(strings.ToLower(tok.text[:i]))
+	if !slices.Contains(fields, field) {
+		return nil, errorAt(p.src, tok.pos, "unknown field %q, expected one of %s", tok.text[:i], joinFields())
+	}
+
+	op, rest := OpEq, tok.text[i+1:]
+	switch tok.text[i] {
+	case '<':
+		op = OpLt
+	case '>':
+		op = OpGt
+	}
+	if op != OpEq && strings.HasPrefix(rest, "=") {
+		op, rest = op+"=", rest[1:]
+	}
+	opPos := tok.pos + i
+	if op != OpEq && field != FieldPriority && field != FieldDue {
+		return nil, errorAt(p.src, opPos, "%s cannot be compared with %q, use %s:", field, op, field)
+	}
+
+	term := Term{Field: field, Op: op, Value: rest, Pos: tok.pos + len(tok.text) - len(rest), End: tok.end}
+	if tok.quoted {
+		if rest != "" {
+			return nil, errorAt(p.src, tok.valuePos, "unexpected quote in the middle of a value")
+		}
+		term.Value, term.Pos = tok.value, tok.valuePos
+	}
+	if term.Value == "" {
+		return nil, errorAt(p.src, opPos, "missing value after %s%s", field, op)
+	}
+	return term, p.checkValue(&term)
+}
+
+// checkValue validates the value of a term against its field and lowers the
+// values of fields with a fixed set of values.
+func (p *parser) checkValue(t *Term) error {
+	var allowed []string
+	switch t.Field {
+	case FieldStatus:
+		allowed = statuses
+	case FieldIs:
+		allowed = flags
+	case FieldPriority:
+		allowed = priorities
+	case FieldDue:
+		if _, err := time.Parse(dateLayout, t.Value); err == nil {
+			return nil
+		}
+		if t.Op != OpEq {
+			return p.errorAtValue(t, "due%s needs a date such as 2026-11-01", t.Op)
+		}
+		allowed = dueWindows
+	default:
+		return nil
+	}
+
+	value := strings.ToLower(t.Value)
+	if !slices.Contains(allowed, value) {
+		if t.Field == FieldDue {
+			return p.errorAtValue(t, "due must be a date such as 2026-11-01 or one of %s", strings.Join(allowed, ", "))
+		}
+		return p.errorAtValue(t, "%s must be one of %s", t.Field, strings.Join(allowed, ", "))
+	}
+	t.Value = value
+	return nil
+}
+
+func (p *parser) errorAtValue(t *Term, format string, args ...any) error {
+	return errorAt(p.src, t.Pos, format, args...)
+}
+
+func joinFields() string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// endsWord reports whether c ends a word.
+func endsWord(c byte) bool {
+	return isSpace(c) || c == '(' || c == ')' || c == '"'
+}
+
+// next reads the next token into p.tok.
+func (p *parser) next() error {
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if start == len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start, end: start}
+		return nil
+	}
+
+	switch c := p.src[start]; {
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, pos: start, end: p.pos}
+		return nil
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, pos: start, end: p.pos}
+		return nil
+	case c == '"':
+		text, err := p.readPhrase()
+		if err != nil {
+			return err
+		}
+		p.tok = token{kind: tokPhrase, text: text, pos: start, end: p.pos}
+		return nil
+	case c == '-':
+		p.pos++
+		if p.pos == len(p.src) || isSpace(p.src[p.pos]) || p.src[p.pos] == ')' {
+			return errorAt(p.src, start, "\"-\" must be followed by the term to exclude")
+		}
+		p.tok = token{kind: tokMinus, pos: start, end: p.pos}
+		return nil
+	}
+
+	for p.pos < len(p.src) && !endsWord(p.src[p.pos]) {
+		p.pos++
+	}
+	p.tok = token{kind: tokWord, text: p.src[start:p.pos], pos: start, end: p.pos}
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		if !strings.ContainsAny(p.tok.text, ":<>") {
+			return errorAt(p.src, p.pos, "a phrase must be separated from the word before it by a space")
+		}
+		p.tok.valuePos = p.pos
+		value, err := p.readPhrase()
+		if err != nil {
+			return err
+		}
+		p.tok.value, p.tok.quoted, p.tok.end = value, true, p.pos
+	}
+	return nil
+}
+
+// readPhrase reads a quoted string starting at p.pos. Within it, \" stands
+// for a quote and \\ for a backslash.
+func (p *parser) readPhrase() (string, error) {
+	start := p.pos
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '"' || p.src[p.pos+1] == '\\'):
+			p.pos++
+			b.WriteByte(p.src[p.pos])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errorAt(p.src, start, "phrase is never closed, add a closing quote")
+}
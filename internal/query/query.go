@@ -0,0 +1,158 @@
+// Package query parses and evaluates task search queries such as
+//
+//	status:open label:work due<2026-11-01 "exact phrase" -blocked
+//
+// A query is a list of terms separated by spaces that must all match:
+//
+//	word, "a phrase"      the title or description contains it, ignoring case
+//	status:open           open or completed tasks
+//	is:blocked            blocked, recurring or subtask tasks; a bare
+//	                      blocked is short for is:blocked, "blocked" searches
+//	                      the text
+//	label:work            tasks carrying the label, label:"two words" for
+//	                      names with spaces
+//	project:work          tasks of the project
+//	priority:high         also priority>=medium and the other comparisons
+//	due:2026-11-01        tasks due that day; also due:today, due:overdue,
+//	                      due:next_7_days and due:none
+//	due<2026-11-01        tasks due before that day; also <=, > and >=
+//
+// A leading "-" negates a term, OR matches either side and parentheses group
+// terms. OR binds more loosely than the implicit AND, so "a b OR c" means
+// "(a b) OR c".
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Node is a node of a parsed query: And, Or, Not, Text or Term.
+type Node interface {
+	node()
+}
+
+// And matches tasks that match all of Nodes; an empty And matches every
+// task.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches tasks that match any of Nodes.
+type Or struct {
+	Nodes []Node
+}
+
+type Not struct {
+	Node Node
+}
+
+// Text matches tasks whose title or description contains Value, ignoring
+// case. Phrase records whether it was quoted.
+type Text struct {
+	Value  string
+	Phrase bool
+}
+
+type Field string
+
+const (
+	FieldStatus   Field = "status"
+	FieldIs       Field = "is"
+	FieldLabel    Field = "label"
+	FieldProject  Field = "project"
+	FieldPriority Field = "priority"
+	FieldDue      Field = "due"
+)
+
+var fields = []Field{FieldStatus, FieldIs, FieldLabel, FieldProject, FieldPriority, FieldDue}
+
+type Op string
+
+const (
+	OpEq Op = ":"
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+)
+
+// Term compares a field with a value, as in label:work or due<2026-11-01.
+// Values of fields with a fixed set of values are lower case. Pos and End
+// are the byte offsets of the value in the query, quotes included.
+type Term struct {
+	Field    Field
+	Op       Op
+	Value    string
+	Pos, End int
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Text) node() {}
+func (Term) node() {}
+
+// Error is a problem with a query at Column, counted in characters from 1.
+type Error struct {
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+func errorAt(src string, pos int, format string, args ...any) *Error {
+	return &Error{Column: utf8.RuneCountInString(src[:pos]) + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Quote returns value in the form a query needs it in, quoted when it
+// contains spaces, parentheses or quotes.
+func Quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\r\n()\"\\") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// ReplaceValues rewrites the terms of src on field whose value equals old,
+// ignoring case, to name new instead. Queries that don't parse are returned
+// unchanged.
+func ReplaceValues(src string, field Field, old, new string) string {
+	root, err := Parse(src)
+	if err != nil {
+		return src
+	}
+
+	var terms []Term
+	walk(root, func(t Term) {
+		if t.Field == field && strings.EqualFold(t.Value, old) {
+			terms = append(terms, t)
+		}
+	})
+	for i := len(terms) - 1; i >= 0; i-- {
+		src = src[:terms[i].Pos] + Quote(new) + src[terms[i].End:]
+	}
+	return src
+}
+
+// walk calls fn for the terms of n in the order they appear in the query.
+func walk(n Node, fn func(Term)) {
+	switch n := n.(type) {
+	case And:
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+	case Or:
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+	case Not:
+		walk(n.Node, fn)
+	case Term:
+		fn(n)
+	}
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"task-backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Node
+	}{
+		{"", And{}},
+		{"  invoice ", Text{Value: "invoice"}},
+		{`status:open label:work due<2026-11-01 "exact phrase" -blocked`, And{Nodes: []Node{
+			Term{Field: FieldStatus, Op: OpEq, Value: "open", Pos: 7, End: 11},
+			Term{Field: FieldLabel, Op: OpEq, Value: "work", Pos: 18, End: 22},
+			Term{Field: FieldDue, Op: OpLt, Value: "2026-11-01", Pos: 27, End: 37},
+			Text{Value: "exact phrase", Phrase: true},
+			Not{Node: Term{Field: FieldIs, Op: OpEq, Value: "blocked", Pos: 54, End: 61}},
+		}}},
+		{`Label:"needs review" PRIORITY>=High`, And{Nodes: []Node{
+			Term{Field: FieldLabel, Op: OpEq, Value: "needs review", Pos: 6, End: 20},
+			Term{Field: FieldPriority, Op: OpGe, Value: "high", Pos: 31, End: 35},
+		}}},
+		{"a b OR c", Or{Nodes: []Node{
+			And{Nodes: []Node{Text{Value: "a"}, Text{Value: "b"}}},
+			Text{Value: "c"},
+		}}},
+		{"-(is:blocked OR due:none) e-mail", And{Nodes: []Node{
+			Not{Node: Or{Nodes: []Node{
+				Term{Field: FieldIs, Op: OpEq, Value: "blocked", Pos: 5, End: 12},
+				Term{Field: FieldDue, Op: OpEq, Value: "none", Pos: 20, End: 24},
+			}}},
+			Text{Value: "e-mail"},
+		}}},
+		{`"say \"hi\" \\ bye" or`, And{Nodes: []Node{Text{Value: `say "hi" \ bye`, Phrase: true}, Text{Value: "or"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse returned %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"label:work stauts:open", `column 12: unknown field "stauts", expected one of status, is, label, project, priority, due`},
+		{"status:done", "column 8: status must be one of open, completed"},
+		{"is:", "column 3: missing value after is:"},
+		{"label<work", `column 6: label cannot be compared with "<", use label:`},
+		{"due<=tomorrow", "column 6: due<= needs a date such as 2026-11-01"},
+		{"due:2026-13-01", "column 5: due must be a date such as 2026-11-01 or one of today, overdue, next_7_days, none"},
+		{`plan "ahead`, "column 6: phrase is never closed, add a closing quote"},
+		{`plan"ahead"`, "column 5: a phrase must be separated from the word before it by a space"},
+		{"(a OR b", `column 1: "(" is never closed`},
+		{"a) b", `column 2: unexpected ")" without a matching "("`},
+		{"OR a", "column 1: expected a term before OR"},
+		{"a OR", "column 5: expected a term"},
+		{"a - b", `column 3: "-" must be followed by the term to exclude`},
+		{"()", "column 2: expected a term"},
+		{"überfällig stauts:x", `column 12: unknown field "stauts", expected one of status, is, label, project, priority, due`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var qe *Error
+			if !errors.As(err, &qe) {
+				t.Fatalf("Parse(%q) returned %v, want a query error", tt.src, err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, berlin)
+	due := func(month time.Month, day, hour int) *time.Time {
+		d := time.Date(2026, month, day, hour, 0, 0, 0, berlin)
+		return &d
+	}
+
+	tasks := []models.Task{
+		{ID: 1, Title: "Send invoice", Description: "October invoice", LabelIDs: []uint64{10}, ProjectID: 20, Priority: models.PriorityHigh, DueAt: due(10, 18, 12)},
+		{ID: 2, Title: "Review budget", Description: "Needs an exact phrase", LabelIDs: []uint64{10, 11}, ProjectID: 21, Priority: models.PriorityLow, DueAt: due(10, 31, 23), Blocked: true},
+		{ID: 3, Title: "Paid invoice", Description: "Done", ProjectID: 22, Completed: true, DueAt: due(11, 1, 0)},
+		{ID: 4, Title: "Water plants", Description: "Both balconies", ParentID: 1, Recurrence: &models.Recurrence{}},
+	}
+	env := Env{
+		Labels:   map[string]uint64{"work": 10, "needs review": 11},
+		Projects: map[string][]uint64{"office": {20, 21}, "home": {22}},
+		Now:      now,
+	}
+
+	tests := []struct {
+		src  string
+		want []uint64
+	}{
+		{"", []uint64{1, 2, 3, 4}},
+		{`status:open label:work due<2026-11-01 "exact phrase" -blocked`, nil},
+		{`status:open label:work due<2026-11-01 -is:blocked`, []uint64{1}},
+		{"BLOCKED", []uint64{2}},
+		{`"blocked"`, nil},
+		{"INVOICE", []uint64{1, 3}},
+		{`"exact phrase"`, []uint64{2}},
+		{"exact phrase -budget", nil},
+		{`label:"Needs Review"`, []uint64{2}},
+		{"project:office", []uint64{1, 2}},
+		{"priority>=medium", []uint64{1}},
+		{"priority<high", []uint64{2, 3, 4}},
+		{"priority:none", []uint64{3, 4}},
+		{"due:2026-10-31", []uint64{2}},
+		{"due<=2026-10-31", []uint64{1, 2}},
+		{"due>2026-10-31", []uint64{3}},
+		{"due>=2026-11-01", []uint64{3}},
+		{"due:overdue", []uint64{1}},
+		{"due:today OR due:none", []uint64{4}},
+		{"due:next_7_days", nil},
+		{"is:recurring OR is:subtask", []uint64{4}},
+		{"status:completed OR (label:work -is:blocked)", []uint64{1, 3}},
+		{"--invoice", []uint64{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			matches, err := Compile(tt.src, env)
+			if err != nil {
+				t.Fatalf("Compile returned %v", err)
+			}
+			var got []uint64
+			for _, task := range tasks {
+				if matches(task) {
+					got = append(got, task.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compile(%q) matched %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_UnknownNames(t *testing.T) {
+	env := Env{Labels: map[string]uint64{"work": 10}, Now: time.Now()}
+
+	_, err := Compile("label:work project:garden", env)
+	if err == nil || err.Error() != `column 20: there is no project named "garden"` {
+		t.Errorf("Expected an error for the unknown project, got %v", err)
+	}
+
+	env.IgnoreUnknown = true
+	matches, err := Compile("-label:gone", env)
+	if err != nil || !matches(models.Task{LabelIDs: []uint64{10}}) {
+		t.Errorf("Expected unknown labels to match no task when ignored, got %v", err)
+	}
+}
+
+func TestReplaceValues(t *testing.T) {
+	tests := []struct {
+		src, old, new string
+		want          string
+	}{
+		{"label:work -label:WORK work", "work", "job", "label:job -label:job work"},
+		{`label:"old name" OR project:old`, "old name", "new", "label:new OR project:old"},
+		{"(label:home)", "home", "my home", `(label:"my home")`},
+		{"label:a", "a", `say "hi"`, `label:"say \"hi\""`},
+		{"label:work (", "work", "job", "label:work ("},
+	}
+
+	for _, tt := range tests {
+		if got := ReplaceValues(tt.src, FieldLabel, tt.old, tt.new); got != tt.want {
+			t.Errorf("ReplaceValues(%q, %q, %q) = %q, want %q", tt.src, tt.old, tt.new, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+// Package quickadd parses single-line task entries such as
+// "Pay rent tomorrow 9am #home !high every month" into a title and the
+// task properties written inline:
+//
+//	#name              a label
+//	!priority          none, low, medium, high or urgent
+//	today, tomorrow    a due date, also "monday", "next friday", "on
+//	                   2026-11-01", "in 3 days", "in 2 weeks", "next week"
+//	9am, 17:30         a due time, optionally preceded by "at"
+//	every ...          a recurrence: "every day", "every 2 weeks", "every
+//	                   monday and thursday", "every weekday", "daily",
+//	                   "weekly", "monthly"
+//
+// Everything else makes up the title.
+package quickadd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoTitle = errors.New("quickadd: the entry has no title")
+
+// Result is what Parse found in an entry. DueAt is nil when the entry has
+// neither a date, a time nor a recurrence; RRule is an RFC 5545 rule.
+type Result struct {
+	Title    string     `json:"title"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	Labels   []string   `json:"labels,omitempty"`
+	Priority string     `json:"priority,omitempty"`
+	RRule    string     `json:"rrule,omitempty"`
+}
+
+// Entries that name a day but no time are due at the end of that day.
+const (
+	endOfDayHour   = 23
+	endOfDayMinute = 59
+)
+
+var priorities = []string{"none", "low", "medium", "high", "urgent"}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sunday": time.Sunday,
+}
+
+// weekdayAbbrevs are only recognized after "every", "next" or "on", where
+// they cannot be mistaken for ordinary words.
+var weekdayAbbrevs = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday, "sun": time.Sunday,
+}
+
+var byDay = map[time.Weekday]string{
+	time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE", time.Thursday: "TH",
+	time.Friday: "FR", time.Saturday: "SA", time.Sunday: "SU",
+}
+
+type parser struct {
+	now    time.Time
+	words  []string
+	lower  []string
+	result Result
+
+	date    *time.Time
+	hour    int
+	minute  int
+	hasTime bool
+	days    []time.Weekday
+}
+
+// Parse parses an entry. Relative dates are resolved against now, in now's
+// location.
+func Parse(line string, now time.Time) (Result, error) {
+	p := &parser{now: now, words: strings.Fields(line)}
+	for _, w := range p.words {
+		p.lower = append(p.lower, strings.TrimRight(strings.ToLower(w), ",;"))
+	}
+
+	var title []string
+	for i := 0; i < len(p.words); {
+		n := p.match(i)
+		if n == 0 {
+			title = append(title, p.words[i])
+			n = 1
+		}
+		i += n
+	}
+
+	p.result.Title = strings.Join(title, " ")
+	if p.result.Title == "" {
+		return Result{}, ErrNoTitle
+	}
+	p.resolveDue()
+	return p.result, nil
+}
+
+// match tries every kind of token at word i and returns how many words it
+// consumed.
+func (p *parser) match(i int) int {
+	for _, m := range []func(int) int{p.label, p.priority, p.recurrence, p.day, p.clock} {
+		if n := m(i); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+func (p *parser) word(i int) string {
+	if i < len(p.lower) {
+		return p.lower[i]
+	}
+	return ""
+}
+
+func (p *parser) label(i int) int {
+	name, ok := strings.CutPrefix(strings.TrimRight(p.words[i], ",;"), "#")
+	if !ok || name == "" {
+		return 0
+	}
+	for _, l := range p.result.Labels {
+		if strings.EqualFold(l, name) {
+			return 1
+		}
+	}
+	p.result.Labels = append(p.result.Labels, name)
+	return 1
+}
+
+func (p *parser) priority(i int) int {
+	name, ok := strings.CutPrefix(p.word(i), "!")
+	if !ok || p.result.Priority != "" {
+		return 0
+	}
+	for _, priority := range priorities {
+		if name == priority {
+			p.result.Priority = priority
+			return 1
+		}
+	}
+	return 0
+}
+
+func (p *parser) recurrence(i int) int {
+	if p.result.RRule != "" {
+		return 0
+	}
+	switch p.word(i) {
+	case "daily":
+		p.result.RRule = "FREQ=DAILY"
+		return 1
+	case "weekly":
+		p.result.RRule = "FREQ=WEEKLY"
+		return 1
+	case "monthly":
+		p.result.RRule = "FREQ=MONTHLY"
+		return 1
+	case "every":
+	default:
+		return 0
+	}
+
+	next := p.word(i + 1)
+	if next == "weekday" || next == "weekdays" {
+		p.days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		p.result.RRule = "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"
+		return 2
+	}
+	if days, n := p.weekdays(i + 1); n > 0 {
+		p.days = days
+		codes := make([]string, len(days))
+		for j, d := range days {
+			codes[j] = byDay[d]
+		}
+		p.result.RRule = "FREQ=WEEKLY;BYDAY=" + strings.Join(codes, ",")
+		return 1 + n
+	}
+
+	interval, n := 1, 1
+	if next == "other" {
+		interval, n = 2, 2
+	} else if v, err := strconv.Atoi(next); err == nil && v > 0 {
+		interval, n = v, 2
+	}
+	freq, ok := frequency(p.word(i + n))
+	if !ok {
+		return 0
+	}
+	p.result.RRule = "FREQ=" + freq
+	if interval > 1 {
+		p.result.RRule += fmt.Sprintf(";INTERVAL=%d", interval)
+	}
+	return n + 1
+}
+
+func frequency(unit string) (string, bool) {
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return "DAILY", true
+	case "week":
+		return "WEEKLY", true
+	case "month":
+		return "MONTHLY", true
+	}
+	return "", false
+}
+
+// weekdays reads a list of weekdays such as "mon, wed and fri" starting at
+// word i.
+func (p *parser) weekdays(i int) ([]time.Weekday, int) {
+	var days []time.Weekday
+	n := 0
+	for {
+		day, ok := weekday(p.word(i+n), true)
+		if !ok {
+			break
+		}
+		days = append(days, day)
+		n++
+		if p.word(i+n) != "and" {
+			continue
+		}
+		if _, ok := weekday(p.word(i+n+1), true); !ok {
+			break
+		}
+		n++
+	}
+	return days, n
+}
+
+func weekday(w string, abbrevs bool) (time.Weekday, bool) {
+	if day, ok := weekdayNames[w]; ok {
+		return day, true
+	}
+	day, ok := weekdayAbbrevs[w]
+	return day, ok && abbrevs
+}
+
+func (p *parser) day(i int) int {
+	if p.date != nil {
+		return 0
+	}
+	today := p.today()
+	w := p.word(i)
+
+	switch w {
+	case "today":
+		return p.setDate(today, 1)
+	case "tomorrow":
+		return p.setDate(today.AddDate(0, 0, 1), 1)
+	case "next", "on":
+		if d, ok := weekday(p.word(i+1), true); ok {
+			return p.setDate(nextWeekday(today, d), 2)
+		}
+		if w == "next" && p.word(i+1) == "week" {
+			return p.setDate(nextWeekday(today, time.Monday), 2)
+		}
+		if w == "on" {
+			if date, ok := p.isoDate(p.word(i + 1)); ok {
+				return p.setDate(date, 2)
+			}
+		}
+		return 0
+	case "in":
+		n, err := strconv.Atoi(p.word(i + 1))
+		if err != nil || n <= 0 {
+			return 0
+		}
+		switch strings.TrimSuffix(p.word(i+2), "s") {
+		case "day":
+			return p.setDate(today.AddDate(0, 0, n), 3)
+		case "week":
+			return p.setDate(today.AddDate(0, 0, 7*n), 3)
+		case "month":
+			return p.setDate(today.AddDate(0, n, 0), 3)
+		}
+		return 0
+	}
+
+	if d, ok := weekday(w, false); ok {
+		return p.setDate(nextWeekday(today, d), 1)
+	}
+	if date, ok := p.isoDate(w); ok {
+		return p.setDate(date, 1)
+	}
+	return 0
+}
+
+func (p *parser) setDate(date time.Time, n int) int {
+	p.date = &date
+	return n
+}
+
+func (p *parser) isoDate(w string) (time.Time, bool) {
+	date, err := time.ParseInLocation("2006-01-02", w, p.now.Location())
+	return date, err == nil
+}
+
+func (p *parser) clock(i int) int {
+	if p.hasTime {
+		return 0
+	}
+	n := 0
+	if p.word(i) == "at" {
+		n = 1
+	}
+	w := p.word(i + n)
+
+	var hour, minute int
+	switch {
+	case w == "noon":
+		hour = 12
+	case strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm"):
+		h, m, ok := hourMinute(w[:len(w)-2])
+		if !ok || h < 1 || h > 12 {
+			return 0
+		}
+		hour, minute = h%12, m
+		if strings.HasSuffix(w, "pm") {
+			hour += 12
+		}
+	case strings.Contains(w, ":"):
+		h, m, ok := hourMinute(w)
+		if !ok || h > 23 {
+			return 0
+		}
+		hour, minute = h, m
+	default:
+		return 0
+	}
+
+	p.hour, p.minute, p.hasTime = hour, minute, true
+	return n + 1
+}
+
+// hourMinute parses "9" or "9:30".
+func hourMinute(s string) (int, int, bool) {
+	hs, ms, hasMinutes := strings.Cut(s, ":")
+	h, err := strconv.Atoi(hs)
+	if err != nil || h < 0 || len(hs) > 2 {
+		return 0, 0, false
+	}
+	if !hasMinutes {
+		return h, 0, true
+	}
+	m, err := strconv.Atoi(ms)
+	if err != nil || m < 0 || m > 59 || len(ms) != 2 {
+		return 0, 0, false
+	}
+	return h, m, true
+}
+
+func (p *parser) today() time.Time {
+	y, m, d := p.now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, p.now.Location())
+}
+
+// nextWeekday returns the first day after from that falls on day.
+func nextWeekday(from time.Time, day time.Weekday) time.Time {
+	diff := (int(day) - int(from.Weekday()) + 7) % 7
+	if diff == 0 {
+		diff = 7
+	}
+	return from.AddDate(0, 0, diff)
+}
+
+// resolveDue combines the date, time and recurrence into DueAt. A time
+// without a date is due today, or tomorrow once it has passed; a recurrence
+// on weekdays without a date starts on the first of those days.
+func (p *parser) resolveDue() {
+	if p.date == nil && !p.hasTime && p.result.RRule == "" {
+		return
+	}
+
+	hour, minute := endOfDayHour, endOfDayMinute
+	if p.hasTime {
+		hour, minute = p.hour, p.minute
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, p.now.Location())
+	}
+
+	var due time.Time
+	switch {
+	case p.date != nil:
+		due = at(*p.date)
+	case len(p.days) > 0:
+		due = at(p.today())
+		for !due.After(p.now) || !containsDay(p.days, due.Weekday()) {
+			due = at(due.AddDate(0, 0, 1))
+		}
+	default:
+		due = at(p.today())
+		if !due.After(p.now) {
+			due = at(p.today().AddDate(0, 0, 1))
+		}
+	}
+	p.result.DueAt = &due
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
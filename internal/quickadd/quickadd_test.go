@@ -0,0 +1,71 @@
+package quickadd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Monday morning.
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, berlin)
+	at := func(month time.Month, day, hour, minute int) *time.Time {
+		d := time.Date(2026, month, day, hour, minute, 0, 0, berlin)
+		return &d
+	}
+
+	tests := []struct {
+		line string
+		want Result
+	}{
+		{"Buy milk", Result{Title: "Buy milk"}},
+		{"Pay rent tomorrow 9am #home !high every month", Result{
+			Title: "Pay rent", DueAt: at(10, 20, 9, 0), Labels: []string{"home"}, Priority: "high", RRule: "FREQ=MONTHLY",
+		}},
+		{"Call mom today", Result{Title: "Call mom", DueAt: at(10, 19, 23, 59)}},
+		{"Standup at 9:30am", Result{Title: "Standup", DueAt: at(10, 20, 9, 30)}},
+		{"Lunch at noon", Result{Title: "Lunch", DueAt: at(10, 19, 12, 0)}},
+		{"Deploy 17:45", Result{Title: "Deploy", DueAt: at(10, 19, 17, 45)}},
+		{"Review PR friday 2pm", Result{Title: "Review PR", DueAt: at(10, 23, 14, 0)}},
+		{"Plan sprint monday", Result{Title: "Plan sprint", DueAt: at(10, 26, 23, 59)}},
+		{"Retro next thu", Result{Title: "Retro", DueAt: at(10, 22, 23, 59)}},
+		{"Report next week", Result{Title: "Report", DueAt: at(10, 26, 23, 59)}},
+		{"Renew passport in 3 days", Result{Title: "Renew passport", DueAt: at(10, 22, 23, 59)}},
+		{"Dentist in 2 weeks at 8am", Result{Title: "Dentist", DueAt: at(11, 2, 8, 0)}},
+		{"Taxes on 2026-11-01", Result{Title: "Taxes", DueAt: at(11, 1, 23, 59)}},
+		{"Water plants every 3 days", Result{Title: "Water plants", DueAt: at(10, 19, 23, 59), RRule: "FREQ=DAILY;INTERVAL=3"}},
+		{"Gym every mon, wed and fri 7am", Result{Title: "Gym", DueAt: at(10, 21, 7, 0), RRule: "FREQ=WEEKLY;BYDAY=MO,WE,FR"}},
+		{"Timesheet every weekday 5pm", Result{Title: "Timesheet", DueAt: at(10, 19, 17, 0), RRule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"}},
+		{"Backups every other week", Result{Title: "Backups", DueAt: at(10, 19, 23, 59), RRule: "FREQ=WEEKLY;INTERVAL=2"}},
+		{"Newsletter weekly !low #work #Work #writing", Result{
+			Title: "Newsletter", DueAt: at(10, 19, 23, 59), Labels: []string{"work", "writing"}, Priority: "low", RRule: "FREQ=WEEKLY",
+		}},
+		{"Team spam filter every now and then", Result{Title: "Team spam filter every now and then"}},
+		{"Read sun tzu !important", Result{Title: "Read sun tzu !important"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := Parse(tt.line, now)
+			if err != nil {
+				t.Fatalf("Parse returned %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_NoTitle(t *testing.T) {
+	for _, line := range []string{"", "   ", "tomorrow 9am #home !high"} {
+		if _, err := Parse(line, time.Now()); !errors.Is(err, ErrNoTitle) {
+			t.Errorf("Parse(%q) error = %v, want ErrNoTitle", line, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package rank generates lexicographic ranks for manually ordered lists.
+// A rank can always be generated between two others, so moving an item
+// only rewrites that item's rank. Ranks never end in the smallest digit,
+// which keeps room in front of every rank.
+package rank
+
+import "strings"
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// Between returns a rank that sorts after a and before b. An empty a means
+// the start of the list and an empty b its end. When b does not sort after
+// a, which only happens with duplicate ranks, the result sorts right after
+// a.
+func Between(a, b string) string {
+	if b != "" && a >= b {
+		b = ""
+	}
+	return midpoint(a, b)
+}
+
+// midpoint follows the fractional indexing scheme by David Greenspan: a is
+// padded with zero digits, and b == "" stands for the end of the list.
+func midpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == digitAt(b, n) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(tail(a, n), b[n:])
+		}
+	}
+
+	da := digitAt(a, 0)
+	db := base
+	if b != "" {
+		db = digitAt(b, 0)
+	}
+	if db-da > 1 {
+		return string(digits[(da+db+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(tail(a, 1), "")
+}
+
+func digitAt(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	return strings.IndexByte(digits, s[i])
+}
+
+func tail(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
+
+// Spread returns n evenly spaced ranks in ascending order, using as few
+// digits as possible. It is used to rebalance lists whose ranks have grown
+// long.
+func Spread(n int) []string {
+	width, capacity := 1, base
+	for capacity <= n {
+		width++
+		capacity *= base
+	}
+
+	ranks := make([]string, n)
+	buf := make([]byte, width)
+	for i := range ranks {
+		v := (i + 1) * capacity / (n + 1)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = digits[v%base]
+			v /= base
+		}
+		ranks[i] = strings.TrimRight(string(buf), digits[:1])
+	}
+	return ranks
+}
@@ -0,0 +1,68 @@
+package rank
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"empty list", "", "", "i"},
+		{"append", "i", "", "r"},
+		{"prepend", "", "i", "9"},
+		{"gap", "a", "c", "b"},
+		{"adjacent digits", "a", "b", "ai"},
+		{"before smallest digit", "", "1", "0i"},
+		{"shared prefix", "ab", "ad", "ac"},
+		{"longer upper bound", "a", "a5", "a3"},
+		{"duplicates sort after a", "k", "k", "s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Between(tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("Between(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			if got <= tt.a || (tt.b != "" && tt.a < tt.b && got >= tt.b) {
+				t.Errorf("Between(%q, %q) = %q is out of order", tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestBetween_RepeatedInsertsStayOrdered(t *testing.T) {
+	lo, hi := "a", "b"
+	for i := 0; i < 200; i++ {
+		mid := Between(lo, hi)
+		if mid <= lo || mid >= hi || strings.HasSuffix(mid, "0") {
+			t.Fatalf("Between(%q, %q) = %q", lo, hi, mid)
+		}
+		if i%2 == 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{1, 5, 35, 36, 1000} {
+		ranks := Spread(n)
+		if len(ranks) != n {
+			t.Fatalf("Spread(%d) returned %d ranks", n, len(ranks))
+		}
+		for i, r := range ranks {
+			if r == "" || strings.HasSuffix(r, "0") {
+				t.Errorf("Spread(%d)[%d] = %q is not a valid rank", n, i, r)
+			}
+			if i > 0 && ranks[i-1] >= r {
+				t.Errorf("Spread(%d) is not ascending at %d: %q >= %q", n, i, ranks[i-1], r)
+			}
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules
+// used by recurring tasks: DAILY, WEEKLY and MONTHLY frequencies with
+// INTERVAL, BYDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// WeekdayNum is a BYDAY entry. Ordinal selects the nth weekday of the month
+// for monthly rules, counting from the end when negative; 0 means every
+// such weekday.
+type WeekdayNum struct {
+	Ordinal int
+	Day     time.Weekday
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []WeekdayNum
+	// Count limits the series to that many occurrences, 0 means no limit.
+	Count int
+	// Until is the last moment an occurrence may start at, zero means no
+	// limit.
+	Until time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// maxEmptyPeriods bounds the search for the next occurrence of rules that
+// rarely match, such as the 31st of every other month.
+const maxEmptyPeriods = 1000
+
+// Parse parses an RRULE value, with or without the "RRULE:" prefix. A
+// floating UNTIL is interpreted in loc.
+func Parse(value string, loc *time.Location) (Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	rule := Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || val == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				err = fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positive(name, val)
+		case "COUNT":
+			rule.Count, err = positive(name, val)
+		case "UNTIL":
+			rule.Until, err = parseUntil(val, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "WKST":
+			if _, ok := weekdays[strings.ToUpper(val)]; !ok {
+				err = fmt.Errorf("%w: unknown WKST %q", ErrInvalidRule, val)
+			}
+		default:
+			err = fmt.Errorf("%w: unsupported part %s", ErrInvalidRule, name)
+		}
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	case rule.Count != 0 && !rule.Until.IsZero():
+		return Rule{}, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRule)
+	}
+	for _, d := range rule.ByDay {
+		if d.Ordinal != 0 && rule.Freq != Monthly {
+			return Rule{}, fmt.Errorf("%w: BYDAY ordinals require FREQ=MONTHLY", ErrInvalidRule)
+		}
+	}
+	return rule, nil
+}
+
+func positive(name, val string) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidRule, name)
+	}
+	return n, nil
+}
+
+func parseUntil(val string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, val, loc); err == nil {
+			if strings.HasSuffix(val, "Z") {
+				t, _ = time.Parse(layout, val)
+			} else if layout == "20060102" {
+				t = t.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: malformed UNTIL %q", ErrInvalidRule, val)
+}
+
+func parseByDay(val string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(strings.ToUpper(val), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("%w: malformed BYDAY %q", ErrInvalidRule, item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidRule, item)
+		}
+		ordinal := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%w: malformed BYDAY %q", ErrInvalidRule, item)
+			}
+			ordinal = n
+		}
+		days = append(days, WeekdayNum{Ordinal: ordinal, Day: day})
+	}
+	return days, nil
+}
+
+// Next returns the first occurrence of the series starting at start that
+// comes strictly after after. Occurrences are computed in start's location,
+// so they keep their wall clock time across DST changes. Next honors UNTIL
+// but not COUNT, which depends on how many occurrences the caller has
+// already used.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	if start.After(after) {
+		if !r.Until.IsZero() && start.After(r.Until) {
+			return time.Time{}, false
+		}
+		return start, true
+	}
+	for period, empty := 0, 0; empty < maxEmptyPeriods; period++ {
+		candidates := r.period(start, period)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, t := range candidates {
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Occurrences returns up to n occurrences of the series starting at start,
+// start itself included, honoring COUNT and UNTIL.
+func (r Rule) Occurrences(start time.Time, n int) []time.Time {
+	if r.Count != 0 && n > r.Count {
+		n = r.Count
+	}
+	var out []time.Time
+	if n <= 0 || (!r.Until.IsZero() && start.After(r.Until)) {
+		return out
+	}
+	out = append(out, start)
+	for len(out) < n {
+		next, ok := r.Next(start, out[len(out)-1])
+		if !ok {
+			break
+		}
+		out = append(out, next)
+	}
+	return out
+}
+
+// period returns the candidate occurrences in the nth interval after the
+// one containing start, in chronological order.
+func (r Rule) period(start time.Time, n int) []time.Time {
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+	var out []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := at(start.Year(), start.Month(), start.Day()+n*r.Interval)
+		if len(r.ByDay) == 0 || r.hasWeekday(day.Weekday()) {
+			out = append(out, day)
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(start.Year(), start.Month(), start.Day()-offset+7*n*r.Interval)
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(monday.Year(), monday.Month(), monday.Day()+offset)}
+		}
+		for i := 0; i < 7; i++ {
+			day := at(monday.Year(), monday.Month(), monday.Day()+i)
+			if r.hasWeekday(day.Weekday()) {
+				out = append(out, day)
+			}
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, start.Location())
+		days := daysIn(first.Year(), first.Month())
+		if len(r.ByDay) == 0 {
+			if start.Day() <= days {
+				out = append(out, at(first.Year(), first.Month(), start.Day()))
+			}
+			return out
+		}
+		for d := 1; d <= days; d++ {
+			day := at(first.Year(), first.Month(), d)
+			if r.matchesMonthDay(day, days) {
+				out = append(out, day)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+func (r Rule) hasWeekday(day time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matchesMonthDay(day time.Time, days int) bool {
+	for _, d := range r.ByDay {
+		if d.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case d.Ordinal == 0:
+			return true
+		case d.Ordinal > 0 && (day.Day()-1)/7+1 == d.Ordinal:
+			return true
+		case d.Ordinal < 0 && (days-day.Day())/7+1 == -d.Ordinal:
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,104 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"missing freq", "INTERVAL=2"},
+		{"unsupported freq", "FREQ=YEARLY"},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0"},
+		{"count and until", "FREQ=DAILY;COUNT=3;UNTIL=20250101"},
+		{"unknown weekday", "FREQ=WEEKLY;BYDAY=XX"},
+		{"ordinal on weekly", "FREQ=WEEKLY;BYDAY=2MO"},
+		{"malformed part", "FREQ=DAILY;COUNT"},
+		{"duplicate part", "FREQ=DAILY;FREQ=WEEKLY"},
+		{"unsupported part", "FREQ=DAILY;BYHOUR=9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.rule, time.UTC); !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", tt.rule, err)
+			}
+		})
+	}
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		n     int
+		want  []string
+	}{
+		{"daily", "FREQ=DAILY", "2025-03-29 09:00", 3,
+			[]string{"2025-03-29 09:00", "2025-03-30 09:00", "2025-03-31 09:00"}},
+		{"every other day with count", "FREQ=DAILY;INTERVAL=2;COUNT=2", "2025-01-01 08:00", 5,
+			[]string{"2025-01-01 08:00", "2025-01-03 08:00"}},
+		{"weekdays only", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "2025-01-03 08:00", 3,
+			[]string{"2025-01-03 08:00", "2025-01-06 08:00", "2025-01-07 08:00"}},
+		{"weekly on tuesday and thursday", "RRULE:FREQ=WEEKLY;BYDAY=TU,TH", "2025-01-07 18:30", 4,
+			[]string{"2025-01-07 18:30", "2025-01-09 18:30", "2025-01-14 18:30", "2025-01-16 18:30"}},
+		{"biweekly", "FREQ=WEEKLY;INTERVAL=2", "2025-01-06 10:00", 3,
+			[]string{"2025-01-06 10:00", "2025-01-20 10:00", "2025-02-03 10:00"}},
+		{"weekly until", "FREQ=WEEKLY;UNTIL=20250120", "2025-01-06 10:00", 5,
+			[]string{"2025-01-06 10:00", "2025-01-13 10:00", "2025-01-20 10:00"}},
+		{"monthly skips short months", "FREQ=MONTHLY", "2025-01-31 12:00", 3,
+			[]string{"2025-01-31 12:00", "2025-03-31 12:00", "2025-05-31 12:00"}},
+		{"last friday of the month", "FREQ=MONTHLY;BYDAY=-1FR", "2025-01-31 12:00", 3,
+			[]string{"2025-01-31 12:00", "2025-02-28 12:00", "2025-03-28 12:00"}},
+		{"second monday", "FREQ=MONTHLY;BYDAY=2MO;COUNT=2", "2025-01-13 09:00", 5,
+			[]string{"2025-01-13 09:00", "2025-02-10 09:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, berlin)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.rule, err)
+			}
+			got := rule.Occurrences(day(tt.start), tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(day(want)) {
+					t.Errorf("occurrence %d = %v, want %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRule_Next(t *testing.T) {
+	rule, _ := Parse("FREQ=WEEKLY;BYDAY=MO,FR", time.UTC)
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	next, ok := rule.Next(start, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC))
+	if !ok || !next.Equal(time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next = %v, %v; want friday 2025-01-10", next, ok)
+	}
+	if next, ok := rule.Next(start, start.AddDate(0, 0, -1)); !ok || !next.Equal(start) {
+		t.Errorf("Expected the start to be the first occurrence, got %v", next)
+	}
+}
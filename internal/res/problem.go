@@ -0,0 +1,97 @@
+package res
+
+import (
+	"errors"
+	"net/http"
+	"task-backend/internal/apperr"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MIMEProblemJSON = "application/problem+json"
+	problemTypeBase = "/problems/"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+func StatusFor(err error) int {
+	switch {
+	case errors.Is(err, apperr.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperr.ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperr.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, apperr.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, apperr.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, apperr.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, apperr.ErrFailedDependency):
+		return http.StatusFailedDependency
+	case errors.Is(err, apperr.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperr.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, apperr.ErrTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func NewProblem(c *gin.Context, err error) Problem {
+	status := StatusFor(err)
+	e, ok := apperr.As(err)
+	if !ok {
+		e = apperr.New(apperr.ErrInternal, apperr.CodeInternal, "Internal server error", "an unexpected error occurred")
+	}
+
+	return Problem{
+		Type:     problemTypeBase + e.Code,
+		Title:    e.Title,
+		Status:   status,
+		Detail:   e.Detail,
+		Instance: requestid.Get(c),
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
+
+// WriteError renders err as application/problem+json when the client asks
+// for it and falls back to the legacy ErrorResponse shape otherwise.
+func WriteError(c *gin.Context, err error) {
+	p := NewProblem(c, err)
+
+	if c.NegotiateFormat(gin.MIMEJSON, MIMEProblemJSON) == MIMEProblemJSON {
+		c.Header("Content-Type", MIMEProblemJSON)
+		c.AbortWithStatusJSON(p.Status, p)
+		return
+	}
+
+	legacy := ErrorResponse{Message: p.Title, Error: p.Detail}
+	if p.Errors != nil {
+		legacy.Error = p.Errors
+	}
+	c.AbortWithStatusJSON(p.Status, legacy)
+}
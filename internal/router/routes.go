@@ -5,9 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"task-backend/internal/apperr"
 	"task-backend/internal/handlers"
 	"task-backend/internal/middlewares"
-	"task-backend/internal/res"
+	"task-backend/internal/storage"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/requestid"
@@ -30,13 +32,18 @@ func RegisterRoutes(taskHandler *handlers.TaskHandler) http.Handler {
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
 	r.Use(requestid.New())
+	r.Use(middlewares.ErrorHandler())
+	r.Use(middlewares.Timeout(timeoutConfig()))
 
 	rate, err := limiter.NewRateFromFormatted("5-M")
 	if err != nil {
 		log.Fatalf("Invalid rate limit format: %v", err)
 	}
 	store := memory.NewStore()
-	r.Use(mgin.NewMiddleware(limiter.New(store, rate)))
+	r.Use(mgin.NewMiddleware(limiter.New(store, rate), mgin.WithLimitReachedHandler(func(c *gin.Context) {
+		_ = c.Error(apperr.New(apperr.ErrTooManyRequests, apperr.CodeRateLimited, "Too many requests", "rate limit exceeded, retry later"))
+		c.Abort()
+	})))
 
 	corsOrigin := os.Getenv("CORS")
 	if corsOrigin == "" {
@@ -46,26 +53,147 @@ func RegisterRoutes(taskHandler *handlers.TaskHandler) http.Handler {
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{corsOrigin},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"ETag", "Idempotent-Replayed", "Undo-Token"},
 		AllowCredentials: corsOrigin != "*",
 	}))
 
-	taskGroup := r.Group("/tasks", middlewares.AuthMiddleware())
-	{
-		taskGroup.GET("", taskHandler.GetAllTasks)
-		taskGroup.GET("/:id", taskHandler.GetTaskByID)
-		taskGroup.POST("", taskHandler.CreateTask)
-		taskGroup.PUT("/:id", taskHandler.UpdateTask)
-		taskGroup.DELETE("/:id", taskHandler.DeleteTask)
+	idempotencyTTL := 24 * time.Hour
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid IDEMPOTENCY_TTL: %v", err)
+		}
+		idempotencyTTL = d
 	}
+	idempotency := middlewares.Idempotency(storage.NewIdempotencyStore(), idempotencyTTL)
+
+	RegisterTaskRoutes(r.Group("/tasks", middlewares.AuthMiddleware()), taskHandler, idempotency)
+	RegisterUndoRoutes(r.Group("/undo", middlewares.AuthMiddleware()), taskHandler)
+	RegisterLabelRoutes(r.Group("/labels", middlewares.AuthMiddleware()), taskHandler)
+	RegisterProjectRoutes(r.Group("/projects", middlewares.AuthMiddleware()), taskHandler)
+	RegisterFieldRoutes(r.Group("/fields", middlewares.AuthMiddleware()), taskHandler)
+	RegisterTemplateRoutes(r.Group("/templates", middlewares.AuthMiddleware()), taskHandler, idempotency)
+	RegisterViewRoutes(r.Group("/views", middlewares.AuthMiddleware()), taskHandler)
+	RegisterBoardRoutes(r.Group("/boards", middlewares.AuthMiddleware()), taskHandler)
 
 	r.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, res.ErrorResponse{
-			Message: "Route not found",
-			Error:   "invalid route",
-		})
+		_ = c.Error(apperr.New(apperr.ErrNotFound, apperr.CodeRouteNotFound, "Route not found", "invalid route"))
 	})
 
 	return r
 }
+
+func timeoutConfig() middlewares.TimeoutConfig {
+	cfg := middlewares.TimeoutConfig{Default: 5 * time.Second}
+
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid REQUEST_TIMEOUT: %v", err)
+		}
+		cfg.Default = d
+	}
+
+	routes, err := middlewares.ParseRouteTimeouts(os.Getenv("ROUTE_TIMEOUTS"))
+	if err != nil {
+		log.Fatalf("Invalid ROUTE_TIMEOUTS: %v", err)
+	}
+	cfg.Routes = routes
+
+	return cfg
+}
+
+func RegisterTaskRoutes(taskGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler, idempotency gin.HandlerFunc) {
+	taskGroup.GET("", taskHandler.GetAllTasks)
+	taskGroup.GET("/trash", taskHandler.GetTrash)
+	taskGroup.GET("/ready", taskHandler.GetReadyTasks)
+	taskGroup.GET("/:id", taskHandler.GetTaskByID)
+	taskGroup.POST("", idempotency, taskHandler.CreateTask)
+	taskGroup.POST("/batch", idempotency, taskHandler.BatchTasks)
+	taskGroup.POST("/quick", idempotency, taskHandler.QuickAddTask)
+	taskGroup.PUT("/:id", taskHandler.UpdateTask)
+	taskGroup.PATCH("/:id", taskHandler.PatchTask)
+	taskGroup.DELETE("/:id", taskHandler.DeleteTask)
+	taskGroup.POST("/:id/restore", taskHandler.RestoreTask)
+	taskGroup.POST("/:id/move", taskHandler.MoveTask)
+	taskGroup.POST("/:id/checklist", taskHandler.AddChecklistItem)
+	taskGroup.PUT("/:id/checklist/:item", taskHandler.UpdateChecklistItem)
+	taskGroup.DELETE("/:id/checklist/:item", taskHandler.RemoveChecklistItem)
+	taskGroup.POST("/:id/checklist/:item/toggle", taskHandler.ToggleChecklistItem)
+	taskGroup.POST("/:id/checklist/:item/move", taskHandler.MoveChecklistItem)
+	taskGroup.GET("/:id/comments", taskHandler.GetComments)
+	taskGroup.POST("/:id/comments", taskHandler.CreateComment)
+	taskGroup.PUT("/:id/comments/:comment", taskHandler.UpdateComment)
+	taskGroup.DELETE("/:id/comments/:comment", taskHandler.DeleteComment)
+	taskGroup.GET("/:id/attachments", taskHandler.GetAttachments)
+	taskGroup.POST("/:id/attachments", taskHandler.UploadAttachment)
+	taskGroup.GET("/:id/attachments/:attachment", taskHandler.DownloadAttachment)
+	taskGroup.DELETE("/:id/attachments/:attachment", taskHandler.DeleteAttachment)
+	taskGroup.GET("/:id/children", taskHandler.GetSubtasks)
+	taskGroup.GET("/:id/occurrences", taskHandler.GetOccurrences)
+	taskGroup.GET("/:id/subtree", taskHandler.GetTaskTree)
+	taskGroup.GET("/:id/history", taskHandler.GetTaskHistory)
+	taskGroup.GET("/:id/history/:rev", taskHandler.GetTaskRevision)
+	taskGroup.POST("/:id/revert/:rev", taskHandler.RevertTask)
+	taskGroup.DELETE("/trash/:id", taskHandler.PurgeTask)
+}
+
+func RegisterUndoRoutes(undoGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	undoGroup.POST("/:token", taskHandler.Undo)
+}
+
+func RegisterLabelRoutes(labelGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	labelGroup.GET("", taskHandler.GetLabels)
+	labelGroup.GET("/:id", taskHandler.GetLabelByID)
+	labelGroup.POST("", taskHandler.CreateLabel)
+	labelGroup.PUT("/:id", taskHandler.UpdateLabel)
+	labelGroup.DELETE("/:id", taskHandler.DeleteLabel)
+}
+
+func RegisterFieldRoutes(fieldGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	fieldGroup.GET("", taskHandler.GetCustomFields)
+	fieldGroup.GET("/:id", taskHandler.GetCustomFieldByID)
+	fieldGroup.POST("", taskHandler.CreateCustomField)
+	fieldGroup.PUT("/:id", taskHandler.UpdateCustomField)
+	fieldGroup.DELETE("/:id", taskHandler.DeleteCustomField)
+}
+
+func RegisterTemplateRoutes(templateGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler, idempotency gin.HandlerFunc) {
+	templateGroup.GET("", taskHandler.GetTemplates)
+	templateGroup.GET("/:id", taskHandler.GetTemplateByID)
+	templateGroup.POST("", taskHandler.CreateTemplate)
+	templateGroup.PUT("/:id", taskHandler.UpdateTemplate)
+	templateGroup.DELETE("/:id", taskHandler.DeleteTemplate)
+	templateGroup.POST("/:id/instantiate", idempotency, taskHandler.InstantiateTemplate)
+}
+
+func RegisterViewRoutes(viewGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	viewGroup.GET("", taskHandler.GetViews)
+	viewGroup.GET("/:id", taskHandler.GetViewByID)
+	viewGroup.GET("/:id/tasks", taskHandler.GetViewTasks)
+	viewGroup.POST("", taskHandler.CreateView)
+	viewGroup.PUT("/:id", taskHandler.UpdateView)
+	viewGroup.DELETE("/:id", taskHandler.DeleteView)
+}
+
+func RegisterProjectRoutes(projectGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	projectGroup.GET("", taskHandler.GetProjects)
+	projectGroup.GET("/:id", taskHandler.GetProjectByID)
+	projectGroup.GET("/:id/tasks", taskHandler.GetProjectTasks)
+	projectGroup.POST("", taskHandler.CreateProject)
+	projectGroup.PUT("/:id", taskHandler.UpdateProject)
+	projectGroup.POST("/:id/archive", taskHandler.ArchiveProject)
+	projectGroup.POST("/:id/unarchive", taskHandler.UnarchiveProject)
+	projectGroup.DELETE("/:id", taskHandler.DeleteProject)
+}
+
+func RegisterBoardRoutes(boardGroup *gin.RouterGroup, taskHandler *handlers.TaskHandler) {
+	boardGroup.GET("", taskHandler.GetBoards)
+	boardGroup.GET("/:id", taskHandler.GetBoardByID)
+	boardGroup.POST("", taskHandler.CreateBoard)
+	boardGroup.PUT("/:id", taskHandler.UpdateBoard)
+	boardGroup.DELETE("/:id", taskHandler.DeleteBoard)
+	boardGroup.POST("/:id/cards/:task/move", taskHandler.MoveCard)
+}
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"task-backend/internal/handlers"
+	"task-backend/internal/models"
 	"task-backend/internal/router"
 	"task-backend/internal/services"
 	"task-backend/internal/storage"
@@ -21,10 +24,32 @@ func NewServer() *http.Server {
 	}
 
 	store := storage.NewTaskStore()
+	switch policy := models.CompletionPolicy(os.Getenv("SUBTASK_COMPLETION")); policy {
+	case "", models.CompleteBlocked, models.CompleteCascade:
+		store.CompletionPolicy = policy
+	default:
+		log.Fatalf("Invalid SUBTASK_COMPLETION: %q", policy)
+	}
 
 	taskService := services.NewTaskService(store)
+	taskService.UndoWindow = envDuration("UNDO_WINDOW", services.DefaultUndoWindow)
+	taskService.Blobs = blobStore()
+	if v := os.Getenv("MAX_ATTACHMENT_SIZE"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid MAX_ATTACHMENT_SIZE: %q", v)
+		}
+		taskService.MaxAttachmentSize = size
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go taskService.RunTrashPurger(jobsCtx, envDuration("TRASH_RETENTION", 30*24*time.Hour), envDuration("TRASH_PURGE_INTERVAL", time.Hour))
+	go taskService.RunRankRebalancer(jobsCtx, envDuration("RANK_REBALANCE_INTERVAL", time.Hour))
 
-	taskHandler := &handlers.TaskHandler{TaskService: taskService}
+	taskHandler := &handlers.TaskHandler{
+		TaskService:    taskService,
+		RequireIfMatch: os.Getenv("REQUIRE_IF_MATCH") == "true",
+	}
 
 	handler := router.RegisterRoutes(taskHandler)
 
@@ -36,5 +61,52 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	server.RegisterOnShutdown(stopJobs)
+
 	return server
 }
+
+// blobStore returns the store for attachment content selected by BLOB_STORE:
+// "local" (the default) keeps files below BLOB_DIR, "s3" uses an
+// S3-compatible bucket.
+func blobStore() services.BlobStore {
+	switch kind := os.Getenv("BLOB_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		store, err := storage.NewFileBlobStore(dir)
+		if err != nil {
+			log.Fatalf("Invalid BLOB_DIR: %v", err)
+		}
+		return store
+	case "s3":
+		store, err := storage.NewS3BlobStore(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+		if err != nil {
+			log.Fatalf("Invalid S3 blob store: %v", err)
+		}
+		return store
+	default:
+		log.Fatalf("Invalid BLOB_STORE: %q", kind)
+		return nil
+	}
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s: %q", key, v)
+	}
+	return d
+}
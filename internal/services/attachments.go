@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+
+	"github.com/google/uuid"
+)
+
+const DefaultMaxAttachmentSize = 10 << 20
+
+// AttachmentTypes are the content types accepted for attachments. Types are
+// sniffed from the content; the type the client claims is ignored.
+var AttachmentTypes = []string{
+	"application/pdf",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"text/plain",
+}
+
+var (
+	ErrAttachmentsDisabled = apperr.New(apperr.ErrUnavailable, apperr.CodeAttachmentsDisabled, "Attachments disabled", "no blob store is configured")
+	ErrAttachmentTooLarge  = apperr.New(apperr.ErrTooLarge, apperr.CodeAttachmentTooLarge, "Attachment too large", "the attachment exceeds the size limit")
+	ErrAttachmentType      = apperr.New(apperr.ErrUnsupportedType, apperr.CodeAttachmentType, "Unsupported attachment type", "only PDFs, images and plain text can be attached")
+)
+
+// BlobStore keeps the content of attachments under opaque keys. Open returns
+// an apperr.ErrNotFound error for unknown keys, and deleting a missing blob
+// succeeds.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func publicAttachment(attachment models.Attachment) models.Attachment {
+	attachment.ID = my_utils.ObfuscateNumbers(attachment.ID)
+	attachment.TaskID = my_utils.ObfuscateNumbers(attachment.TaskID)
+	return attachment
+}
+
+// sniffType detects the content type of content and rewinds it.
+func sniffType(content io.ReadSeeker) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mediaType, err
+}
+
+func attachmentName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// AddAttachment stores size bytes of content as a new attachment of a task.
+func (s *TaskService) AddAttachment(ctx context.Context, userID string, taskID uint64, name string, size int64, content io.ReadSeeker) (models.Attachment, error) {
+	if s.Blobs == nil {
+		return models.Attachment{}, ErrAttachmentsDisabled
+	}
+	if size > s.MaxAttachmentSize {
+		return models.Attachment{}, ErrAttachmentTooLarge.WithDetail(fmt.Sprintf("attachments must not exceed %d bytes", s.MaxAttachmentSize))
+	}
+
+	contentType, err := sniffType(content)
+	if err != nil {
+		return models.Attachment{}, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidRequestBody, "Invalid request body", err)
+	}
+	if !slices.Contains(AttachmentTypes, contentType) {
+		return models.Attachment{}, ErrAttachmentType.WithDetail(fmt.Sprintf("%s files cannot be attached", contentType))
+	}
+
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	if _, err := s.store.GetByID(ctx, userID, realID); err != nil {
+		return models.Attachment{}, storeError(err)
+	}
+
+	key := "attachments/" + uuid.NewString()
+	if err := s.Blobs.Put(ctx, key, io.LimitReader(content, size), size); err != nil {
+		return models.Attachment{}, storeError(err)
+	}
+
+	created, err := s.store.AddAttachment(ctx, userID, realID, models.Attachment{
+		Name:        attachmentName(name),
+		ContentType: contentType,
+		Size:        size,
+		BlobKey:     key,
+	})
+	if err != nil {
+		if err := s.Blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+			log.Printf("Deleting blob %s failed: %v", key, err)
+		}
+		return models.Attachment{}, storeError(err)
+	}
+	return publicAttachment(created), nil
+}
+
+func (s *TaskService) ListAttachments(ctx context.Context, userID string, taskID uint64) ([]models.Attachment, error) {
+	attachments, err := s.store.GetAttachments(ctx, userID, my_utils.DeobfuscateNumbers(taskID))
+	if err != nil {
+		return nil, storeError(err)
+	}
+	for i := range attachments {
+		attachments[i] = publicAttachment(attachments[i])
+	}
+	return attachments, nil
+}
+
+// OpenAttachment returns an attachment with a reader for its content, which
+// the caller must close.
+func (s *TaskService) OpenAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) (models.Attachment, io.ReadSeekCloser, error) {
+	if s.Blobs == nil {
+		return models.Attachment{}, nil, ErrAttachmentsDisabled
+	}
+
+	attachment, err := s.store.GetAttachment(ctx, userID, my_utils.DeobfuscateNumbers(taskID), my_utils.DeobfuscateNumbers(attachmentID))
+	if err != nil {
+		return models.Attachment{}, nil, storeError(err)
+	}
+	content, err := s.Blobs.Open(ctx, attachment.BlobKey)
+	if err != nil {
+		return models.Attachment{}, nil, storeError(err)
+	}
+	return publicAttachment(attachment), content, nil
+}
+
+func (s *TaskService) DeleteAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) error {
+	if err := s.store.DeleteAttachment(ctx, userID, my_utils.DeobfuscateNumbers(taskID), my_utils.DeobfuscateNumbers(attachmentID)); err != nil {
+		return storeError(err)
+	}
+	s.collectBlobs(ctx)
+	return nil
+}
+
+// collectBlobs deletes the blobs of attachments that were removed, directly
+// or with their task. Failures are logged and the blobs are left for the
+// next collection to retry; the request that removed them has already
+// succeeded.
+func (s *TaskService) collectBlobs(ctx context.Context) {
+	if s.Blobs == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	keys, err := s.store.TakeOrphanedBlobs(ctx)
+	if err != nil {
+		log.Printf("Collecting orphaned blobs failed: %v", err)
+		return
+	}
+	var failed []string
+	for _, key := range keys {
+		if err := s.Blobs.Delete(ctx, key); err != nil {
+			log.Printf("Deleting blob %s failed: %v", key, err)
+			failed = append(failed, key)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+	if err := s.store.ReturnOrphanedBlobs(ctx, failed); err != nil {
+		log.Printf("Returning %d orphaned blobs failed: %v", len(failed), err)
+	}
+}
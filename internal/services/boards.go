@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+var (
+	ErrColumnNotFound    = apperr.New(apperr.ErrNotFound, apperr.CodeColumnNotFound, "Column not found", "the board has no column with this id")
+	ErrCardNotOnBoard    = apperr.New(apperr.ErrUnprocessable, apperr.CodeCardNotOnBoard, "Card not on board", "the task is not shown on this board")
+	ErrColumnUnreachable = apperr.New(apperr.ErrUnprocessable, apperr.CodeColumnUnreachable, "Column unreachable", "an earlier column of the board already shows the tasks this column would hold")
+	ErrOtherList         = apperr.New(apperr.ErrUnprocessable, apperr.CodeOtherList, "Different list", "cards can only be placed next to cards of the same project")
+	ErrWIPLimitExceeded  = apperr.New(apperr.ErrConflict, apperr.CodeWIPLimitExceeded, "WIP limit exceeded", "the column already holds as many tasks as its WIP limit allows")
+)
+
+func publicBoard(board models.Board) models.Board {
+	board.ID = my_utils.ObfuscateNumbers(board.ID)
+	board.ProjectID = obfuscateID(board.ProjectID)
+	board.StateField = obfuscateID(board.StateField)
+	return board
+}
+
+func boardFromRequest(req dto.BoardRequest) models.Board {
+	board := models.Board{
+		Name:       req.Name,
+		ProjectID:  deobfuscateID(req.Project),
+		StateField: deobfuscateID(req.StateField),
+	}
+	for _, c := range req.Columns {
+		board.Columns = append(board.Columns, models.BoardColumn{
+			ID:       c.ID,
+			Name:     c.Name,
+			Status:   models.TaskStatus(c.Status),
+			State:    c.State,
+			WIPLimit: c.WIPLimit,
+		})
+	}
+	return board
+}
+
+func (s *TaskService) CreateBoard(ctx context.Context, userID string, req dto.BoardRequest) (models.Board, error) {
+	if err := req.Validate(); err != nil {
+		return models.Board{}, err
+	}
+
+	created, err := s.store.CreateBoard(ctx, userID, boardFromRequest(req))
+	if err != nil {
+		return models.Board{}, storeError(err)
+	}
+	return publicBoard(created), nil
+}
+
+func (s *TaskService) GetBoards(ctx context.Context, userID string) ([]models.Board, error) {
+	boards, err := s.store.GetBoards(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range boards {
+		boards[i] = publicBoard(boards[i])
+	}
+	return boards, nil
+}
+
+func (s *TaskService) UpdateBoard(ctx context.Context, userID string, boardID uint64, req dto.BoardRequest) (models.Board, error) {
+	if err := req.Validate(); err != nil {
+		return models.Board{}, err
+	}
+
+	updated, err := s.store.UpdateBoard(ctx, userID, my_utils.DeobfuscateNumbers(boardID), boardFromRequest(req))
+	if err != nil {
+		return models.Board{}, storeError(err)
+	}
+	return publicBoard(updated), nil
+}
+
+func (s *TaskService) DeleteBoard(ctx context.Context, userID string, boardID uint64) error {
+	if err := s.store.DeleteBoard(ctx, userID, my_utils.DeobfuscateNumbers(boardID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// boardColumn returns a function that finds the column a stored task is shown
+// in. Like the task listing, boards spanning all projects leave out the
+// tasks of archived projects.
+func (s *TaskService) boardColumn(ctx context.Context, userID string, board models.Board) (func(models.Task) (models.BoardColumn, bool), error) {
+	archived := make(map[uint64]bool)
+	if board.ProjectID == 0 {
+		var err error
+		if archived, err = s.archivedProjects(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return func(task models.Task) (models.BoardColumn, bool) {
+		if archived[task.ProjectID] {
+			return models.BoardColumn{}, false
+		}
+		return board.Column(task)
+	}, nil
+}
+
+// GetBoard returns a board with the tasks of each column in list order.
+func (s *TaskService) GetBoard(ctx context.Context, userID string, boardID uint64) (dto.BoardResponse, error) {
+	board, err := s.store.GetBoard(ctx, userID, my_utils.DeobfuscateNumbers(boardID))
+	if err != nil {
+		return dto.BoardResponse{}, storeError(err)
+	}
+	columnOf, err := s.boardColumn(ctx, userID, board)
+	if err != nil {
+		return dto.BoardResponse{}, err
+	}
+	tasks, err := s.store.Query(ctx, userID, func(task models.Task) bool {
+		_, ok := columnOf(task)
+		return ok
+	})
+	if err != nil {
+		return dto.BoardResponse{}, storeError(err)
+	}
+
+	cards := make(map[uint64][]models.Task, len(board.Columns))
+	for _, task := range tasks {
+		column, _ := columnOf(task)
+		cards[column.ID] = append(cards[column.ID], publicTask(task))
+	}
+
+	resp := dto.BoardResponse{Board: publicBoard(board), Columns: make([]dto.BoardColumnCards, len(board.Columns))}
+	for i, column := range board.Columns {
+		resp.Columns[i] = dto.BoardColumnCards{Column: column, Tasks: cards[column.ID]}
+		if resp.Columns[i].Tasks == nil {
+			resp.Columns[i].Tasks = []models.Task{}
+		}
+	}
+	return resp, nil
+}
+
+// MoveCard moves a task into a column of a board by changing its completion
+// and state to the column's, and places it before or after another card when
+// asked to. Moves into a column at its WIP limit fail with
+// ErrWIPLimitExceeded.
+func (s *TaskService) MoveCard(ctx context.Context, userID string, boardID, taskID uint64, ifMatch uint64, req dto.MoveCardRequest) (models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+
+	board, err := s.store.GetBoard(ctx, userID, my_utils.DeobfuscateNumbers(boardID))
+	if err != nil {
+		return models.Task{}, "", storeError(err)
+	}
+	var column models.BoardColumn
+	for _, c := range board.Columns {
+		if c.ID == req.Column {
+			column = c
+		}
+	}
+	if column.ID == 0 {
+		return models.Task{}, "", ErrColumnNotFound
+	}
+
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
+	}
+	if ifMatch != 0 && existingTask.Version != ifMatch {
+		return models.Task{}, "", ErrVersionStale
+	}
+	columnOf, err := s.boardColumn(ctx, userID, board)
+	if err != nil {
+		return models.Task{}, "", err
+	}
+	current, ok := columnOf(existingTask)
+	if !ok {
+		return models.Task{}, "", ErrCardNotOnBoard
+	}
+	placed := board.Place(existingTask, column)
+	if target, ok := columnOf(placed); !ok || target.ID != column.ID {
+		return models.Task{}, "", ErrColumnUnreachable
+	}
+
+	// Anchors are checked up front so that a bad one doesn't leave the card
+	// moved to the column but not placed.
+	anchorID := deobfuscateID(req.Before + req.After)
+	if anchorID != 0 {
+		anchor, err := s.store.GetByID(ctx, userID, anchorID)
+		if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			return models.Task{}, "", storeError(err)
+		}
+		if target, ok := columnOf(anchor); err != nil || !ok || target.ID != column.ID || anchorID == realID {
+			return models.Task{}, "", ErrCardNotOnBoard.WithDetail("the card to place it next to is not in the column")
+		}
+		if anchor.ProjectID != existingTask.ProjectID {
+			return models.Task{}, "", ErrOtherList
+		}
+	}
+
+	moved := existingTask
+	var steps []models.UndoStep
+	if current.ID != column.ID {
+		if column.WIPLimit > 0 {
+			inColumn := func(t models.Task) bool {
+				c, ok := columnOf(t)
+				return ok && c.ID == column.ID
+			}
+			moved, err = s.store.UpdateWithinLimit(ctx, userID, realID, placed, existingTask.Version, inColumn, column.WIPLimit)
+		} else {
+			moved, err = s.store.Update(ctx, userID, realID, placed, existingTask.Version)
+		}
+		if errors.Is(err, ErrWIPLimitExceeded) {
+			return models.Task{}, "", err
+		}
+		if err != nil {
+			return models.Task{}, "", versionError(err, ifMatch)
+		}
+		if step, ok := s.spawnNext(ctx, userID, existingTask, moved); ok {
+			steps = append(steps, step)
+		}
+	}
+	if anchorID != 0 {
+		moved, err = s.store.Move(ctx, userID, realID, deobfuscateID(req.Before), deobfuscateID(req.After), moved.Version)
+		if err != nil {
+			return models.Task{}, "", versionError(err, ifMatch)
+		}
+	}
+	if moved.Version == existingTask.Version {
+		return publicTask(moved), "", nil
+	}
+
+	steps = append([]models.UndoStep{{TaskID: moved.ID, Version: moved.Version, Previous: existingTask, Reorder: anchorID != 0}}, steps...)
+	token := s.issueUndo(ctx, userID, steps...)
+	return publicTask(moved), token, nil
+}
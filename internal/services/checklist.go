@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+const MaxChecklistItems = 50
+
+var ErrChecklistItemNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeChecklistItemNotFound, "Checklist item not found", "the task has no checklist item with this id")
+
+func checklistProgress(items []models.ChecklistItem) models.ChecklistProgress {
+	progress := models.ChecklistProgress{Total: len(items)}
+	for _, item := range items {
+		if item.Checked {
+			progress.Done++
+		}
+	}
+	return progress
+}
+
+func itemIndex(items []models.ChecklistItem, itemID uint64) (int, error) {
+	for i, item := range items {
+		if item.ID == itemID {
+			return i, nil
+		}
+	}
+	return 0, ErrChecklistItemNotFound
+}
+
+// editChecklist applies edit to a copy of a task's checklist and stores the
+// result as a new version of the task. Items that edit adds without an ID
+// get the next one from the task's counter.
+func (s *TaskService) editChecklist(ctx context.Context, userID string, taskID, ifMatch uint64, edit func([]models.ChecklistItem) ([]models.ChecklistItem, error)) (models.Task, string, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
+	}
+	if ifMatch != 0 && existingTask.Version != ifMatch {
+		return models.Task{}, "", ErrVersionStale
+	}
+
+	items, err := edit(append([]models.ChecklistItem(nil), existingTask.Checklist...))
+	if err != nil {
+		return models.Task{}, "", err
+	}
+	previous := existingTask
+	existingTask.Checklist = items
+	numberChecklist(&existingTask)
+
+	updated, err := s.store.Update(ctx, userID, realID, existingTask, existingTask.Version)
+	if err != nil {
+		return models.Task{}, "", versionError(err, ifMatch)
+	}
+
+	token := s.issueUndo(ctx, userID, models.UndoStep{TaskID: updated.ID, Version: updated.Version, Previous: previous})
+	return publicTask(updated), token, nil
+}
+
+// numberChecklist gives the checklist items of task without an ID one from
+// its counter. Items copied from templates or earlier occurrences may carry
+// IDs the counter hasn't handed out, so it first catches up with them.
+func numberChecklist(task *models.Task) {
+	for _, item := range task.Checklist {
+		task.ChecklistCounter = max(task.ChecklistCounter, item.ID)
+	}
+	for i := range task.Checklist {
+		if task.Checklist[i].ID == 0 {
+			task.ChecklistCounter++
+			task.Checklist[i].ID = task.ChecklistCounter
+		}
+	}
+}
+
+// AddChecklistItem appends an item to a task's checklist.
+func (s *TaskService) AddChecklistItem(ctx context.Context, userID string, taskID, ifMatch uint64, req dto.ChecklistItemRequest) (models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+	return s.editChecklist(ctx, userID, taskID, ifMatch, func(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		if len(items) >= MaxChecklistItems {
+			return nil, apperr.Validation(map[string]string{
+				"checklist": fmt.Sprintf("Checklist must not exceed %d items", MaxChecklistItems),
+			})
+		}
+		return append(items, models.ChecklistItem{Text: req.Text, Checked: req.Checked}), nil
+	})
+}
+
+// UpdateChecklistItem replaces the text and checked state of an item.
+func (s *TaskService) UpdateChecklistItem(ctx context.Context, userID string, taskID, ifMatch, itemID uint64, req dto.ChecklistItemRequest) (models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+	return s.editChecklist(ctx, userID, taskID, ifMatch, func(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i, err := itemIndex(items, itemID)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Text = req.Text
+		items[i].Checked = req.Checked
+		return items, nil
+	})
+}
+
+func (s *TaskService) ToggleChecklistItem(ctx context.Context, userID string, taskID, ifMatch, itemID uint64) (models.Task, string, error) {
+	return s.editChecklist(ctx, userID, taskID, ifMatch, func(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i, err := itemIndex(items, itemID)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Checked = !items[i].Checked
+		return items, nil
+	})
+}
+
+// MoveChecklistItem moves an item to a position in the checklist. Positions
+// past the end move it to the end.
+func (s *TaskService) MoveChecklistItem(ctx context.Context, userID string, taskID, ifMatch, itemID uint64, req dto.ChecklistMoveRequest) (models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+	return s.editChecklist(ctx, userID, taskID, ifMatch, func(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i, err := itemIndex(items, itemID)
+		if err != nil {
+			return nil, err
+		}
+		item := items[i]
+		items = append(items[:i], items[i+1:]...)
+		pos := min(req.Position, len(items))
+		return append(items[:pos], append([]models.ChecklistItem{item}, items[pos:]...)...), nil
+	})
+}
+
+func (s *TaskService) RemoveChecklistItem(ctx context.Context, userID string, taskID, ifMatch, itemID uint64) (models.Task, string, error) {
+	return s.editChecklist(ctx, userID, taskID, ifMatch, func(items []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i, err := itemIndex(items, itemID)
+		if err != nil {
+			return nil, err
+		}
+		return append(items[:i], items[i+1:]...), nil
+	})
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+const (
+	DefaultCommentPageSize = 20
+	MaxCommentPageSize     = 100
+)
+
+func publicComment(comment models.Comment) models.Comment {
+	comment.ID = my_utils.ObfuscateNumbers(comment.ID)
+	comment.TaskID = my_utils.ObfuscateNumbers(comment.TaskID)
+	return comment
+}
+
+func (s *TaskService) AddComment(ctx context.Context, userID string, taskID uint64, req dto.CommentRequest) (models.Comment, error) {
+	if err := req.Validate(); err != nil {
+		return models.Comment{}, err
+	}
+
+	created, err := s.store.AddComment(ctx, userID, my_utils.DeobfuscateNumbers(taskID), models.Comment{Body: req.Body})
+	if err != nil {
+		return models.Comment{}, storeError(err)
+	}
+	return publicComment(created), nil
+}
+
+// ListComments returns a page of a task's comments, oldest first. after is
+// the cursor returned with the previous page, or 0 for the first page.
+func (s *TaskService) ListComments(ctx context.Context, userID string, taskID, after uint64, limit int) (dto.CommentPage, error) {
+	if limit < 1 || limit > MaxCommentPageSize {
+		return dto.CommentPage{}, apperr.Validation(map[string]string{
+			"limit": fmt.Sprintf("Limit must be between 1 and %d", MaxCommentPageSize),
+		})
+	}
+
+	comments, more, err := s.store.GetComments(ctx, userID, my_utils.DeobfuscateNumbers(taskID), deobfuscateID(after), limit)
+	if err != nil {
+		return dto.CommentPage{}, storeError(err)
+	}
+
+	page := dto.CommentPage{}
+	for i := range comments {
+		comments[i] = publicComment(comments[i])
+	}
+	if more && len(comments) > 0 {
+		page.NextCursor = comments[len(comments)-1].ID
+	}
+	page.Comments = comments
+	return page, nil
+}
+
+func (s *TaskService) UpdateComment(ctx context.Context, userID string, taskID, commentID uint64, req dto.CommentRequest) (models.Comment, error) {
+	if err := req.Validate(); err != nil {
+		return models.Comment{}, err
+	}
+
+	updated, err := s.store.UpdateComment(ctx, userID, my_utils.DeobfuscateNumbers(taskID), my_utils.DeobfuscateNumbers(commentID), req.Body)
+	if err != nil {
+		return models.Comment{}, storeError(err)
+	}
+	return publicComment(updated), nil
+}
+
+func (s *TaskService) DeleteComment(ctx context.Context, userID string, taskID, commentID uint64) error {
+	if err := s.store.DeleteComment(ctx, userID, my_utils.DeobfuscateNumbers(taskID), my_utils.DeobfuscateNumbers(commentID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+func publicField(field models.CustomField) models.CustomField {
+	field.ID = my_utils.ObfuscateNumbers(field.ID)
+	return field
+}
+
+// obfuscateFields and deobfuscateFields convert the field IDs that key a
+// task's custom field values.
+func obfuscateFields(values map[uint64]any) map[uint64]any {
+	return convertFieldKeys(values, my_utils.ObfuscateNumbers)
+}
+
+func deobfuscateFields(values map[uint64]any) map[uint64]any {
+	return convertFieldKeys(values, my_utils.DeobfuscateNumbers)
+}
+
+func convertFieldKeys(values map[uint64]any, convert func(uint64) uint64) map[uint64]any {
+	if values == nil {
+		return nil
+	}
+	out := make(map[uint64]any, len(values))
+	for id, v := range values {
+		out[convert(id)] = v
+	}
+	return out
+}
+
+func (s *TaskService) CreateCustomField(ctx context.Context, userID string, req dto.CustomFieldRequest) (models.CustomField, error) {
+	if err := req.Validate(); err != nil {
+		return models.CustomField{}, err
+	}
+
+	created, err := s.store.CreateCustomField(ctx, userID, models.CustomField{Name: req.Name, Type: models.FieldType(req.Type), Options: req.Options})
+	if err != nil {
+		return models.CustomField{}, storeError(err)
+	}
+	return publicField(created), nil
+}
+
+func (s *TaskService) GetCustomFields(ctx context.Context, userID string) ([]models.CustomField, error) {
+	fields, err := s.store.GetCustomFields(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range fields {
+		fields[i] = publicField(fields[i])
+	}
+	return fields, nil
+}
+
+func (s *TaskService) GetCustomField(ctx context.Context, userID string, fieldID uint64) (models.CustomField, error) {
+	field, err := s.store.GetCustomField(ctx, userID, my_utils.DeobfuscateNumbers(fieldID))
+	if err != nil {
+		return models.CustomField{}, storeError(err)
+	}
+	return publicField(field), nil
+}
+
+// UpdateCustomField renames a field or changes its options. The type of a
+// field is fixed; tasks lose values of select options that were removed.
+func (s *TaskService) UpdateCustomField(ctx context.Context, userID string, fieldID uint64, req dto.CustomFieldRequest) (models.CustomField, error) {
+	if err := req.Validate(); err != nil {
+		return models.CustomField{}, err
+	}
+
+	updated, err := s.store.UpdateCustomField(ctx, userID, my_utils.DeobfuscateNumbers(fieldID), models.CustomField{Name: req.Name, Type: models.FieldType(req.Type), Options: req.Options})
+	if err != nil {
+		return models.CustomField{}, storeError(err)
+	}
+	return publicField(updated), nil
+}
+
+// DeleteCustomField deletes a field and its values on all tasks.
+func (s *TaskService) DeleteCustomField(ctx context.Context, userID string, fieldID uint64) error {
+	if err := s.store.DeleteCustomField(ctx, userID, my_utils.DeobfuscateNumbers(fieldID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// resolveFieldFilter converts the custom field part of filter to stored
+// IDs and values. Values may be given as text, as they are in query
+// parameters, or already typed.
+func (s *TaskService) resolveFieldFilter(ctx context.Context, userID string, filter models.TaskFilter) (models.TaskFilter, error) {
+	if len(filter.Fields) == 0 && filter.SortField == 0 {
+		return filter, nil
+	}
+
+	fields, err := s.store.GetCustomFields(ctx, userID)
+	if err != nil {
+		return filter, storeError(err)
+	}
+	byID := make(map[uint64]models.CustomField, len(fields))
+	for _, f := range fields {
+		byID[f.ID] = f
+	}
+
+	values := make(map[uint64]any, len(filter.Fields))
+	for id, raw := range filter.Fields {
+		field, exists := byID[my_utils.DeobfuscateNumbers(id)]
+		if !exists {
+			return filter, apperr.Validation(map[string]string{"field": "Unknown custom field"})
+		}
+		var value any
+		if text, ok := raw.(string); ok {
+			value, err = field.ParseValue(text)
+		} else {
+			value, err = field.Normalize(raw)
+		}
+		if err != nil {
+			return filter, apperr.Validation(map[string]string{"field": err.Error()})
+		}
+		values[field.ID] = value
+	}
+	filter.Fields = values
+
+	if filter.SortField != 0 {
+		filter.SortField = my_utils.DeobfuscateNumbers(filter.SortField)
+		if _, exists := byID[filter.SortField]; !exists {
+			return filter, apperr.Validation(map[string]string{"sort": "Unknown custom field"})
+		}
+	}
+	return filter, nil
+}
+
+// sortByField orders tasks by the values of a custom field, keeping the list
+// order among equal values. Tasks without a value come last either way.
+func sortByField(tasks []models.Task, fieldID uint64, desc bool) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].CustomFields[fieldID], tasks[j].CustomFields[fieldID]
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if desc {
+			return models.CompareFieldValues(a, b) > 0
+		}
+		return models.CompareFieldValues(a, b) < 0
+	})
+}
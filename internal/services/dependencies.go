@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/models"
+)
+
+// GetReadyTasks returns the open tasks that have no open blockers, in
+// dependency order. Tasks of archived projects are left out.
+func (s *TaskService) GetReadyTasks(ctx context.Context, userID string) ([]models.Task, error) {
+	tasks, err := s.store.GetReady(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	archived, err := s.archivedProjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if !archived[task.ProjectID] {
+			ready = append(ready, publicTask(task))
+		}
+	}
+	return ready, nil
+}
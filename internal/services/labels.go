@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+func publicLabel(label models.Label) models.Label {
+	label.ID = my_utils.ObfuscateNumbers(label.ID)
+	return label
+}
+
+func (s *TaskService) CreateLabel(ctx context.Context, userID string, req dto.LabelRequest) (models.Label, error) {
+	if err := req.Validate(); err != nil {
+		return models.Label{}, err
+	}
+
+	created, err := s.store.CreateLabel(ctx, userID, models.Label{Name: req.Name, Color: req.Color})
+	if err != nil {
+		return models.Label{}, storeError(err)
+	}
+	return publicLabel(created), nil
+}
+
+func (s *TaskService) GetLabels(ctx context.Context, userID string) ([]models.Label, error) {
+	labels, err := s.store.GetLabels(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range labels {
+		labels[i] = publicLabel(labels[i])
+	}
+	return labels, nil
+}
+
+func (s *TaskService) GetLabel(ctx context.Context, userID string, labelID uint64) (models.Label, error) {
+	label, err := s.store.GetLabel(ctx, userID, my_utils.DeobfuscateNumbers(labelID))
+	if err != nil {
+		return models.Label{}, storeError(err)
+	}
+	return publicLabel(label), nil
+}
+
+func (s *TaskService) UpdateLabel(ctx context.Context, userID string, labelID uint64, req dto.LabelRequest) (models.Label, error) {
+	if err := req.Validate(); err != nil {
+		return models.Label{}, err
+	}
+
+	updated, err := s.store.UpdateLabel(ctx, userID, my_utils.DeobfuscateNumbers(labelID), models.Label{Name: req.Name, Color: req.Color})
+	if err != nil {
+		return models.Label{}, storeError(err)
+	}
+	return publicLabel(updated), nil
+}
+
+// DeleteLabel deletes a label and detaches it from all tasks.
+func (s *TaskService) DeleteLabel(ctx context.Context, userID string, labelID uint64) error {
+	if err := s.store.DeleteLabel(ctx, userID, my_utils.DeobfuscateNumbers(labelID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
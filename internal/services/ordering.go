@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"log"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+	"time"
+)
+
+// DefaultMaxRankLength is the rank length above which the rebalancer
+// respaces a list.
+const DefaultMaxRankLength = 12
+
+// MoveTask places a task right before or after another task of its list
+// and returns a token that undoes the move.
+func (s *TaskService) MoveTask(ctx context.Context, userID string, taskID uint64, ifMatch uint64, req dto.MoveTaskRequest) (models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
+	}
+	if ifMatch != 0 && existingTask.Version != ifMatch {
+		return models.Task{}, "", ErrVersionStale
+	}
+
+	moved, err := s.store.Move(ctx, userID, realID, deobfuscateID(req.Before), deobfuscateID(req.After), existingTask.Version)
+	if err != nil {
+		return models.Task{}, "", versionError(err, ifMatch)
+	}
+
+	token := s.issueUndo(ctx, userID, models.UndoStep{TaskID: moved.ID, Version: moved.Version, Previous: existingTask, Reorder: true})
+	return publicTask(moved), token, nil
+}
+
+// RunRankRebalancer respaces lists with overly long ranks every interval
+// until ctx is done.
+func (s *TaskService) RunRankRebalancer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lists, err := s.store.Rebalance(ctx, DefaultMaxRankLength)
+			if err != nil {
+				log.Printf("Rank rebalance failed: %v", storeError(err))
+				continue
+			}
+			if lists > 0 {
+				log.Printf("Rebalanced %d task lists", lists)
+			}
+		}
+	}
+}
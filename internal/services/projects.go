@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+func publicProject(project models.Project) models.Project {
+	project.ID = my_utils.ObfuscateNumbers(project.ID)
+	return project
+}
+
+func (s *TaskService) CreateProject(ctx context.Context, userID string, req dto.ProjectRequest) (models.Project, error) {
+	if err := req.Validate(); err != nil {
+		return models.Project{}, err
+	}
+
+	created, err := s.store.CreateProject(ctx, userID, models.Project{Name: req.Name})
+	if err != nil {
+		return models.Project{}, storeError(err)
+	}
+	return publicProject(created), nil
+}
+
+// GetProjects returns the user's projects, creating the Inbox on first use.
+func (s *TaskService) GetProjects(ctx context.Context, userID string) ([]models.Project, error) {
+	projects, err := s.store.GetProjects(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range projects {
+		projects[i] = publicProject(projects[i])
+	}
+	return projects, nil
+}
+
+func (s *TaskService) GetProject(ctx context.Context, userID string, projectID uint64) (models.Project, error) {
+	project, err := s.store.GetProject(ctx, userID, my_utils.DeobfuscateNumbers(projectID))
+	if err != nil {
+		return models.Project{}, storeError(err)
+	}
+	return publicProject(project), nil
+}
+
+func (s *TaskService) RenameProject(ctx context.Context, userID string, projectID uint64, req dto.ProjectRequest) (models.Project, error) {
+	if err := req.Validate(); err != nil {
+		return models.Project{}, err
+	}
+
+	project, err := s.store.RenameProject(ctx, userID, my_utils.DeobfuscateNumbers(projectID), req.Name)
+	if err != nil {
+		return models.Project{}, storeError(err)
+	}
+	return publicProject(project), nil
+}
+
+// ArchiveProject archives or unarchives a project. Tasks of an archived
+// project are left out of task listings that are not scoped to it.
+func (s *TaskService) ArchiveProject(ctx context.Context, userID string, projectID uint64, archived bool) (models.Project, error) {
+	project, err := s.store.SetProjectArchived(ctx, userID, my_utils.DeobfuscateNumbers(projectID), archived)
+	if err != nil {
+		return models.Project{}, storeError(err)
+	}
+	return publicProject(project), nil
+}
+
+// DeleteProject deletes a project. Its tasks move to the Inbox, and with
+// cascade set they are moved to the trash as well.
+func (s *TaskService) DeleteProject(ctx context.Context, userID string, projectID uint64, cascade bool) error {
+	if err := s.store.DeleteProject(ctx, userID, my_utils.DeobfuscateNumbers(projectID), cascade); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+func (s *TaskService) GetProjectTasks(ctx context.Context, userID string, projectID uint64, filter models.TaskFilter) ([]models.Task, error) {
+	if _, err := s.store.GetProject(ctx, userID, my_utils.DeobfuscateNumbers(projectID)); err != nil {
+		return nil, storeError(err)
+	}
+	filter.ProjectID = projectID
+	return s.ListTasks(ctx, userID, filter)
+}
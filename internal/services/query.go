@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/query"
+	"time"
+)
+
+// compileQuery compiles the search query of filter against the user's labels
+// and projects. Dates in the query are days in the filter's time zone.
+func (s *TaskService) compileQuery(ctx context.Context, userID string, filter models.TaskFilter) (query.Matcher, error) {
+	if strings.TrimSpace(filter.Query) == "" {
+		return func(models.Task) bool { return true }, nil
+	}
+
+	loc, err := location(filter.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	env := query.Env{
+		Labels:        make(map[string]uint64),
+		Projects:      make(map[string][]uint64),
+		Now:           time.Now().In(loc),
+		IgnoreUnknown: filter.IgnoreUnknownNames,
+	}
+
+	labels, err := s.store.GetLabels(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	for _, l := range labels {
+		env.Labels[strings.ToLower(l.Name)] = l.ID
+	}
+	projects, err := s.store.GetProjects(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	for _, p := range projects {
+		name := strings.ToLower(p.Name)
+		env.Projects[name] = append(env.Projects[name], p.ID)
+	}
+
+	matches, err := query.Compile(filter.Query, env)
+	var qe *query.Error
+	if errors.As(err, &qe) {
+		return nil, apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidQuery, "Invalid query", err)
+	}
+	return matches, err
+}
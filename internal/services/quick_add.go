@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/quickadd"
+	"time"
+	"unicode/utf8"
+)
+
+// location loads the time zone a request names, UTC when it names none.
+func location(timeZone string) (*time.Location, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, apperr.Validation(map[string]string{"timezone": "Timezone must be an IANA time zone such as Europe/Berlin"})
+	}
+	return loc, nil
+}
+
+// QuickAddTask parses a single-line entry and creates the task it describes.
+// Labels are matched by name, ignoring case, and created when missing. With
+// dryRun nothing is written and only the parse result is returned.
+func (s *TaskService) QuickAddTask(ctx context.Context, userID string, req dto.QuickAddRequest, dryRun bool) (dto.QuickAddResponse, error) {
+	if err := req.Validate(); err != nil {
+		return dto.QuickAddResponse{}, err
+	}
+
+	loc, err := location(req.TimeZone)
+	if err != nil {
+		return dto.QuickAddResponse{}, err
+	}
+
+	parsed, err := quickadd.Parse(req.Text, time.Now().In(loc))
+	if errors.Is(err, quickadd.ErrNoTitle) {
+		return dto.QuickAddResponse{}, apperr.Validation(map[string]string{"text": "Text must contain a title"})
+	}
+	if err != nil {
+		return dto.QuickAddResponse{}, err
+	}
+
+	newTask := dto.CreateTaskRequest{
+		Title:       parsed.Title,
+		Description: req.Description,
+		Priority:    parsed.Priority,
+		DueAt:       parsed.DueAt,
+		RRule:       parsed.RRule,
+	}
+	if newTask.Description == "" {
+		newTask.Description = quickAddDescription(req.Text)
+	}
+	if parsed.RRule != "" {
+		newTask.TimeZone = loc.String()
+	}
+	task, err := taskFromRequest(userID, newTask)
+	if err != nil {
+		return dto.QuickAddResponse{}, err
+	}
+	for _, name := range parsed.Labels {
+		label := dto.LabelRequest{Name: name}
+		if err := label.Validate(); err != nil {
+			return dto.QuickAddResponse{}, err
+		}
+	}
+	if dryRun {
+		return dto.QuickAddResponse{Parsed: parsed}, nil
+	}
+
+	// The labels are created together with the task, so that a failed
+	// create leaves none behind and concurrent entries share them.
+	created, err := s.store.CreateWithLabels(ctx, userID, task, parsed.Labels)
+	if err != nil {
+		return dto.QuickAddResponse{}, storeError(err)
+	}
+	public := publicTask(created)
+	return dto.QuickAddResponse{Parsed: parsed, Task: &public}, nil
+}
+
+// quickAddDescription turns an entry into a description that satisfies the
+// length limits of dto.CreateTaskRequest: entries shorter than 8 characters
+// are prefixed, and entries longer than 250 are cut off.
+func quickAddDescription(text string) string {
+	if utf8.RuneCountInString(text) < 8 {
+		return "Quick add: " + text
+	}
+	if runes := []rune(text); len(runes) > 250 {
+		return string(runes[:249]) + "…"
+	}
+	return text
+}
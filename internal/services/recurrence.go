@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/recurrence"
+	my_utils "task-backend/utils"
+	"time"
+)
+
+const (
+	DefaultPreviewOccurrences = 5
+	MaxPreviewOccurrences     = 50
+)
+
+var ErrNotRecurring = apperr.New(apperr.ErrUnprocessable, apperr.CodeNotRecurring, "Task not recurring", "the task has no recurrence rule")
+
+func parseRule(rule, timeZone string) (recurrence.Rule, *time.Location, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return recurrence.Rule{}, nil, apperr.Validation(map[string]string{"timezone": "Timezone must be an IANA time zone such as Europe/Berlin"})
+	}
+	parsed, err := recurrence.Parse(rule, loc)
+	if err != nil {
+		return recurrence.Rule{}, nil, apperr.Validation(map[string]string{"rrule": err.Error()})
+	}
+	return parsed, loc, nil
+}
+
+// schedule sets the due date and recurrence of updated. For an occurrence
+// of a series, ScopeThis keeps the series unchanged and remembers what the
+// occurrence looked like before, while ScopeFuture makes an edit of the
+// content or schedule the new basis of the series, re-anchoring the rule
+// when it or the due date changed. Edits of other fields, such as
+// completing the task, leave the series alone in either scope.
+func schedule(previous models.Task, updated *models.Task, dueAt *time.Time, rule, timeZone string, scope models.EditScope) error {
+	current := previous.Recurrence
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if scope == models.ScopeThis && current != nil && (rule != current.Rule || timeZone != current.TimeZone) {
+		return apperr.Validation(map[string]string{"rrule": "Rrule and timezone can only be changed for all future occurrences"})
+	}
+
+	updated.DueAt = dueAt
+	if rule == "" {
+		updated.Recurrence = nil
+		return nil
+	}
+	_, loc, err := parseRule(rule, timeZone)
+	if err != nil {
+		return err
+	}
+	due := dueAt.In(loc)
+	updated.DueAt = &due
+
+	if current == nil {
+		updated.Recurrence = &models.Recurrence{Rule: rule, TimeZone: timeZone, Start: due, Index: 1}
+		return nil
+	}
+
+	rec := *current
+	moved := previous.DueAt == nil || !previous.DueAt.Equal(due)
+	rescheduled := moved || rule != rec.Rule || timeZone != rec.TimeZone
+	edited := previous.Title != updated.Title || previous.Description != updated.Description
+	switch {
+	case scope == models.ScopeThis:
+		if rec.Base == nil && (moved || edited) {
+			rec.Base = &models.OccurrenceBase{Title: previous.Title, Description: previous.Description, DueAt: *previous.DueAt}
+		}
+	case rescheduled:
+		rec.Rule = rule
+		rec.TimeZone = timeZone
+		rec.Start = due
+		rec.Base = nil
+	case edited:
+		rec.Base = nil
+	}
+	updated.Recurrence = &rec
+	return nil
+}
+
+// seriesID returns the id of the first task of the series task belongs to.
+func seriesID(task models.Task) uint64 {
+	if task.Recurrence.SeriesID == 0 {
+		return task.ID
+	}
+	return task.Recurrence.SeriesID
+}
+
+// scheduled returns the title, description and due date the series had
+// planned for task, before any edit to this occurrence alone.
+func scheduled(task models.Task) models.OccurrenceBase {
+	if task.Recurrence.Base != nil {
+		return *task.Recurrence.Base
+	}
+	return models.OccurrenceBase{Title: task.Title, Description: task.Description, DueAt: *task.DueAt}
+}
+
+// nextOccurrence builds the occurrence that follows task, if the rule has
+// one left.
+func nextOccurrence(task models.Task) (models.Task, bool) {
+	rec := task.Recurrence
+	rule, loc, err := parseRule(rec.Rule, rec.TimeZone)
+	if err != nil || (rule.Count != 0 && rec.Index >= rule.Count) {
+		return models.Task{}, false
+	}
+	base := scheduled(task)
+	due, ok := rule.Next(rec.Start.In(loc), base.DueAt.In(loc))
+	if !ok {
+		return models.Task{}, false
+	}
+
+	checklist := make([]models.ChecklistItem, len(task.Checklist))
+	for i, item := range task.Checklist {
+		item.Checked = false
+		checklist[i] = item
+	}
+
+	return models.Task{
+		Title:            base.Title,
+		Description:      base.Description,
+		Priority:         task.Priority,
+		ProjectID:        task.ProjectID,
+		ParentID:         task.ParentID,
+		LabelIDs:         task.LabelIDs,
+		CustomFields:     maps.Clone(task.CustomFields),
+		Checklist:        checklist,
+		ChecklistCounter: task.ChecklistCounter,
+		DueAt:            &due,
+		Recurrence: &models.Recurrence{
+			Rule:     rec.Rule,
+			TimeZone: rec.TimeZone,
+			Start:    rec.Start,
+			SeriesID: seriesID(task),
+			Index:    rec.Index + 1,
+		},
+	}, true
+}
+
+// spawnNext creates the next occurrence when updated completes a recurring
+// task and returns the undo step that removes it again. The completion has
+// already been stored, so failures are logged instead of reported.
+func (s *TaskService) spawnNext(ctx context.Context, userID string, previous, updated models.Task) (models.UndoStep, bool) {
+	if previous.ID == 0 || previous.Completed || !updated.Completed || updated.Recurrence == nil || updated.DueAt == nil {
+		return models.UndoStep{}, false
+	}
+	next, ok := nextOccurrence(updated)
+	if !ok {
+		return models.UndoStep{}, false
+	}
+
+	// Completing an occurrence again after reopening it, or completing it in
+	// two requests at once, must not create a second copy of the next one.
+	created, ok, err := s.store.CreateOccurrence(context.WithoutCancel(ctx), userID, next)
+	if err != nil {
+		log.Printf("Failed to create next occurrence of task %d: %v", updated.ID, err)
+		return models.UndoStep{}, false
+	}
+	if !ok {
+		return models.UndoStep{}, false
+	}
+	return models.UndoStep{TaskID: created.ID, Version: created.Version}, true
+}
+
+// PreviewOccurrences returns the due dates of the next n occurrences of a
+// recurring task, starting with its own.
+func (s *TaskService) PreviewOccurrences(ctx context.Context, userID string, taskID uint64, n int) ([]time.Time, error) {
+	if n < 1 || n > MaxPreviewOccurrences {
+		return nil, apperr.Validation(map[string]string{
+			"count": fmt.Sprintf("Count must be between 1 and %d", MaxPreviewOccurrences),
+		})
+	}
+	task, err := s.store.GetByID(ctx, userID, my_utils.DeobfuscateNumbers(taskID))
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if task.Recurrence == nil || task.DueAt == nil {
+		return nil, ErrNotRecurring
+	}
+
+	out := []time.Time{*task.DueAt}
+	for len(out) < n {
+		next, ok := nextOccurrence(task)
+		if !ok {
+			break
+		}
+		out = append(out, *next.DueAt)
+		task = next
+	}
+	return out, nil
+}
+
+func publicRecurrence(task models.Task) *models.Recurrence {
+	if task.Recurrence == nil {
+		return nil
+	}
+	rec := *task.Recurrence
+	rec.SeriesID = my_utils.ObfuscateNumbers(seriesID(task))
+	return &rec
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+func (s *TaskService) GetSubtasks(ctx context.Context, userID string, taskID uint64) ([]models.Task, error) {
+	tasks, err := s.store.GetChildren(ctx, userID, my_utils.DeobfuscateNumbers(taskID))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range tasks {
+		tasks[i] = publicTask(tasks[i])
+	}
+	return tasks, nil
+}
+
+func (s *TaskService) GetTaskTree(ctx context.Context, userID string, taskID uint64) (models.TaskNode, error) {
+	root, err := s.store.GetSubtree(ctx, userID, my_utils.DeobfuscateNumbers(taskID))
+	if err != nil {
+		return models.TaskNode{}, storeError(err)
+	}
+	return publicNode(root), nil
+}
+
+func publicNode(node models.TaskNode) models.TaskNode {
+	node.Task = publicTask(node.Task)
+	for i := range node.Children {
+		node.Children[i] = publicNode(node.Children[i])
+	}
+	return node
+}
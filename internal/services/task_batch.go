@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"task-backend/internal/apperr"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+const MaxBatchSize = 100
+
+var ErrBatchAborted = apperr.New(apperr.ErrFailedDependency, apperr.CodeBatchAborted, "Batch aborted",
+	"another operation in the atomic batch failed")
+
+// BatchTasks applies a list of create, update and delete operations. Each
+// result carries either the affected task or the error for that operation.
+// The returned bool reports whether the batch was committed; an atomic batch
+// is committed only when every operation succeeds. A committed batch comes
+// with a token that undoes all of its successful operations.
+func (s *TaskService) BatchTasks(ctx context.Context, userID string, req dto.BatchRequest) ([]models.BatchResult, bool, string, error) {
+	if n := len(req.Operations); n == 0 || n > MaxBatchSize {
+		return nil, false, "", apperr.Validation(map[string]string{
+			"operations": fmt.Sprintf("Operations must contain between 1 and %d items", MaxBatchSize),
+		})
+	}
+
+	results := make([]models.BatchResult, len(req.Operations))
+	ops := make([]models.BatchOp, 0, len(req.Operations))
+	positions := make([]int, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		storeOp, err := s.batchOp(ctx, userID, op)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		ops = append(ops, storeOp)
+		positions = append(positions, i)
+	}
+
+	if req.Atomic && len(ops) < len(req.Operations) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = ErrBatchAborted
+			}
+		}
+		return results, false, "", nil
+	}
+
+	stored, committed, err := s.store.ApplyBatch(ctx, userID, ops, req.Atomic)
+	if err != nil {
+		return nil, false, "", storeError(err)
+	}
+
+	var steps []models.UndoStep
+	for j, r := range stored {
+		i := positions[j]
+		switch {
+		case r.Err != nil:
+			results[i].Err = versionError(r.Err, req.Operations[i].IfMatch)
+		case !committed:
+			results[i].Err = ErrBatchAborted
+		default:
+			steps = append(steps, models.UndoStep{TaskID: r.Task.ID, Version: r.Task.Version, Previous: r.Previous})
+			if step, ok := s.spawnNext(ctx, userID, r.Previous, r.Task); ok {
+				steps = append(steps, step)
+			}
+			r.Task = publicTask(r.Task)
+			results[i].Task = r.Task
+		}
+	}
+	return results, committed, s.issueUndo(ctx, userID, steps...), nil
+}
+
+// batchOp validates op and turns it into a repository operation. Updates
+// are full replacements built on the current task, guarded by the version
+// that was read unless the client supplied its own.
+func (s *TaskService) batchOp(ctx context.Context, userID string, op dto.BatchOperation) (models.BatchOp, error) {
+	kind := models.BatchOpKind(op.Op)
+	switch kind {
+	case models.BatchCreate, models.BatchUpdate:
+		if op.Task == nil {
+			return models.BatchOp{}, apperr.Validation(map[string]string{"task": "Task is required"})
+		}
+		if err := op.Task.Validate(); err != nil {
+			return models.BatchOp{}, err
+		}
+	case models.BatchDelete:
+	default:
+		return models.BatchOp{}, apperr.Validation(map[string]string{"op": "Op must be one of create, update, delete"})
+	}
+
+	if kind == models.BatchCreate {
+		task := models.Task{
+			Title:        op.Task.Title,
+			Description:  op.Task.Description,
+			Priority:     models.Priority(op.Task.Priority),
+			ProjectID:    deobfuscateID(op.Task.Project),
+			ParentID:     deobfuscateID(op.Task.Parent),
+			LabelIDs:     deobfuscateIDs(op.Task.Labels),
+			CustomFields: deobfuscateFields(op.Task.CustomFields),
+			DependsOn:    deobfuscateIDs(op.Task.DependsOn),
+			Completed:    op.Task.Completed,
+		}
+		if err := schedule(models.Task{}, &task, op.Task.DueAt, op.Task.RRule, op.Task.TimeZone, models.ScopeFuture); err != nil {
+			return models.BatchOp{}, err
+		}
+		return models.BatchOp{Kind: kind, Task: task}, nil
+	}
+
+	if op.ID == 0 {
+		return models.BatchOp{}, apperr.Validation(map[string]string{"id": "Id is required"})
+	}
+	realID := my_utils.DeobfuscateNumbers(op.ID)
+
+	if kind == models.BatchDelete {
+		return models.BatchOp{Kind: kind, TaskID: realID, ExpectedVersion: op.IfMatch}, nil
+	}
+
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.BatchOp{}, storeError(err)
+	}
+	expectedVersion := existingTask.Version
+	if op.IfMatch != 0 {
+		expectedVersion = op.IfMatch
+	}
+
+	previous := existingTask
+	existingTask.Title = op.Task.Title
+	existingTask.Description = op.Task.Description
+	existingTask.Priority = models.Priority(op.Task.Priority)
+	existingTask.ProjectID = deobfuscateID(op.Task.Project)
+	existingTask.ParentID = deobfuscateID(op.Task.Parent)
+	existingTask.LabelIDs = deobfuscateIDs(op.Task.Labels)
+	existingTask.CustomFields = deobfuscateFields(op.Task.CustomFields)
+	existingTask.DependsOn = deobfuscateIDs(op.Task.DependsOn)
+	existingTask.Completed = op.Task.Completed
+	if err := schedule(previous, &existingTask, op.Task.DueAt, op.Task.RRule, op.Task.TimeZone, models.ScopeFuture); err != nil {
+		return models.BatchOp{}, err
+	}
+	return models.BatchOp{Kind: kind, TaskID: realID, Task: existingTask, ExpectedVersion: expectedVersion}, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+var ErrRevisionNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeRevisionNotFound, "Revision not found", "no revision with this number is retained for the task")
+
+func (s *TaskService) GetTaskHistory(ctx context.Context, userID string, taskID uint64) ([]models.Revision, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	revs, err := s.store.GetRevisions(ctx, userID, realID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range revs {
+		revs[i] = publicRevision(revs[i])
+	}
+	return revs, nil
+}
+
+// publicRevision converts the IDs in a stored revision, including the ones
+// in its changes, to the obfuscated form that clients see.
+func publicRevision(rev models.Revision) models.Revision {
+	rev.TaskID = my_utils.ObfuscateNumbers(rev.TaskID)
+	changes := make([]models.FieldChange, len(rev.Changes))
+	for i, ch := range rev.Changes {
+		switch {
+		case ch.Field == "project" || ch.Field == "parent" || ch.Field == "labels" || ch.Field == "depends_on":
+			ch.Old, ch.New = obfuscateList(ch.Old), obfuscateList(ch.New)
+		case strings.HasPrefix(ch.Field, customFieldChange):
+			id, _ := strconv.ParseUint(strings.TrimPrefix(ch.Field, customFieldChange), 10, 64)
+			ch.Field = customFieldChange + strconv.FormatUint(obfuscateID(id), 10)
+		}
+		changes[i] = ch
+	}
+	rev.Changes = changes
+	return rev
+}
+
+// customFieldChange prefixes the field ID in the changes of custom fields.
+const customFieldChange = "custom_fields."
+
+// obfuscateList obfuscates a comma separated list of IDs.
+func obfuscateList(list string) string {
+	if list == "" {
+		return ""
+	}
+	ids := strings.Split(list, ",")
+	for i, id := range ids {
+		n, _ := strconv.ParseUint(id, 10, 64)
+		ids[i] = strconv.FormatUint(obfuscateID(n), 10)
+	}
+	return strings.Join(ids, ",")
+}
+
+func (s *TaskService) GetTaskRevision(ctx context.Context, userID string, taskID, rev uint64) (models.Revision, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	revision, err := s.getRevision(ctx, userID, realID, rev)
+	if err != nil {
+		return models.Revision{}, err
+	}
+	return publicRevision(revision), nil
+}
+
+// getRevision tells a missing revision apart from a missing task, which the
+// store reports with the same error kind.
+func (s *TaskService) getRevision(ctx context.Context, userID string, realID, rev uint64) (models.Revision, error) {
+	revision, err := s.store.GetRevision(ctx, userID, realID, rev)
+	if err == nil {
+		return revision, nil
+	}
+	if errors.Is(err, apperr.ErrNotFound) {
+		if _, getErr := s.store.GetByID(ctx, userID, realID); getErr == nil {
+			return models.Revision{}, ErrRevisionNotFound
+		}
+	}
+	return models.Revision{}, storeError(err)
+}
+
+// RevertTask restores the fields a client writes to the values they had at
+// revision rev. References to labels, custom fields and tasks deleted since
+// are dropped, and a deleted project is replaced by the current one. The
+// revert is recorded as a new revision, so it can itself be reverted.
+func (s *TaskService) RevertTask(ctx context.Context, userID string, taskID, rev, ifMatch uint64) (models.Task, string, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	revision, err := s.getRevision(ctx, userID, realID, rev)
+	if err != nil {
+		return models.Task{}, "", err
+	}
+
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
+	}
+	snapshot, err := s.restorable(ctx, userID, revision.Snapshot, existingTask)
+	if err != nil {
+		return models.Task{}, "", err
+	}
+	return s.replaceTask(ctx, userID, existingTask, ifMatch, models.ScopeFuture, taskDocument(snapshot))
+}
+
+// restorable drops what a task snapshot refers to but no longer exists.
+func (s *TaskService) restorable(ctx context.Context, userID string, snapshot, current models.Task) (models.Task, error) {
+	labels, err := s.store.GetLabels(ctx, userID)
+	if err != nil {
+		return models.Task{}, storeError(err)
+	}
+	snapshot.LabelIDs = slices.DeleteFunc(slices.Clone(snapshot.LabelIDs), func(id uint64) bool {
+		return !slices.ContainsFunc(labels, func(l models.Label) bool { return l.ID == id })
+	})
+
+	fields, err := s.store.GetCustomFields(ctx, userID)
+	if err != nil {
+		return models.Task{}, storeError(err)
+	}
+	snapshot.CustomFields = maps.Clone(snapshot.CustomFields)
+	maps.DeleteFunc(snapshot.CustomFields, func(id uint64, _ any) bool {
+		return !slices.ContainsFunc(fields, func(f models.CustomField) bool { return f.ID == id })
+	})
+
+	projects, err := s.store.GetProjects(ctx, userID)
+	if err != nil {
+		return models.Task{}, storeError(err)
+	}
+	if !slices.ContainsFunc(projects, func(p models.Project) bool { return p.ID == snapshot.ProjectID }) {
+		snapshot.ProjectID = current.ProjectID
+	}
+
+	exists := func(id uint64) (bool, error) {
+		_, err := s.store.GetByID(ctx, userID, id)
+		if errors.Is(err, apperr.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, storeError(err)
+	}
+	if snapshot.ParentID != 0 {
+		ok, err := exists(snapshot.ParentID)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if !ok {
+			snapshot.ParentID = 0
+		}
+	}
+	var deps []uint64
+	for _, id := range snapshot.DependsOn {
+		ok, err := exists(id)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if ok {
+			deps = append(deps, id)
+		}
+	}
+	snapshot.DependsOn = deps
+	return snapshot, nil
+}
@@ -1,82 +1,477 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"task-backend/internal/apperr"
 	"task-backend/internal/dto"
 	"task-backend/internal/models"
+	"task-backend/internal/patch"
 	my_utils "task-backend/utils"
+	"time"
 )
 
+// TaskRepository implementations report failures with errors wrapping
+// apperr.ErrNotFound, apperr.ErrConflict or apperr.ErrForbidden; anything
+// else is treated as a storage failure. They must stop working and return
+// ctx.Err() once ctx is done. Returned tasks have Blocked and BlockedBy
+// computed from their open dependencies, and writes that change them bump
+// the version of the dependent tasks.
 type TaskRepository interface {
-	Create(userID string, task models.Task) models.Task
-	GetAll(userID string) []models.Task
-	GetByID(userID string, taskID uint64) (models.Task, bool)
-	Update(userID string, taskID uint64, updated models.Task) bool
-	Delete(userID string, taskID uint64) bool
+	// Create and Update check labels, custom field values and dependencies,
+	// put tasks without a project into the user's Inbox and record a revision
+	// for every content change.
+	Create(ctx context.Context, userID string, task models.Task) (models.Task, error)
+	// GetAll returns the live tasks in list order: by project, then by rank.
+	GetAll(ctx context.Context, userID string) ([]models.Task, error)
+	// Query returns the live tasks match reports true for, in the order of
+	// GetAll. match sees Blocked and BlockedBy and must not call back into
+	// the repository.
+	Query(ctx context.Context, userID string, match func(models.Task) bool) ([]models.Task, error)
+	GetByID(ctx context.Context, userID string, taskID uint64) (models.Task, error)
+	// Update, Delete and Restore bump the task version by exactly one. A
+	// non-zero expectedVersion is compared with the stored version atomically
+	// and a mismatch fails with apperr.ErrConflict.
+	Update(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64) (models.Task, error)
+	// Delete moves a task to the trash, where only GetTrash, Restore, Purge
+	// and PurgeTrash see it.
+	Delete(ctx context.Context, userID string, taskID uint64, expectedVersion uint64) error
+	// ApplyBatch applies all ops in a single transaction and reports per-op
+	// failures in the results.
+	ApplyBatch(ctx context.Context, userID string, ops []models.BatchOp, atomic bool) ([]models.BatchResult, bool, error)
+	GetTrash(ctx context.Context, userID string) ([]models.Task, error)
+	Restore(ctx context.Context, userID string, taskID uint64) (models.Task, error)
+	Purge(ctx context.Context, userID string, taskID uint64) error
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int, error)
+	GetRevisions(ctx context.Context, userID string, taskID uint64) ([]models.Revision, error)
+	GetRevision(ctx context.Context, userID string, taskID, rev uint64) (models.Revision, error)
+	SaveUndo(ctx context.Context, token string, entry models.UndoEntry) error
+	Undo(ctx context.Context, userID, token string) ([]models.Task, error)
+	CreateLabel(ctx context.Context, userID string, label models.Label) (models.Label, error)
+	// CreateWithLabels creates a task with the labels named in names added,
+	// creating the missing ones along with the task.
+	CreateWithLabels(ctx context.Context, userID string, task models.Task, names []string) (models.Task, error)
+	// CreateOccurrence creates the next occurrence of a recurring series
+	// unless the series already has one with its index, live or trashed, and
+	// reports whether it did.
+	CreateOccurrence(ctx context.Context, userID string, task models.Task) (models.Task, bool, error)
+	GetLabels(ctx context.Context, userID string) ([]models.Label, error)
+	GetLabel(ctx context.Context, userID string, labelID uint64) (models.Label, error)
+	UpdateLabel(ctx context.Context, userID string, labelID uint64, updated models.Label) (models.Label, error)
+	// DeleteLabel detaches the label from all tasks.
+	DeleteLabel(ctx context.Context, userID string, labelID uint64) error
+	CreateProject(ctx context.Context, userID string, project models.Project) (models.Project, error)
+	GetProjects(ctx context.Context, userID string) ([]models.Project, error)
+	GetProject(ctx context.Context, userID string, projectID uint64) (models.Project, error)
+	RenameProject(ctx context.Context, userID string, projectID uint64, name string) (models.Project, error)
+	SetProjectArchived(ctx context.Context, userID string, projectID uint64, archived bool) (models.Project, error)
+	DeleteProject(ctx context.Context, userID string, projectID uint64, cascade bool) error
+	GetChildren(ctx context.Context, userID string, taskID uint64) ([]models.Task, error)
+	GetSubtree(ctx context.Context, userID string, taskID uint64) (models.TaskNode, error)
+	GetReady(ctx context.Context, userID string) ([]models.Task, error)
+	// Move changes only the rank of the moved task and bumps its version.
+	Move(ctx context.Context, userID string, taskID, beforeID, afterID uint64, expectedVersion uint64) (models.Task, error)
+	// Rebalance rewrites ranks without changing the order or versions.
+	Rebalance(ctx context.Context, maxLength int) (int, error)
+	// Comments and attachments can only be reached through live tasks. They
+	// are kept while their task is in the trash and removed when it is
+	// purged.
+	AddComment(ctx context.Context, userID string, taskID uint64, comment models.Comment) (models.Comment, error)
+	GetComments(ctx context.Context, userID string, taskID, after uint64, limit int) ([]models.Comment, bool, error)
+	// UpdateComment and DeleteComment fail with apperr.ErrForbidden for
+	// anyone but the comment's author.
+	UpdateComment(ctx context.Context, userID string, taskID, commentID uint64, body string) (models.Comment, error)
+	DeleteComment(ctx context.Context, userID string, taskID, commentID uint64) error
+	AddAttachment(ctx context.Context, userID string, taskID uint64, attachment models.Attachment) (models.Attachment, error)
+	GetAttachments(ctx context.Context, userID string, taskID uint64) ([]models.Attachment, error)
+	GetAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) (models.Attachment, error)
+	DeleteAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) error
+	// TakeOrphanedBlobs hands out the blob keys of removed attachments once;
+	// keys whose blobs could not be deleted are handed back with
+	// ReturnOrphanedBlobs to be retried.
+	TakeOrphanedBlobs(ctx context.Context) ([]string, error)
+	ReturnOrphanedBlobs(ctx context.Context, keys []string) error
+	CreateCustomField(ctx context.Context, userID string, field models.CustomField) (models.CustomField, error)
+	GetCustomFields(ctx context.Context, userID string) ([]models.CustomField, error)
+	GetCustomField(ctx context.Context, userID string, fieldID uint64) (models.CustomField, error)
+	// UpdateCustomField and DeleteCustomField remove the values that no
+	// longer fit from all tasks.
+	UpdateCustomField(ctx context.Context, userID string, fieldID uint64, field models.CustomField) (models.CustomField, error)
+	DeleteCustomField(ctx context.Context, userID string, fieldID uint64) error
+	CreateTemplate(ctx context.Context, userID string, template models.Template) (models.Template, error)
+	GetTemplates(ctx context.Context, userID string) ([]models.Template, error)
+	GetTemplate(ctx context.Context, userID string, templateID uint64) (models.Template, error)
+	UpdateTemplate(ctx context.Context, userID string, templateID uint64, template models.Template) (models.Template, error)
+	DeleteTemplate(ctx context.Context, userID string, templateID uint64) error
+	CreateView(ctx context.Context, userID string, view models.View) (models.View, error)
+	GetViews(ctx context.Context, userID string) ([]models.View, error)
+	GetView(ctx context.Context, userID string, viewID uint64) (models.View, error)
+	UpdateView(ctx context.Context, userID string, viewID uint64, view models.View) (models.View, error)
+	DeleteView(ctx context.Context, userID string, viewID uint64) error
+	CreateBoard(ctx context.Context, userID string, board models.Board) (models.Board, error)
+	GetBoards(ctx context.Context, userID string) ([]models.Board, error)
+	GetBoard(ctx context.Context, userID string, boardID uint64) (models.Board, error)
+	UpdateBoard(ctx context.Context, userID string, boardID uint64, board models.Board) (models.Board, error)
+	DeleteBoard(ctx context.Context, userID string, boardID uint64) error
+	UpdateWithinLimit(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64, counted func(models.Task) bool, limit int) (models.Task, error)
 }
 
+var (
+	ErrTaskNotFound  = apperr.New(apperr.ErrNotFound, apperr.CodeTaskNotFound, "Task not found", "no task with this id exists")
+	ErrTaskConflict  = apperr.New(apperr.ErrConflict, apperr.CodeTaskConflict, "Task conflict", "the task was changed by another request")
+	ErrTaskForbidden = apperr.New(apperr.ErrForbidden, apperr.CodeTaskForbidden, "Forbidden", "the task belongs to another user")
+	ErrVersionStale  = apperr.New(apperr.ErrPrecondition, apperr.CodePreconditionFailed, "Precondition failed", "the task has been modified since the supplied version")
+	ErrTimeout       = apperr.New(apperr.ErrTimeout, apperr.CodeRequestTimeout, "Request timeout", "the request did not complete before its deadline")
+	ErrCanceled      = apperr.New(apperr.ErrUnavailable, apperr.CodeRequestCanceled, "Request canceled", "the request was canceled before it completed")
+)
+
 type TaskService struct {
 	store TaskRepository
+	// UndoWindow is how long undo tokens returned by writes stay valid.
+	UndoWindow time.Duration
+	// Blobs keeps the content of attachments. Attachments are disabled
+	// without one.
+	Blobs BlobStore
+	// MaxAttachmentSize is the largest attachment accepted, in bytes.
+	MaxAttachmentSize int64
 }
 
 func NewTaskService(store TaskRepository) *TaskService {
-	return &TaskService{store: store}
+	return &TaskService{store: store, UndoWindow: DefaultUndoWindow, MaxAttachmentSize: DefaultMaxAttachmentSize}
+}
+
+// publicTask converts the IDs in a stored task to the obfuscated form that
+// clients see.
+func publicTask(task models.Task) models.Task {
+	task.Recurrence = publicRecurrence(task)
+	task.Progress = checklistProgress(task.Checklist)
+	task.ID = my_utils.ObfuscateNumbers(task.ID)
+	task.ProjectID = obfuscateID(task.ProjectID)
+	task.ParentID = obfuscateID(task.ParentID)
+	task.LabelIDs = obfuscateIDs(task.LabelIDs)
+	task.CustomFields = obfuscateFields(task.CustomFields)
+	task.DependsOn = obfuscateIDs(task.DependsOn)
+	task.BlockedBy = obfuscateIDs(task.BlockedBy)
+	return task
+}
+
+// obfuscateID and deobfuscateID keep 0, which stands for "not set" in
+// optional references.
+func obfuscateID(id uint64) uint64 {
+	if id == 0 {
+		return 0
+	}
+	return my_utils.ObfuscateNumbers(id)
+}
+
+func deobfuscateID(id uint64) uint64 {
+	if id == 0 {
+		return 0
+	}
+	return my_utils.DeobfuscateNumbers(id)
+}
+
+func obfuscateIDs(ids []uint64) []uint64 {
+	if ids == nil {
+		return nil
+	}
+	out := make([]uint64, len(ids))
+	for i, id := range ids {
+		out[i] = my_utils.ObfuscateNumbers(id)
+	}
+	return out
+}
+
+func deobfuscateIDs(ids []uint64) []uint64 {
+	if ids == nil {
+		return nil
+	}
+	out := make([]uint64, len(ids))
+	for i, id := range ids {
+		out[i] = my_utils.DeobfuscateNumbers(id)
+	}
+	return out
+}
+
+func storeError(err error) error {
+	if _, ok := apperr.As(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled
+	case errors.Is(err, apperr.ErrNotFound):
+		return ErrTaskNotFound
+	case errors.Is(err, apperr.ErrConflict):
+		return ErrTaskConflict
+	case errors.Is(err, apperr.ErrForbidden):
+		return ErrTaskForbidden
+	default:
+		return &apperr.Error{
+			Kind:   apperr.ErrInternal,
+			Code:   apperr.CodeStorageFailure,
+			Title:  "Storage failure",
+			Detail: "the task store failed to process the request",
+			Err:    err,
+		}
+	}
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, userID string, newTask dto.CreateTaskRequest) (models.Task, error) {
+	task, err := taskFromRequest(userID, newTask)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	created, err := s.store.Create(ctx, userID, task)
+	if err != nil {
+		return models.Task{}, storeError(err)
+	}
+	return publicTask(created), nil
 }
 
-func (s *TaskService) CreateTask(ctx context.Context, userID string, newTask dto.CreateTaskRequest) models.Task {
+// taskFromRequest validates a create request and builds the task it
+// describes.
+func taskFromRequest(userID string, newTask dto.CreateTaskRequest) (models.Task, error) {
+	if err := newTask.Validate(); err != nil {
+		return models.Task{}, err
+	}
+
 	task := models.Task{
-		Title:       newTask.Title,
-		Description: newTask.Description,
-		UserID:      userID,
+		Title:        newTask.Title,
+		Description:  newTask.Description,
+		Priority:     models.Priority(newTask.Priority),
+		UserID:       userID,
+		ProjectID:    deobfuscateID(newTask.Project),
+		ParentID:     deobfuscateID(newTask.Parent),
+		LabelIDs:     deobfuscateIDs(newTask.Labels),
+		CustomFields: deobfuscateFields(newTask.CustomFields),
+		DependsOn:    deobfuscateIDs(newTask.DependsOn),
+		Completed:    newTask.Completed,
 	}
+	if err := schedule(models.Task{}, &task, newTask.DueAt, newTask.RRule, newTask.TimeZone, models.ScopeFuture); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
 
-	created := s.store.Create(userID, task)
-	created.ID = my_utils.ObfuscateNumbers(created.ID)
-	return created
+func (s *TaskService) GetAllTasks(ctx context.Context, userID string) ([]models.Task, error) {
+	return s.ListTasks(ctx, userID, models.TaskFilter{})
 }
 
-func (s *TaskService) GetAllTasks(ctx context.Context, userID string) []models.Task {
-	tasks := s.store.GetAll(userID)
+// ListTasks returns the tasks matching filter. IDs in filter are in the
+// obfuscated form clients use.
+func (s *TaskService) ListTasks(ctx context.Context, userID string, filter models.TaskFilter) ([]models.Task, error) {
+	filter.ProjectID = deobfuscateID(filter.ProjectID)
+	filter.LabelIDs = deobfuscateIDs(filter.LabelIDs)
+	filter, err := s.resolveFieldFilter(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter, err = resolveDueWindow(filter, time.Now()); err != nil {
+		return nil, err
+	}
+	matchesQuery, err := s.compileQuery(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks of archived projects only show up when that project is listed.
+	archived := make(map[uint64]bool)
+	if filter.ProjectID == 0 {
+		if archived, err = s.archivedProjects(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
 
-	for i := range tasks {
-		tasks[i].ID = my_utils.ObfuscateNumbers(tasks[i].ID)
+	matched, err := s.store.Query(ctx, userID, func(task models.Task) bool {
+		return filter.Matches(task) && matchesQuery(task) && !archived[task.ProjectID]
+	})
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if filter.SortField != 0 {
+		sortByField(matched, filter.SortField, filter.SortDesc)
 	}
-	return tasks
+	for i := range matched {
+		matched[i] = publicTask(matched[i])
+	}
+	return matched, nil
 }
 
-func (s *TaskService) GetTaskByID(ctx context.Context, userID string, taskID uint64) (models.Task, bool) {
+// resolveDueWindow turns the relative due window of filter into due date
+// bounds, with days starting at midnight in the filter's time zone.
+func resolveDueWindow(filter models.TaskFilter, now time.Time) (models.TaskFilter, error) {
+	if filter.Due == "" {
+		return filter, nil
+	}
+	loc, err := location(filter.TimeZone)
+	if err != nil {
+		return filter, err
+	}
+	window, ok := filter.Due.Filter(now.In(loc))
+	if !ok {
+		return filter, apperr.Validation(map[string]string{"due": "Due must be one of overdue, today, next_7_days, none"})
+	}
+
+	filter.DueAfter, filter.DueBefore, filter.NoDueDate = window.DueAfter, window.DueBefore, window.NoDueDate
+	if filter.Status == "" {
+		filter.Status = window.Status
+	}
+	return filter, nil
+}
+
+func (s *TaskService) archivedProjects(ctx context.Context, userID string) (map[uint64]bool, error) {
+	projects, err := s.store.GetProjects(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	archived := make(map[uint64]bool, len(projects))
+	for _, p := range projects {
+		archived[p.ID] = p.Archived
+	}
+	return archived, nil
+}
+
+func (s *TaskService) GetTaskByID(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
 	realID := my_utils.DeobfuscateNumbers(taskID)
-	task, found := s.store.GetByID(userID, realID)
-	if found {
-		task.ID = my_utils.ObfuscateNumbers(task.ID)
+	task, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, storeError(err)
 	}
-	return task, found
+	return publicTask(task), nil
 }
 
-func (s *TaskService) UpdateTask(ctx context.Context, userID string, taskID uint64, updateData dto.UpdateTaskRequest) (models.Task, bool) {
+// UpdateTask replaces a task. ifMatch is the version the client based its
+// changes on, or 0 when it did not send one. scope selects the occurrences
+// of a recurring task the change applies to.
+func (s *TaskService) UpdateTask(ctx context.Context, userID string, taskID uint64, ifMatch uint64, scope models.EditScope, updateData dto.UpdateTaskRequest) (models.Task, string, error) {
 	realID := my_utils.DeobfuscateNumbers(taskID)
-	existingTask, found := s.store.GetByID(userID, realID)
-	if !found {
-		return models.Task{}, false
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
 	}
+	return s.replaceTask(ctx, userID, existingTask, ifMatch, scope, updateData)
+}
 
-	if updateData.Title != nil {
-		existingTask.Title = *updateData.Title
+func (s *TaskService) PatchTask(ctx context.Context, userID string, taskID uint64, ifMatch uint64, scope models.EditScope, p patch.Patch) (models.Task, string, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, "", storeError(err)
 	}
-	if updateData.Description != nil {
-		existingTask.Description = *updateData.Description
+	if ifMatch != 0 && existingTask.Version != ifMatch {
+		return models.Task{}, "", ErrVersionStale
 	}
 
-	ok := s.store.Update(userID, realID, existingTask)
-	if !ok {
-		return models.Task{}, false
+	doc, err := json.Marshal(taskDocument(existingTask))
+	if err != nil {
+		return models.Task{}, "", err
+	}
+
+	patched, err := p.Apply(doc)
+	if err != nil {
+		if errors.Is(err, patch.ErrTestFailed) {
+			return models.Task{}, "", apperr.Wrap(apperr.ErrConflict, apperr.CodePatchTestFailed, "Patch test failed", err)
+		}
+		return models.Task{}, "", apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidPatch, "Invalid patch", err)
+	}
+
+	var updateData dto.UpdateTaskRequest
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&updateData); err != nil {
+		return models.Task{}, "", apperr.Wrap(apperr.ErrInvalid, apperr.CodeInvalidPatch, "Invalid patch", err)
 	}
 
-	existingTask.ID = my_utils.ObfuscateNumbers(existingTask.ID)
-	return existingTask, true
+	return s.replaceTask(ctx, userID, existingTask, ifMatch, scope, updateData)
 }
 
-func (s *TaskService) DeleteTask(ctx context.Context, userID string, taskID uint64) bool {
+func taskDocument(task models.Task) dto.UpdateTaskRequest {
+	return dto.UpdateTaskRequest{
+		Title:        task.Title,
+		Description:  task.Description,
+		Priority:     string(task.Priority),
+		Project:      obfuscateID(task.ProjectID),
+		Parent:       obfuscateID(task.ParentID),
+		Labels:       append([]uint64{}, obfuscateIDs(task.LabelIDs)...),
+		CustomFields: obfuscateFields(task.CustomFields),
+		DependsOn:    append([]uint64{}, obfuscateIDs(task.DependsOn)...),
+		Completed:    task.Completed,
+		DueAt:        task.DueAt,
+		RRule:        task.Recurrence.GetRule(),
+		TimeZone:     task.Recurrence.GetTimeZone(),
+	}
+}
+
+// replaceTask writes updateData over existingTask. The write is conditional
+// on the version that was read, so a concurrent change is reported as a
+// conflict, or as a failed precondition when the client supplied ifMatch.
+// Completing an occurrence of a recurring task creates the next one.
+func (s *TaskService) replaceTask(ctx context.Context, userID string, existingTask models.Task, ifMatch uint64, scope models.EditScope, updateData dto.UpdateTaskRequest) (models.Task, string, error) {
+	if err := updateData.Validate(); err != nil {
+		return models.Task{}, "", err
+	}
+
+	expectedVersion := existingTask.Version
+	if ifMatch != 0 {
+		expectedVersion = ifMatch
+	}
+
+	previous := existingTask
+	existingTask.Title = updateData.Title
+	existingTask.Description = updateData.Description
+	existingTask.Priority = models.Priority(updateData.Priority)
+	existingTask.ProjectID = deobfuscateID(updateData.Project)
+	existingTask.ParentID = deobfuscateID(updateData.Parent)
+	existingTask.LabelIDs = deobfuscateIDs(updateData.Labels)
+	existingTask.CustomFields = deobfuscateFields(updateData.CustomFields)
+	existingTask.DependsOn = deobfuscateIDs(updateData.DependsOn)
+	existingTask.Completed = updateData.Completed
+	if err := schedule(previous, &existingTask, updateData.DueAt, updateData.RRule, updateData.TimeZone, scope); err != nil {
+		return models.Task{}, "", err
+	}
+
+	updated, err := s.store.Update(ctx, userID, existingTask.ID, existingTask, expectedVersion)
+	if err != nil {
+		return models.Task{}, "", versionError(err, ifMatch)
+	}
+
+	steps := []models.UndoStep{{TaskID: updated.ID, Version: updated.Version, Previous: previous}}
+	if step, ok := s.spawnNext(ctx, userID, previous, updated); ok {
+		steps = append(steps, step)
+	}
+	token := s.issueUndo(ctx, userID, steps...)
+	return publicTask(updated), token, nil
+}
+
+func versionError(err error, ifMatch uint64) error {
+	if ifMatch != 0 && errors.Is(err, apperr.ErrConflict) {
+		return ErrVersionStale
+	}
+	return storeError(err)
+}
+
+// DeleteTask moves a task to the trash and returns a token that undoes it.
+// The task's comments are kept so that restoring it brings them back; they
+// are removed when the task is purged.
+func (s *TaskService) DeleteTask(ctx context.Context, userID string, taskID uint64, ifMatch uint64) (string, error) {
 	realID := my_utils.DeobfuscateNumbers(taskID)
-	return s.store.Delete(userID, realID)
+	existingTask, err := s.store.GetByID(ctx, userID, realID)
+	if err != nil {
+		return "", storeError(err)
+	}
+	if ifMatch != 0 && existingTask.Version != ifMatch {
+		return "", ErrVersionStale
+	}
+
+	if err := s.store.Delete(ctx, userID, realID, existingTask.Version); err != nil {
+		return "", versionError(err, ifMatch)
+	}
+	return s.issueUndo(ctx, userID, models.UndoStep{TaskID: realID, Version: existingTask.Version + 1, Previous: existingTask}), nil
 }
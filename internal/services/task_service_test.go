@@ -2,15 +2,24 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"task-backend/internal/apperr"
 	"task-backend/internal/dto"
 	"task-backend/internal/models"
+	"task-backend/internal/patch"
 	my_utils "task-backend/utils"
 	"testing"
+	"time"
 )
 
+// MockTaskStore embeds TaskRepository so it keeps satisfying the interface
+// as it grows; methods the tests don't exercise panic when called.
 type MockTaskStore struct {
+	TaskRepository
 	tasks  map[uint64]models.Task
 	nextID uint64
+	err    error
 }
 
 func NewMockTaskStore() *MockTaskStore {
@@ -20,50 +29,100 @@ func NewMockTaskStore() *MockTaskStore {
 	}
 }
 
-func (m *MockTaskStore) Create(userID string, task models.Task) models.Task {
+func (m *MockTaskStore) Create(ctx context.Context, userID string, task models.Task) (models.Task, error) {
+	if m.err != nil {
+		return models.Task{}, m.err
+	}
 	task.ID = m.nextID
 	task.UserID = userID
+	task.Version = 1
 	m.nextID++
 	m.tasks[task.ID] = task
-	return task
+	return task, nil
 }
 
-func (m *MockTaskStore) GetAll(userID string) []models.Task {
+func (m *MockTaskStore) GetAll(ctx context.Context, userID string) ([]models.Task, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
 	var result []models.Task
 	for _, t := range m.tasks {
 		if t.UserID == userID {
 			result = append(result, t)
 		}
 	}
-	return result
+	return result, nil
 }
 
-func (m *MockTaskStore) GetByID(userID string, taskID uint64) (models.Task, bool) {
+func (m *MockTaskStore) Query(ctx context.Context, userID string, match func(models.Task) bool) ([]models.Task, error) {
+	tasks, err := m.GetAll(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var result []models.Task
+	for _, t := range tasks {
+		if match(t) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTaskStore) GetByID(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
+	if m.err != nil {
+		return models.Task{}, m.err
+	}
 	task, found := m.tasks[taskID]
 	if !found || task.UserID != userID {
-		return models.Task{}, false
+		return models.Task{}, apperr.ErrNotFound
 	}
-	return task, true
+	return task, nil
 }
 
-func (m *MockTaskStore) Update(userID string, taskID uint64, updated models.Task) bool {
+func (m *MockTaskStore) Update(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64) (models.Task, error) {
+	if m.err != nil {
+		return models.Task{}, m.err
+	}
 	task, found := m.tasks[taskID]
 	if !found || task.UserID != userID {
-		return false
+		return models.Task{}, apperr.ErrNotFound
+	}
+	if expectedVersion != 0 && task.Version != expectedVersion {
+		return models.Task{}, apperr.ErrConflict
 	}
 	updated.ID = taskID
 	updated.UserID = userID
+	updated.Version = task.Version + 1
 	m.tasks[taskID] = updated
-	return true
+	return updated, nil
 }
 
-func (m *MockTaskStore) Delete(userID string, taskID uint64) bool {
+func (m *MockTaskStore) Delete(ctx context.Context, userID string, taskID uint64, expectedVersion uint64) error {
+	if m.err != nil {
+		return m.err
+	}
 	task, found := m.tasks[taskID]
 	if !found || task.UserID != userID {
-		return false
+		return apperr.ErrNotFound
+	}
+	if expectedVersion != 0 && task.Version != expectedVersion {
+		return apperr.ErrConflict
 	}
 	delete(m.tasks, taskID)
-	return true
+	return nil
+}
+
+func (m *MockTaskStore) CreateOccurrence(ctx context.Context, userID string, task models.Task) (models.Task, bool, error) {
+	created, err := m.Create(ctx, userID, task)
+	return created, err == nil, err
+}
+
+func (m *MockTaskStore) GetProjects(ctx context.Context, userID string) ([]models.Project, error) {
+	return nil, nil
+}
+
+func (m *MockTaskStore) SaveUndo(ctx context.Context, token string, entry models.UndoEntry) error {
+	return nil
 }
 
 func TestTaskService_CreateTask(t *testing.T) {
@@ -75,7 +134,10 @@ func TestTaskService_CreateTask(t *testing.T) {
 		Description: "Description here",
 	}
 
-	created := service.CreateTask(context.Background(), "user1", req)
+	created, err := service.CreateTask(context.Background(), "user1", req)
+	if err != nil {
+		t.Fatalf("CreateTask returned error: %v", err)
+	}
 	if created.Title != req.Title || created.Description != req.Description || created.UserID != "user1" {
 		t.Errorf("CreateTask returned wrong task data: %+v", created)
 	}
@@ -89,11 +151,14 @@ func TestTaskService_GetAllTasks(t *testing.T) {
 	store := NewMockTaskStore()
 	service := NewTaskService(store)
 
-	store.Create("user1", models.Task{Title: "Task1", Description: "Desc1"})
-	store.Create("user2", models.Task{Title: "Task2", Description: "Desc2"})
-	store.Create("user1", models.Task{Title: "Task3", Description: "Desc3"})
+	store.Create(context.Background(), "user1", models.Task{Title: "Task1", Description: "Desc1"})
+	store.Create(context.Background(), "user2", models.Task{Title: "Task2", Description: "Desc2"})
+	store.Create(context.Background(), "user1", models.Task{Title: "Task3", Description: "Desc3"})
 
-	tasks := service.GetAllTasks(context.Background(), "user1")
+	tasks, err := service.GetAllTasks(context.Background(), "user1")
+	if err != nil {
+		t.Fatalf("GetAllTasks returned error: %v", err)
+	}
 
 	if len(tasks) != 2 {
 		t.Errorf("Expected 2 tasks for user1, got %d", len(tasks))
@@ -113,13 +178,13 @@ func TestTaskService_GetTaskByID(t *testing.T) {
 	store := NewMockTaskStore()
 	service := NewTaskService(store)
 
-	task := store.Create("user1", models.Task{Title: "Task1", Description: "Desc1"})
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Task1", Description: "Desc1"})
 
 	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
 
-	gotTask, found := service.GetTaskByID(context.Background(), "user1", obfuscatedID)
-	if !found {
-		t.Error("Expected to find task, but did not")
+	gotTask, err := service.GetTaskByID(context.Background(), "user1", obfuscatedID)
+	if err != nil {
+		t.Errorf("Expected to find task, got error: %v", err)
 	}
 
 	if gotTask.Title != task.Title || gotTask.Description != task.Description {
@@ -130,9 +195,9 @@ func TestTaskService_GetTaskByID(t *testing.T) {
 		t.Error("Returned task ID is not obfuscated")
 	}
 
-	_, found = service.GetTaskByID(context.Background(), "user2", obfuscatedID)
-	if found {
-		t.Error("Should not find task for wrong user")
+	_, err = service.GetTaskByID(context.Background(), "user2", obfuscatedID)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound for wrong user, got %v", err)
 	}
 }
 
@@ -140,19 +205,19 @@ func TestTaskService_UpdateTask(t *testing.T) {
 	store := NewMockTaskStore()
 	service := NewTaskService(store)
 
-	task := store.Create("user1", models.Task{Title: "Old Title", Description: "Old Desc"})
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Old Title", Description: "Old Desc"})
 	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
 
 	newTitle := "New Title"
-	newDesc := "New Desc"
+	newDesc := "New Description"
 	updateReq := dto.UpdateTaskRequest{
-		Title:       &newTitle,
-		Description: &newDesc,
+		Title:       newTitle,
+		Description: newDesc,
 	}
 
-	updatedTask, ok := service.UpdateTask(context.Background(), "user1", obfuscatedID, updateReq)
-	if !ok {
-		t.Fatal("UpdateTask failed")
+	updatedTask, _, err := service.UpdateTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture, updateReq)
+	if err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
 	}
 
 	if updatedTask.Title != newTitle || updatedTask.Description != newDesc {
@@ -163,9 +228,87 @@ func TestTaskService_UpdateTask(t *testing.T) {
 		t.Error("Updated task ID is not obfuscated")
 	}
 
-	_, ok = service.UpdateTask(context.Background(), "user2", obfuscatedID, updateReq)
-	if ok {
-		t.Error("UpdateTask should fail for wrong user")
+	_, _, err = service.UpdateTask(context.Background(), "user2", obfuscatedID, 0, models.ScopeFuture, updateReq)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("UpdateTask should fail for wrong user, got %v", err)
+	}
+}
+
+func TestTaskService_UpdateTask_RequiresFullDocument(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Old Title", Description: "Old Description"})
+	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
+
+	_, _, err := service.UpdateTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture, dto.UpdateTaskRequest{Title: "New Title"})
+	e, ok := apperr.As(err)
+	if !ok || e.Code != apperr.CodeValidationFailed || e.Fields["description"] == "" {
+		t.Fatalf("Expected validation error for missing description, got %v", err)
+	}
+
+	stored, _ := store.GetByID(context.Background(), "user1", task.ID)
+	if stored.Title != "Old Title" {
+		t.Errorf("Invalid replacement must not modify the task: %+v", stored)
+	}
+}
+
+func TestTaskService_PatchTask(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Old Title", Description: "Old Description"})
+	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
+
+	patched, _, err := service.PatchTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture, patch.MergePatch(`{"title":"Merged Title"}`))
+	if err != nil {
+		t.Fatalf("PatchTask failed: %v", err)
+	}
+	if patched.Title != "Merged Title" || patched.Description != "Old Description" {
+		t.Errorf("Merge patch applied incorrectly: %+v", patched)
+	}
+
+	_, _, err = service.PatchTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture,
+		patch.JSONPatch(`[{"op":"test","path":"/title","value":"Old Title"},{"op":"replace","path":"/title","value":"Other Title"}]`))
+	if !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected failed test op to conflict, got %v", err)
+	}
+
+	_, _, err = service.PatchTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture, patch.MergePatch(`{"description":null}`))
+	if e, ok := apperr.As(err); !ok || e.Code != apperr.CodeValidationFailed {
+		t.Errorf("Expected removing description to fail validation, got %v", err)
+	}
+
+	_, _, err = service.PatchTask(context.Background(), "user1", obfuscatedID, 0, models.ScopeFuture, patch.MergePatch(`{"owner":"someone"}`))
+	if e, ok := apperr.As(err); !ok || e.Code != apperr.CodeInvalidPatch {
+		t.Errorf("Expected unknown member to be rejected, got %v", err)
+	}
+}
+
+func TestTaskService_CompleteRecurringTask_KeepsPriority(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+
+	due := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	task, _ := store.Create(context.Background(), "user1", models.Task{
+		Title:       "Weekly report",
+		Description: "Send the weekly report",
+		Priority:    models.PriorityHigh,
+		DueAt:       &due,
+		Recurrence:  &models.Recurrence{Rule: "FREQ=WEEKLY", TimeZone: "UTC", Start: due, Index: 1},
+	})
+
+	_, _, err := service.PatchTask(context.Background(), "user1", my_utils.ObfuscateNumbers(task.ID), 0, models.ScopeFuture, patch.MergePatch(`{"completed":true}`))
+	if err != nil {
+		t.Fatalf("PatchTask failed: %v", err)
+	}
+
+	next, found := store.tasks[task.ID+1]
+	if !found {
+		t.Fatal("Expected completing the task to create the next occurrence")
+	}
+	if next.Priority != models.PriorityHigh {
+		t.Errorf("Expected the next occurrence to keep priority high, got %q", next.Priority)
 	}
 }
 
@@ -173,21 +316,177 @@ func TestTaskService_DeleteTask(t *testing.T) {
 	store := NewMockTaskStore()
 	service := NewTaskService(store)
 
-	task := store.Create("user1", models.Task{Title: "Title", Description: "Desc"})
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Title", Description: "Desc"})
 	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
 
-	ok := service.DeleteTask(context.Background(), "user1", obfuscatedID)
-	if !ok {
-		t.Error("DeleteTask failed")
+	if _, err := service.DeleteTask(context.Background(), "user1", obfuscatedID, 0); err != nil {
+		t.Errorf("DeleteTask failed: %v", err)
 	}
 
-	_, found := store.GetByID("user1", task.ID)
-	if found {
+	_, err := store.GetByID(context.Background(), "user1", task.ID)
+	if err == nil {
 		t.Error("Task was not deleted")
 	}
 
-	ok = service.DeleteTask(context.Background(), "user2", obfuscatedID)
-	if ok {
-		t.Error("DeleteTask should fail for wrong user")
+	_, err = service.DeleteTask(context.Background(), "user2", obfuscatedID, 0)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("DeleteTask should fail for wrong user, got %v", err)
+	}
+}
+
+func TestTaskService_StoreErrors(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+
+	tests := []struct {
+		name     string
+		storeErr error
+		want     error
+		wantKind error
+	}{
+		{"not found", fmt.Errorf("task 1: %w", apperr.ErrNotFound), ErrTaskNotFound, apperr.ErrNotFound},
+		{"conflict", fmt.Errorf("task 1: %w", apperr.ErrConflict), ErrTaskConflict, apperr.ErrConflict},
+		{"forbidden", fmt.Errorf("task 1: %w", apperr.ErrForbidden), ErrTaskForbidden, apperr.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.err = tt.storeErr
+			_, err := service.GetTaskByID(context.Background(), "user1", 1)
+			if !errors.Is(err, tt.want) || !errors.Is(err, tt.wantKind) {
+				t.Errorf("GetTaskByID error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	diskErr := errors.New("disk on fire")
+	store.err = diskErr
+	_, err := service.DeleteTask(context.Background(), "user1", 1, 0)
+	if !errors.Is(err, apperr.ErrInternal) || !errors.Is(err, diskErr) {
+		t.Errorf("DeleteTask should wrap storage failures, got %v", err)
+	}
+	if e, ok := apperr.As(err); !ok || e.Code != apperr.CodeStorageFailure {
+		t.Errorf("Expected storage_failure code, got %v", err)
+	}
+}
+
+func TestTaskService_UpdateTask_IfMatch(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Old Title", Description: "Old Description"})
+	obfuscatedID := my_utils.ObfuscateNumbers(task.ID)
+	req := dto.UpdateTaskRequest{Title: "New Title", Description: "New Description"}
+
+	updated, _, err := service.UpdateTask(context.Background(), "user1", obfuscatedID, 1, models.ScopeFuture, req)
+	if err != nil {
+		t.Fatalf("UpdateTask with current version failed: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", updated.Version)
+	}
+
+	_, _, err = service.UpdateTask(context.Background(), "user1", obfuscatedID, 1, models.ScopeFuture, req)
+	if !errors.Is(err, ErrVersionStale) || !errors.Is(err, apperr.ErrPrecondition) {
+		t.Errorf("Expected stale version to fail the precondition, got %v", err)
+	}
+
+	_, err = service.DeleteTask(context.Background(), "user1", obfuscatedID, 1)
+	if !errors.Is(err, ErrVersionStale) {
+		t.Errorf("Expected delete with stale version to fail, got %v", err)
+	}
+	if _, err := service.DeleteTask(context.Background(), "user1", obfuscatedID, 2); err != nil {
+		t.Errorf("Expected delete with current version to succeed, got %v", err)
+	}
+}
+
+func TestResolveDueWindow(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC) // already Tuesday in Berlin
+	tuesday := time.Date(2026, 10, 20, 0, 0, 0, 0, berlin)
+
+	today, err := resolveDueWindow(models.TaskFilter{Due: models.DueToday, TimeZone: "Europe/Berlin"}, now)
+	if err != nil || !today.DueAfter.Equal(tuesday) || !today.DueBefore.Equal(tuesday.AddDate(0, 0, 1)) {
+		t.Errorf("Expected today to cover Tuesday in Berlin, got %v to %v, %v", today.DueAfter, today.DueBefore, err)
+	}
+	week, _ := resolveDueWindow(models.TaskFilter{Due: models.DueNext7Days, TimeZone: "Europe/Berlin"}, now)
+	if !week.DueBefore.Equal(tuesday.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the week to end on the next Tuesday, got %v", week.DueBefore)
+	}
+	overdue, _ := resolveDueWindow(models.TaskFilter{Due: models.DueOverdue}, now)
+	if !overdue.DueBefore.Equal(now) || overdue.DueAfter != nil || overdue.Status != models.StatusOpen {
+		t.Errorf("Expected overdue to select open tasks due before now, got %+v", overdue)
+	}
+	if none, _ := resolveDueWindow(models.TaskFilter{Due: models.DueNone}, now); !none.NoDueDate {
+		t.Error("Expected none to select tasks without a due date")
+	}
+	if _, err := resolveDueWindow(models.TaskFilter{Due: "someday"}, now); !errors.Is(err, apperr.ErrInvalid) {
+		t.Errorf("Expected unknown windows to be rejected, got %v", err)
+	}
+}
+
+func TestTaskService_Checklist(t *testing.T) {
+	store := NewMockTaskStore()
+	service := NewTaskService(store)
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Pack"})
+	id := my_utils.ObfuscateNumbers(task.ID)
+	add := func(text string, checked bool) (models.Task, error) {
+		updated, _, err := service.AddChecklistItem(ctx, "user1", id, 0, dto.ChecklistItemRequest{Text: text, Checked: checked})
+		return updated, err
+	}
+	ids := func(task models.Task) []uint64 {
+		out := make([]uint64, len(task.Checklist))
+		for i, item := range task.Checklist {
+			out[i] = item.ID
+		}
+		return out
+	}
+
+	_, _ = add("Toothbrush", true)
+	_, _ = add("Passport", false)
+	updated, err := add("Charger", false)
+	if err != nil {
+		t.Fatalf("AddChecklistItem failed: %v", err)
+	}
+	if updated.Progress != (models.ChecklistProgress{Done: 1, Total: 3}) {
+		t.Errorf("Expected 1 of 3 items done, got %+v", updated.Progress)
+	}
+
+	// Removed IDs are not handed out again, even for the last item.
+	_, _, _ = service.RemoveChecklistItem(ctx, "user1", id, 0, 3)
+	updated, _ = add("Adapter", false)
+	if got := ids(updated); fmt.Sprint(got) != "[1 2 4]" {
+		t.Errorf("Expected the new item to get a fresh ID, got %v", got)
+	}
+
+	updated, _, err = service.MoveChecklistItem(ctx, "user1", id, 0, 4, dto.ChecklistMoveRequest{Position: 0})
+	if err != nil || fmt.Sprint(ids(updated)) != "[4 1 2]" {
+		t.Errorf("Expected the item to move to the front, got %v (%v)", ids(updated), err)
+	}
+	updated, _, _ = service.MoveChecklistItem(ctx, "user1", id, 0, 4, dto.ChecklistMoveRequest{Position: 99})
+	if got := ids(updated); fmt.Sprint(got) != "[1 2 4]" {
+		t.Errorf("Expected positions past the end to move the item to the end, got %v", got)
+	}
+	if _, _, err := service.MoveChecklistItem(ctx, "user1", id, 0, 4, dto.ChecklistMoveRequest{Position: -1}); !errors.Is(err, apperr.ErrInvalid) {
+		t.Errorf("Expected negative positions to be rejected, got %v", err)
+	}
+	if _, _, err := service.MoveChecklistItem(ctx, "user1", id, 0, 3, dto.ChecklistMoveRequest{}); !errors.Is(err, ErrChecklistItemNotFound) {
+		t.Errorf("Expected moving a removed item to fail, got %v", err)
+	}
+
+	updated, _, _ = service.ToggleChecklistItem(ctx, "user1", id, 0, 2)
+	if updated.Progress != (models.ChecklistProgress{Done: 2, Total: 3}) {
+		t.Errorf("Expected toggling to count the item as done, got %+v", updated.Progress)
+	}
+
+	for len(updated.Checklist) < MaxChecklistItems {
+		if updated, err = add("Item", false); err != nil {
+			t.Fatalf("AddChecklistItem failed below the limit: %v", err)
+		}
+	}
+	if _, err := add("One too many", false); !errors.Is(err, apperr.ErrInvalid) {
+		t.Errorf("Expected the checklist limit to be enforced, got %v", err)
 	}
 }
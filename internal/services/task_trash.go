@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"log"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+	"time"
+)
+
+func (s *TaskService) GetTrash(ctx context.Context, userID string) ([]models.Task, error) {
+	tasks, err := s.store.GetTrash(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range tasks {
+		tasks[i] = publicTask(tasks[i])
+	}
+	return tasks, nil
+}
+
+func (s *TaskService) RestoreTask(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	task, err := s.store.Restore(ctx, userID, realID)
+	if err != nil {
+		return models.Task{}, storeError(err)
+	}
+	return publicTask(task), nil
+}
+
+// PurgeTask permanently deletes a task that is already in the trash, along
+// with the blobs of its attachments.
+func (s *TaskService) PurgeTask(ctx context.Context, userID string, taskID uint64) error {
+	realID := my_utils.DeobfuscateNumbers(taskID)
+	if err := s.store.Purge(ctx, userID, realID); err != nil {
+		return storeError(err)
+	}
+	s.collectBlobs(ctx)
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes tasks that have been in the trash
+// for longer than retention.
+func (s *TaskService) PurgeExpiredTrash(ctx context.Context, retention time.Duration) (int, error) {
+	purged, err := s.store.PurgeTrash(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return purged, storeError(err)
+	}
+	s.collectBlobs(ctx)
+	return purged, nil
+}
+
+// RunTrashPurger calls PurgeExpiredTrash every interval until ctx is done.
+func (s *TaskService) RunTrashPurger(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredTrash(ctx, retention)
+			if err != nil {
+				log.Printf("Trash purge failed: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d tasks from trash", purged)
+			}
+		}
+	}
+}
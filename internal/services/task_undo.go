@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"time"
+)
+
+const DefaultUndoWindow = 30 * time.Second
+
+var (
+	ErrUndoTokenInvalid = apperr.New(apperr.ErrNotFound, apperr.CodeUndoTokenInvalid, "Undo token invalid", "the undo token is unknown, expired or already used")
+	ErrUndoConflict     = apperr.New(apperr.ErrConflict, apperr.CodeUndoConflict, "Undo conflict", "a task was changed after the operation being undone")
+)
+
+// issueUndo stores steps under a new token and returns it. The change has
+// already been applied at this point, so failing to store the token only
+// means the change cannot be undone and is not reported to the caller.
+func (s *TaskService) issueUndo(ctx context.Context, userID string, steps ...models.UndoStep) string {
+	if s.UndoWindow <= 0 || len(steps) == 0 {
+		return ""
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("Failed to generate undo token: %v", err)
+		return ""
+	}
+	token := hex.EncodeToString(b)
+
+	entry := models.UndoEntry{
+		UserID:    userID,
+		Steps:     steps,
+		ExpiresAt: time.Now().Add(s.UndoWindow),
+	}
+	if err := s.store.SaveUndo(context.WithoutCancel(ctx), token, entry); err != nil {
+		log.Printf("Failed to save undo token: %v", err)
+		return ""
+	}
+	return token
+}
+
+// Undo reverts the operation that returned token. Tokens are single use and
+// fail once any task they cover has been changed again.
+func (s *TaskService) Undo(ctx context.Context, userID, token string) ([]models.Task, error) {
+	tasks, err := s.store.Undo(ctx, userID, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			return nil, ErrUndoTokenInvalid
+		case errors.Is(err, apperr.ErrConflict):
+			return nil, ErrUndoConflict
+		default:
+			return nil, storeError(err)
+		}
+	}
+	s.collectBlobs(ctx)
+
+	for i := range tasks {
+		tasks[i] = publicTask(tasks[i])
+	}
+	return tasks, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+	"time"
+)
+
+func publicTemplate(template models.Template) models.Template {
+	template.ID = my_utils.ObfuscateNumbers(template.ID)
+	template.LabelIDs = obfuscateIDs(template.LabelIDs)
+	subtasks := make([]models.TemplateTask, len(template.Subtasks))
+	for i, sub := range template.Subtasks {
+		sub.LabelIDs = obfuscateIDs(sub.LabelIDs)
+		subtasks[i] = sub
+	}
+	template.Subtasks = subtasks
+	return template
+}
+
+func templateFromRequest(req dto.TemplateRequest) models.Template {
+	template := models.Template{
+		Name: req.Name,
+		TemplateTask: models.TemplateTask{
+			Title:       req.Title,
+			Description: req.Description,
+			LabelIDs:    deobfuscateIDs(req.Labels),
+			Checklist:   req.Checklist,
+			DueOffset:   req.DueOffset,
+			DueTime:     req.DueTime,
+		},
+	}
+	for _, sub := range req.Subtasks {
+		template.Subtasks = append(template.Subtasks, models.TemplateTask{
+			Title:       sub.Title,
+			Description: sub.Description,
+			LabelIDs:    deobfuscateIDs(sub.Labels),
+			Checklist:   sub.Checklist,
+			DueOffset:   sub.DueOffset,
+			DueTime:     sub.DueTime,
+		})
+	}
+	return template
+}
+
+// templateTask builds the task a template describes for an instantiation
+// anchored at the start of anchor's day.
+func templateTask(tt models.TemplateTask, anchor time.Time, projectID uint64) models.Task {
+	task := models.Task{
+		Title:       tt.Title,
+		Description: tt.Description,
+		ProjectID:   projectID,
+		LabelIDs:    tt.LabelIDs,
+	}
+	for _, text := range tt.Checklist {
+		task.Checklist = append(task.Checklist, models.ChecklistItem{Text: text})
+	}
+	numberChecklist(&task)
+	if tt.DueOffset != nil {
+		day := anchor.AddDate(0, 0, *tt.DueOffset)
+		hour, minute := 23, 59
+		if at, err := time.Parse("15:04", tt.DueTime); err == nil {
+			hour, minute = at.Hour(), at.Minute()
+		}
+		due := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, anchor.Location())
+		task.DueAt = &due
+	}
+	return task
+}
+
+func (s *TaskService) CreateTemplate(ctx context.Context, userID string, req dto.TemplateRequest) (models.Template, error) {
+	if err := req.Validate(); err != nil {
+		return models.Template{}, err
+	}
+
+	created, err := s.store.CreateTemplate(ctx, userID, templateFromRequest(req))
+	if err != nil {
+		return models.Template{}, storeError(err)
+	}
+	return publicTemplate(created), nil
+}
+
+func (s *TaskService) GetTemplates(ctx context.Context, userID string) ([]models.Template, error) {
+	templates, err := s.store.GetTemplates(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range templates {
+		templates[i] = publicTemplate(templates[i])
+	}
+	return templates, nil
+}
+
+func (s *TaskService) GetTemplate(ctx context.Context, userID string, templateID uint64) (models.Template, error) {
+	template, err := s.store.GetTemplate(ctx, userID, my_utils.DeobfuscateNumbers(templateID))
+	if err != nil {
+		return models.Template{}, storeError(err)
+	}
+	return publicTemplate(template), nil
+}
+
+func (s *TaskService) UpdateTemplate(ctx context.Context, userID string, templateID uint64, req dto.TemplateRequest) (models.Template, error) {
+	if err := req.Validate(); err != nil {
+		return models.Template{}, err
+	}
+
+	updated, err := s.store.UpdateTemplate(ctx, userID, my_utils.DeobfuscateNumbers(templateID), templateFromRequest(req))
+	if err != nil {
+		return models.Template{}, storeError(err)
+	}
+	return publicTemplate(updated), nil
+}
+
+func (s *TaskService) DeleteTemplate(ctx context.Context, userID string, templateID uint64) error {
+	if err := s.store.DeleteTemplate(ctx, userID, my_utils.DeobfuscateNumbers(templateID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// InstantiateTemplate creates the tasks of a template in a single atomic
+// batch: the template's task first, followed by its subtasks. Due dates are
+// counted from the anchor date in the request's time zone. The returned
+// token undoes the whole instantiation.
+func (s *TaskService) InstantiateTemplate(ctx context.Context, userID string, templateID uint64, req dto.InstantiateTemplateRequest) ([]models.Task, string, error) {
+	if err := req.Validate(); err != nil {
+		return nil, "", err
+	}
+	loc, err := location(req.TimeZone)
+	if err != nil {
+		return nil, "", err
+	}
+	anchor, err := time.ParseInLocation(models.DateLayout, req.Anchor, loc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	template, err := s.store.GetTemplate(ctx, userID, my_utils.DeobfuscateNumbers(templateID))
+	if err != nil {
+		return nil, "", storeError(err)
+	}
+
+	projectID := deobfuscateID(req.Project)
+	ops := []models.BatchOp{{Kind: models.BatchCreate, Task: templateTask(template.TemplateTask, anchor, projectID)}}
+	for _, sub := range template.Subtasks {
+		ops = append(ops, models.BatchOp{Kind: models.BatchCreate, Task: templateTask(sub, anchor, projectID), ParentOp: 1})
+	}
+
+	stored, committed, err := s.store.ApplyBatch(ctx, userID, ops, true)
+	if err != nil {
+		return nil, "", storeError(err)
+	}
+	if !committed {
+		for _, r := range stored {
+			if r.Err != nil {
+				return nil, "", storeError(r.Err)
+			}
+		}
+	}
+
+	tasks := make([]models.Task, len(stored))
+	steps := make([]models.UndoStep, len(stored))
+	for i, r := range stored {
+		steps[i] = models.UndoStep{TaskID: r.Task.ID, Version: r.Task.Version}
+		tasks[i] = publicTask(r.Task)
+	}
+	return tasks, s.issueUndo(ctx, userID, steps...), nil
+}
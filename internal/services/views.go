@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"task-backend/internal/dto"
+	"task-backend/internal/models"
+	my_utils "task-backend/utils"
+)
+
+func publicView(view models.View) models.View {
+	view.ID = my_utils.ObfuscateNumbers(view.ID)
+	view.ProjectID = obfuscateID(view.ProjectID)
+	view.LabelIDs = obfuscateIDs(view.LabelIDs)
+	return view
+}
+
+func viewFromRequest(req dto.ViewRequest) models.View {
+	return models.View{
+		Name:      req.Name,
+		ProjectID: deobfuscateID(req.Project),
+		LabelIDs:  deobfuscateIDs(req.Labels),
+		AnyLabel:  req.LabelMatch == "any",
+		Status:    models.TaskStatus(req.Status),
+		Due:       models.DueWindow(req.Due),
+		Query:     req.Query,
+		TimeZone:  req.TimeZone,
+	}
+}
+
+// checkViewQuery rejects views whose query doesn't compile, naming labels or
+// projects that don't exist for example.
+func (s *TaskService) checkViewQuery(ctx context.Context, userID string, req dto.ViewRequest) error {
+	_, err := s.compileQuery(ctx, userID, models.TaskFilter{Query: req.Query, TimeZone: req.TimeZone})
+	return err
+}
+
+func (s *TaskService) CreateView(ctx context.Context, userID string, req dto.ViewRequest) (models.View, error) {
+	if err := req.Validate(); err != nil {
+		return models.View{}, err
+	}
+	if err := s.checkViewQuery(ctx, userID, req); err != nil {
+		return models.View{}, err
+	}
+
+	created, err := s.store.CreateView(ctx, userID, viewFromRequest(req))
+	if err != nil {
+		return models.View{}, storeError(err)
+	}
+	return publicView(created), nil
+}
+
+func (s *TaskService) GetViews(ctx context.Context, userID string) ([]models.View, error) {
+	views, err := s.store.GetViews(ctx, userID)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	for i := range views {
+		views[i] = publicView(views[i])
+	}
+	return views, nil
+}
+
+func (s *TaskService) GetView(ctx context.Context, userID string, viewID uint64) (models.View, error) {
+	view, err := s.store.GetView(ctx, userID, my_utils.DeobfuscateNumbers(viewID))
+	if err != nil {
+		return models.View{}, storeError(err)
+	}
+	return publicView(view), nil
+}
+
+func (s *TaskService) UpdateView(ctx context.Context, userID string, viewID uint64, req dto.ViewRequest) (models.View, error) {
+	if err := req.Validate(); err != nil {
+		return models.View{}, err
+	}
+	if err := s.checkViewQuery(ctx, userID, req); err != nil {
+		return models.View{}, err
+	}
+
+	updated, err := s.store.UpdateView(ctx, userID, my_utils.DeobfuscateNumbers(viewID), viewFromRequest(req))
+	if err != nil {
+		return models.View{}, storeError(err)
+	}
+	return publicView(updated), nil
+}
+
+func (s *TaskService) DeleteView(ctx context.Context, userID string, viewID uint64) error {
+	if err := s.store.DeleteView(ctx, userID, my_utils.DeobfuscateNumbers(viewID)); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// GetViewTasks lists the tasks a saved view currently matches, the same way
+// ListTasks does for the view's filter.
+func (s *TaskService) GetViewTasks(ctx context.Context, userID string, viewID uint64) ([]models.Task, error) {
+	view, err := s.GetView(ctx, userID, viewID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ListTasks(ctx, userID, view.Filter())
+}
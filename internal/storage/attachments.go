@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var errAttachmentNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeAttachmentNotFound, "Attachment not found", "no attachment with this id exists on the task")
+
+func (s *TaskStore) AddAttachment(ctx context.Context, userID string, taskID uint64, attachment models.Attachment) (models.Attachment, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Attachment{}, err
+	}
+	defer s.mu.Unlock()
+
+	if !s.liveTask(userID, taskID) {
+		return models.Attachment{}, errTaskNotFound(taskID)
+	}
+
+	s.attachmentCounter++
+	attachment.ID = s.attachmentCounter
+	attachment.TaskID = taskID
+	attachment.CreatedAt = s.now().UTC()
+	s.attachments[taskID] = append(s.attachments[taskID], attachment)
+	return attachment, nil
+}
+
+func (s *TaskStore) GetAttachments(ctx context.Context, userID string, taskID uint64) ([]models.Attachment, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	if !s.liveTask(userID, taskID) {
+		return nil, errTaskNotFound(taskID)
+	}
+	return append([]models.Attachment{}, s.attachments[taskID]...), nil
+}
+
+func (s *TaskStore) GetAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) (models.Attachment, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Attachment{}, err
+	}
+	defer s.mu.RUnlock()
+
+	i, err := s.attachment(userID, taskID, attachmentID)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	return s.attachments[taskID][i], nil
+}
+
+// DeleteAttachment removes an attachment and queues its blob for deletion.
+func (s *TaskStore) DeleteAttachment(ctx context.Context, userID string, taskID, attachmentID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	i, err := s.attachment(userID, taskID, attachmentID)
+	if err != nil {
+		return err
+	}
+	attachments := s.attachments[taskID]
+	s.orphanedBlobs = append(s.orphanedBlobs, attachments[i].BlobKey)
+	s.attachments[taskID] = append(attachments[:i:i], attachments[i+1:]...)
+	return nil
+}
+
+// TakeOrphanedBlobs returns the blob keys of attachments that have been
+// deleted, directly or with their task, since the last call.
+func (s *TaskStore) TakeOrphanedBlobs(ctx context.Context) ([]string, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	keys := s.orphanedBlobs
+	s.orphanedBlobs = nil
+	return keys, nil
+}
+
+// ReturnOrphanedBlobs hands back blob keys taken with TakeOrphanedBlobs
+// whose blobs could not be deleted, so that the next call returns them
+// again.
+func (s *TaskStore) ReturnOrphanedBlobs(ctx context.Context, keys []string) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	s.orphanedBlobs = append(s.orphanedBlobs, keys...)
+	return nil
+}
+
+// attachment returns the index of an attachment of a live task. Callers must
+// hold s.mu.
+func (s *TaskStore) attachment(userID string, taskID, attachmentID uint64) (int, error) {
+	if !s.liveTask(userID, taskID) {
+		return 0, errTaskNotFound(taskID)
+	}
+	for i, attachment := range s.attachments[taskID] {
+		if attachment.ID == attachmentID {
+			return i, nil
+		}
+	}
+	return 0, errAttachmentNotFound
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"task-backend/internal/apperr"
+)
+
+type blobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func testBlobStore(t *testing.T, store blobStore) {
+	ctx := context.Background()
+	content := "hello, attachments"
+
+	if err := store.Put(ctx, "attachments/a", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put returned %v", err)
+	}
+
+	blob, err := store.Open(ctx, "attachments/a")
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	if size, _ := blob.Seek(0, io.SeekEnd); size != int64(len(content)) {
+		t.Errorf("Expected size %d, got %d", len(content), size)
+	}
+	_, _ = blob.Seek(7, io.SeekStart)
+	if got, err := io.ReadAll(blob); err != nil || string(got) != "attachments" {
+		t.Errorf("Expected ranged read, got %q, %v", got, err)
+	}
+	_ = blob.Close()
+
+	if _, err := store.Open(ctx, "attachments/missing"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected missing blob to be not found, got %v", err)
+	}
+	if err := store.Put(ctx, "../escape", strings.NewReader(content), int64(len(content))); err == nil {
+		t.Error("Expected keys outside the store to be rejected")
+	}
+
+	if err := store.Delete(ctx, "attachments/a"); err != nil {
+		t.Fatalf("Delete returned %v", err)
+	}
+	if _, err := store.Open(ctx, "attachments/a"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected deleted blob to be gone, got %v", err)
+	}
+	if err := store.Delete(ctx, "attachments/a"); err != nil {
+		t.Errorf("Expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestFileBlobStore(t *testing.T) {
+	store, err := NewFileBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+}
+
+// newS3StandIn serves the subset of the S3 API the blob store uses, with
+// path-style buckets and range support.
+func newS3StandIn(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=test/") || !strings.Contains(auth, "/eu-test/s3/aws4_request") || r.Header.Get("X-Amz-Date") == "" {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+		if !ok {
+			http.Error(w, "NoSuchBucket", http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[key] = data
+		case http.MethodGet, http.MethodHead:
+			data, exists := objects[key]
+			if !exists {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestS3BlobStore(t *testing.T) {
+	server := newS3StandIn(t)
+	store, err := NewS3BlobStore(S3Config{Endpoint: server.URL, Region: "eu-test", Bucket: "bucket", AccessKey: "test", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+
+	if _, err := NewS3BlobStore(S3Config{Endpoint: server.URL}); err == nil {
+		t.Error("Expected a missing bucket to be rejected")
+	}
+}
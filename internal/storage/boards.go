@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var (
+	errBoardNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeBoardNotFound, "Board not found", "no board with this id exists")
+	errBoardExists   = apperr.New(apperr.ErrConflict, apperr.CodeBoardExists, "Board exists", "a board with this name already exists")
+)
+
+func errWIPLimit(limit int) error {
+	return apperr.New(apperr.ErrConflict, apperr.CodeWIPLimitExceeded, "WIP limit exceeded",
+		fmt.Sprintf("the column already holds its limit of %d tasks", limit))
+}
+
+// checkBoard verifies the project and state field a board refers to and
+// that its column states are options of that field. Callers must hold s.mu.
+func (s *TaskStore) checkBoard(userID string, board models.Board) error {
+	if board.ProjectID != 0 {
+		if _, exists := s.projects[userID][board.ProjectID]; !exists {
+			return errUnknownProject
+		}
+	}
+
+	var field models.CustomField
+	if board.StateField != 0 {
+		var exists bool
+		if field, exists = s.customFields[userID][board.StateField]; !exists {
+			return errUnknownField
+		}
+		if field.Type != models.FieldSelect {
+			return apperr.Validation(map[string]string{"state_field": "State field must be a select field"})
+		}
+	}
+	for i, c := range board.Columns {
+		if c.State == "" {
+			continue
+		}
+		key := fmt.Sprintf("columns[%d].state", i)
+		if board.StateField == 0 {
+			return apperr.Validation(map[string]string{key: "State needs the board to have a state field"})
+		}
+		if _, err := field.Normalize(c.State); err != nil {
+			return apperr.Validation(map[string]string{key: err.Error()})
+		}
+	}
+	return nil
+}
+
+// numberColumns gives new columns of a board IDs; columns that keep the ID
+// of one of the current columns keep that ID.
+func numberColumns(columns, current []models.BoardColumn) []models.BoardColumn {
+	var next uint64
+	kept := make(map[uint64]bool, len(current))
+	for _, c := range current {
+		kept[c.ID] = true
+		next = max(next, c.ID)
+	}
+
+	numbered := make([]models.BoardColumn, len(columns))
+	for i, c := range columns {
+		if !kept[c.ID] {
+			next++
+			c.ID = next
+		}
+		delete(kept, c.ID)
+		numbered[i] = c
+	}
+	return numbered
+}
+
+// boardNameTaken reports whether another board of the user already has
+// name, ignoring case. Callers must hold s.mu.
+func (s *TaskStore) boardNameTaken(userID string, name string, exceptID uint64) bool {
+	for id, b := range s.boards[userID] {
+		if id != exceptID && strings.EqualFold(b.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// moveBoards points the boards of a deleted project at the Inbox, which
+// received the project's tasks. Callers must hold s.mu for writing.
+func (s *TaskStore) moveBoards(userID string, projectID, inbox uint64) {
+	for id, b := range s.boards[userID] {
+		if b.ProjectID == projectID {
+			b.ProjectID = inbox
+			s.boards[userID][id] = b
+		}
+	}
+}
+
+func (s *TaskStore) CreateBoard(ctx context.Context, userID string, board models.Board) (models.Board, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Board{}, err
+	}
+	defer s.mu.Unlock()
+
+	if s.boardNameTaken(userID, board.Name, 0) {
+		return models.Board{}, errBoardExists
+	}
+	if err := s.checkBoard(userID, board); err != nil {
+		return models.Board{}, err
+	}
+
+	s.boardCounter++
+	board.ID = s.boardCounter
+	board.UserID = userID
+	board.Columns = numberColumns(board.Columns, nil)
+	if _, exists := s.boards[userID]; !exists {
+		s.boards[userID] = make(map[uint64]models.Board)
+	}
+	s.boards[userID][board.ID] = board
+	return board, nil
+}
+
+// GetBoards returns the user's boards sorted by name.
+func (s *TaskStore) GetBoards(ctx context.Context, userID string) ([]models.Board, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	boards := make([]models.Board, 0, len(s.boards[userID]))
+	for _, b := range s.boards[userID] {
+		boards = append(boards, b)
+	}
+	sort.Slice(boards, func(i, j int) bool { return boards[i].Name < boards[j].Name })
+	return boards, nil
+}
+
+func (s *TaskStore) GetBoard(ctx context.Context, userID string, boardID uint64) (models.Board, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Board{}, err
+	}
+	defer s.mu.RUnlock()
+
+	board, exists := s.boards[userID][boardID]
+	if !exists {
+		return models.Board{}, errBoardNotFound
+	}
+	return board, nil
+}
+
+// UpdateBoard replaces a board. Columns that carry the ID of a current
+// column keep it, so clients can rename and reorder columns.
+func (s *TaskStore) UpdateBoard(ctx context.Context, userID string, boardID uint64, updated models.Board) (models.Board, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Board{}, err
+	}
+	defer s.mu.Unlock()
+
+	current, exists := s.boards[userID][boardID]
+	if !exists {
+		return models.Board{}, errBoardNotFound
+	}
+	if s.boardNameTaken(userID, updated.Name, boardID) {
+		return models.Board{}, errBoardExists
+	}
+	if err := s.checkBoard(userID, updated); err != nil {
+		return models.Board{}, err
+	}
+
+	updated.ID = boardID
+	updated.UserID = userID
+	updated.Columns = numberColumns(updated.Columns, current.Columns)
+	s.boards[userID][boardID] = updated
+	return updated, nil
+}
+
+func (s *TaskStore) DeleteBoard(ctx context.Context, userID string, boardID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.boards[userID][boardID]; !exists {
+		return errBoardNotFound
+	}
+	delete(s.boards[userID], boardID)
+	return nil
+}
+
+// UpdateWithinLimit updates a task like Update, unless that would add it to
+// a set of tasks that already holds limit tasks; counted tells which live
+// tasks are in the set. The count and the update happen in one transaction,
+// so concurrent updates cannot both take the last place. Tasks that are
+// already counted are updated regardless.
+func (s *TaskStore) UpdateWithinLimit(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64, counted func(models.Task) bool, limit int) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
+	defer s.mu.Unlock()
+
+	tasksMap := s.userTasks[userID]
+	if current, exists := tasksMap[taskID]; exists && !current.Trashed() && !counted(current) {
+		n := 0
+		for _, task := range tasksMap {
+			if task.ID != taskID && !task.Trashed() && counted(task) {
+				n++
+			}
+		}
+		if n >= limit {
+			return models.Task{}, errWIPLimit(limit)
+		}
+	}
+	return s.update(userID, taskID, updated, expectedVersion)
+}
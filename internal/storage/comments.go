@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var (
+	errCommentNotFound  = apperr.New(apperr.ErrNotFound, apperr.CodeCommentNotFound, "Comment not found", "no comment with this id exists on the task")
+	errCommentForbidden = apperr.New(apperr.ErrForbidden, apperr.CodeCommentForbidden, "Forbidden", "only the author can change a comment")
+)
+
+// forgetTask drops the data kept alongside a task once the task itself is
+// gone for good and queues the blobs of its attachments for deletion.
+// Callers must hold s.mu for writing.
+func (s *TaskStore) forgetTask(taskID uint64) {
+	delete(s.revisions, taskID)
+	delete(s.comments, taskID)
+	for _, attachment := range s.attachments[taskID] {
+		s.orphanedBlobs = append(s.orphanedBlobs, attachment.BlobKey)
+	}
+	delete(s.attachments, taskID)
+}
+
+// liveTask reports whether the user has a task with taskID that is not in
+// the trash. Callers must hold s.mu.
+func (s *TaskStore) liveTask(userID string, taskID uint64) bool {
+	task, exists := s.userTasks[userID][taskID]
+	return exists && !task.Trashed()
+}
+
+func (s *TaskStore) AddComment(ctx context.Context, userID string, taskID uint64, comment models.Comment) (models.Comment, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Comment{}, err
+	}
+	defer s.mu.Unlock()
+
+	if !s.liveTask(userID, taskID) {
+		return models.Comment{}, errTaskNotFound(taskID)
+	}
+
+	s.commentCounter++
+	comment.ID = s.commentCounter
+	comment.TaskID = taskID
+	comment.AuthorID = userID
+	comment.CreatedAt = s.now().UTC()
+	comment.EditedAt = nil
+	s.comments[taskID] = append(s.comments[taskID], comment)
+	return comment, nil
+}
+
+// GetComments returns up to limit comments of a task, oldest first,
+// starting after the comment with id after (0 for the first page). The bool
+// reports whether more comments follow.
+func (s *TaskStore) GetComments(ctx context.Context, userID string, taskID, after uint64, limit int) ([]models.Comment, bool, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, false, err
+	}
+	defer s.mu.RUnlock()
+
+	if !s.liveTask(userID, taskID) {
+		return nil, false, errTaskNotFound(taskID)
+	}
+
+	page := make([]models.Comment, 0, limit)
+	for _, comment := range s.comments[taskID] {
+		if comment.ID <= after {
+			continue
+		}
+		if len(page) == limit {
+			return page, true, nil
+		}
+		page = append(page, comment)
+	}
+	return page, false, nil
+}
+
+// comment returns the index of a comment the user may change. Callers must
+// hold s.mu.
+func (s *TaskStore) comment(userID string, taskID, commentID uint64) (int, error) {
+	if !s.liveTask(userID, taskID) {
+		return 0, errTaskNotFound(taskID)
+	}
+	for i, comment := range s.comments[taskID] {
+		if comment.ID != commentID {
+			continue
+		}
+		if comment.AuthorID != userID {
+			return 0, errCommentForbidden
+		}
+		return i, nil
+	}
+	return 0, errCommentNotFound
+}
+
+func (s *TaskStore) UpdateComment(ctx context.Context, userID string, taskID, commentID uint64, body string) (models.Comment, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Comment{}, err
+	}
+	defer s.mu.Unlock()
+
+	i, err := s.comment(userID, taskID, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	comments := s.comments[taskID]
+	editedAt := s.now().UTC()
+	comments[i].Body = body
+	comments[i].EditedAt = &editedAt
+	return comments[i], nil
+}
+
+func (s *TaskStore) DeleteComment(ctx context.Context, userID string, taskID, commentID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	i, err := s.comment(userID, taskID, commentID)
+	if err != nil {
+		return err
+	}
+	comments := s.comments[taskID]
+	s.comments[taskID] = append(comments[:i:i], comments[i+1:]...)
+	return nil
+}
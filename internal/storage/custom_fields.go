@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var (
+	errFieldNotFound   = apperr.New(apperr.ErrNotFound, apperr.CodeFieldNotFound, "Custom field not found", "no custom field with this id exists")
+	errFieldExists     = apperr.New(apperr.ErrConflict, apperr.CodeFieldExists, "Custom field exists", "a custom field with this name already exists")
+	errFieldTypeChange = apperr.New(apperr.ErrConflict, apperr.CodeFieldTypeChange, "Custom field type change", "the type of a custom field cannot be changed")
+	errUnknownField    = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownField, "Unknown custom field", "one or more custom fields do not exist")
+)
+
+// fieldValues checks values against the user's custom fields and returns
+// them in their stored form. Null values clear a field and are dropped.
+// Callers must hold s.mu.
+func (s *TaskStore) fieldValues(userID string, values map[uint64]any) (map[uint64]any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	stored := make(map[uint64]any, len(values))
+	for id, v := range values {
+		field, exists := s.customFields[userID][id]
+		if !exists {
+			return nil, errUnknownField
+		}
+		if v == nil {
+			continue
+		}
+		value, err := field.Normalize(v)
+		if err != nil {
+			return nil, apperr.Validation(map[string]string{"custom_fields": err.Error()})
+		}
+		stored[id] = value
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	return stored, nil
+}
+
+// existingFieldValues drops values of custom fields that have been deleted
+// or no longer accept them. Callers must hold s.mu.
+func (s *TaskStore) existingFieldValues(userID string, values map[uint64]any) map[uint64]any {
+	var kept map[uint64]any
+	for id, v := range values {
+		field, exists := s.customFields[userID][id]
+		if !exists {
+			continue
+		}
+		if _, err := field.Normalize(v); err != nil {
+			continue
+		}
+		if kept == nil {
+			kept = make(map[uint64]any, len(values))
+		}
+		kept[id] = v
+	}
+	return kept
+}
+
+// fieldNameTaken reports whether another custom field of the user already
+// has name, ignoring case. Callers must hold s.mu.
+func (s *TaskStore) fieldNameTaken(userID string, name string, exceptID uint64) bool {
+	for id, f := range s.customFields[userID] {
+		if id != exceptID && strings.EqualFold(f.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TaskStore) CreateCustomField(ctx context.Context, userID string, field models.CustomField) (models.CustomField, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.CustomField{}, err
+	}
+	defer s.mu.Unlock()
+
+	if s.fieldNameTaken(userID, field.Name, 0) {
+		return models.CustomField{}, errFieldExists
+	}
+
+	s.customFieldCounter++
+	field.ID = s.customFieldCounter
+	field.UserID = userID
+	if _, exists := s.customFields[userID]; !exists {
+		s.customFields[userID] = make(map[uint64]models.CustomField)
+	}
+	s.customFields[userID][field.ID] = field
+	return field, nil
+}
+
+// GetCustomFields returns the user's custom fields sorted by name.
+func (s *TaskStore) GetCustomFields(ctx context.Context, userID string) ([]models.CustomField, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	fields := make([]models.CustomField, 0, len(s.customFields[userID]))
+	for _, f := range s.customFields[userID] {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, nil
+}
+
+func (s *TaskStore) GetCustomField(ctx context.Context, userID string, fieldID uint64) (models.CustomField, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.CustomField{}, err
+	}
+	defer s.mu.RUnlock()
+
+	field, exists := s.customFields[userID][fieldID]
+	if !exists {
+		return models.CustomField{}, errFieldNotFound
+	}
+	return field, nil
+}
+
+// UpdateCustomField renames a custom field or changes its options. Tasks
+// holding a select option that was removed lose the value and move to a new
+// version.
+func (s *TaskStore) UpdateCustomField(ctx context.Context, userID string, fieldID uint64, updated models.CustomField) (models.CustomField, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.CustomField{}, err
+	}
+	defer s.mu.Unlock()
+
+	current, exists := s.customFields[userID][fieldID]
+	if !exists {
+		return models.CustomField{}, errFieldNotFound
+	}
+	if updated.Type != current.Type {
+		return models.CustomField{}, errFieldTypeChange
+	}
+	if s.fieldNameTaken(userID, updated.Name, fieldID) {
+		return models.CustomField{}, errFieldExists
+	}
+
+	updated.ID = fieldID
+	updated.UserID = userID
+	s.customFields[userID][fieldID] = updated
+	s.clearFieldValues(userID, fieldID, func(v any) bool {
+		_, err := updated.Normalize(v)
+		return err != nil
+	})
+	return updated, nil
+}
+
+// DeleteCustomField deletes a custom field and removes its values from every
+// task of the user, trashed ones included, in the same transaction.
+func (s *TaskStore) DeleteCustomField(ctx context.Context, userID string, fieldID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.customFields[userID][fieldID]; !exists {
+		return errFieldNotFound
+	}
+	delete(s.customFields[userID], fieldID)
+	s.clearFieldValues(userID, fieldID, func(any) bool { return true })
+	return nil
+}
+
+// clearFieldValues removes the values of a field for which clear returns
+// true and bumps the versions of the tasks that held them. Callers must hold
+// s.mu for writing.
+func (s *TaskStore) clearFieldValues(userID string, fieldID uint64, clear func(any) bool) {
+	for id, task := range s.userTasks[userID] {
+		v, ok := task.CustomFields[fieldID]
+		if !ok || !clear(v) {
+			continue
+		}
+		values := make(map[uint64]any, len(task.CustomFields)-1)
+		for k, v := range task.CustomFields {
+			if k != fieldID {
+				values[k] = v
+			}
+		}
+		if len(values) == 0 {
+			values = nil
+		}
+		task.CustomFields = values
+		task.Version++
+		s.userTasks[userID][id] = task
+	}
+}
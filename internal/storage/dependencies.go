@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var (
+	errUnknownDependency = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownDependency, "Unknown dependency", "a task in depends_on does not exist")
+	errDependencyCycle   = &apperr.Error{
+		Kind:   apperr.ErrInvalid,
+		Code:   apperr.CodeDependencyCycle,
+		Title:  "Dependency cycle",
+		Detail: "a task cannot depend on itself or on a task that already depends on it",
+		Fields: map[string]string{"depends_on": "Depends_on would create a dependency cycle"},
+	}
+)
+
+// checkDependencies validates that taskID may depend on deps and returns
+// them deduplicated and sorted. taskID is 0 for tasks that don't exist yet.
+// New dependencies must be live tasks; ones already in current may have been
+// trashed since, and are dropped if they no longer exist.
+func checkDependencies(tasks map[uint64]models.Task, taskID uint64, deps, current []uint64) ([]uint64, error) {
+	if len(deps) == 0 {
+		return nil, nil
+	}
+	known := make(map[uint64]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+
+	seen := make(map[uint64]bool, len(deps))
+	out := make([]uint64, 0, len(deps))
+	for _, id := range deps {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if id == taskID {
+			return nil, errDependencyCycle
+		}
+		dep, exists := tasks[id]
+		if !exists && known[id] {
+			continue
+		}
+		if !exists || (dep.Trashed() && !known[id]) {
+			return nil, errUnknownDependency
+		}
+		if taskID != 0 && dependsOn(tasks, id, taskID) {
+			return nil, errDependencyCycle
+		}
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// dependsOn reports whether from depends on target, directly or through
+// other tasks.
+func dependsOn(tasks map[uint64]models.Task, from, target uint64) bool {
+	visited := map[uint64]bool{from: true}
+	stack := []uint64{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range tasks[id].DependsOn {
+			if dep == target {
+				return true
+			}
+			if !visited[dep] {
+				visited[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	return false
+}
+
+// validDependencies keeps the deps that still exist and don't close a
+// cycle, for writes that can't be rejected such as undo.
+func validDependencies(tasks map[uint64]models.Task, taskID uint64, deps []uint64) []uint64 {
+	var out []uint64
+	for _, id := range deps {
+		if _, exists := tasks[id]; exists && id != taskID && !dependsOn(tasks, id, taskID) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// dropDependencies removes purged tasks from the dependencies of the
+// remaining ones.
+func dropDependencies(tasks map[uint64]models.Task, purged map[uint64]bool) {
+	for id, task := range tasks {
+		var kept []uint64
+		for _, dep := range task.DependsOn {
+			if !purged[dep] {
+				kept = append(kept, dep)
+			}
+		}
+		if len(kept) != len(task.DependsOn) {
+			task.DependsOn = kept
+			task.Version++
+			tasks[id] = task
+		}
+	}
+}
+
+// annotate fills in the computed Blocked and BlockedBy fields of task: it is
+// blocked by every live dependency that is not completed yet.
+func annotate(tasks map[uint64]models.Task, task models.Task) models.Task {
+	task.BlockedBy = nil
+	for _, id := range task.DependsOn {
+		if dep, exists := tasks[id]; exists && isLive(dep) && !dep.Completed {
+			task.BlockedBy = append(task.BlockedBy, id)
+		}
+	}
+	task.Blocked = len(task.BlockedBy) > 0
+	return task
+}
+
+// blockers records the computed BlockedBy of every task that depends on
+// others, so that a write can tell whose blockers it changed.
+type blockers map[uint64]models.Task
+
+func snapshotBlockers(tasks map[uint64]models.Task) blockers {
+	b := make(blockers)
+	for id, task := range tasks {
+		if len(task.DependsOn) > 0 {
+			b[id] = annotate(tasks, task)
+		}
+	}
+	return b
+}
+
+// touch bumps the version of the tasks whose BlockedBy changed since the
+// snapshot was taken, as completing, trashing or restoring a blocker changes
+// how its dependents read and so must change their ETags. Tasks the write
+// saved itself already have a new version.
+func (b blockers) touch(tasks map[uint64]models.Task) {
+	for id, task := range tasks {
+		before, exists := b[id]
+		if !exists || before.Version != task.Version {
+			continue
+		}
+		if !slices.Equal(before.BlockedBy, annotate(tasks, task).BlockedBy) {
+			task.Version++
+			tasks[id] = task
+		}
+	}
+}
+
+// GetReady returns the live open tasks without open blockers in topological
+// order of the dependency graph, ties broken by id.
+func (s *TaskStore) GetReady(ctx context.Context, userID string) ([]models.Task, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	tasks := s.userTasks[userID]
+	open := make([]models.Task, 0)
+	for _, task := range tasks {
+		if isLive(task) && !task.Completed {
+			open = append(open, annotate(tasks, task))
+		}
+	}
+
+	ready := make([]models.Task, 0)
+	for _, task := range topoSort(open) {
+		if !task.Blocked {
+			ready = append(ready, task)
+		}
+	}
+	return ready, nil
+}
+
+// topoSort orders tasks so that every task comes after the tasks it depends
+// on, using Kahn's algorithm with the smallest id first among ready tasks.
+// Dependencies outside tasks are ignored.
+func topoSort(tasks []models.Task) []models.Task {
+	byID := make(map[uint64]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	indegree := make(map[uint64]int, len(tasks))
+	dependents := make(map[uint64][]uint64)
+	for _, task := range tasks {
+		for _, dep := range task.DependsOn {
+			if _, exists := byID[dep]; exists {
+				indegree[task.ID]++
+				dependents[dep] = append(dependents[dep], task.ID)
+			}
+		}
+	}
+
+	var queue []uint64
+	for _, task := range tasks {
+		if indegree[task.ID] == 0 {
+			queue = append(queue, task.ID)
+		}
+	}
+	out := make([]models.Task, 0, len(tasks))
+	for len(queue) > 0 {
+		sort.Slice(queue, func(i, j int) bool { return queue[i] < queue[j] })
+		id := queue[0]
+		queue = queue[1:]
+		out = append(out, byID[id])
+		for _, next := range dependents[id] {
+			if indegree[next]--; indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return out
+}
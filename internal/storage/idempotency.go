@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"task-backend/internal/models"
+)
+
+const idempotencySweepInterval = time.Minute
+
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]models.IdempotencyRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{
+		records: make(map[string]models.IdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+func idempotencyKey(userID, key string) string {
+	return userID + "\x00" + key
+}
+
+// Reserve claims key for a new request. When an unexpired record already
+// exists it is returned with reserved set to false and nothing changes.
+func (s *IdempotencyStore) Reserve(ctx context.Context, userID, key, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	k := idempotencyKey(userID, key)
+	if rec, exists := s.records[k]; exists && now.Before(rec.ExpiresAt) {
+		return rec, false, nil
+	}
+
+	rec := models.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(ttl),
+	}
+	s.records[k] = rec
+	return rec, true, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, userID, key string, rec models.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey(userID, key)
+	reserved, exists := s.records[k]
+	if !exists {
+		return nil
+	}
+
+	rec.Fingerprint = reserved.Fingerprint
+	rec.ExpiresAt = reserved.ExpiresAt
+	rec.Completed = true
+	s.records[k] = rec
+	return nil
+}
+
+// Release forgets a reservation whose request did not succeed, so that the
+// client may retry with the same key.
+func (s *IdempotencyStore) Release(ctx context.Context, userID, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, idempotencyKey(userID, key))
+	return nil
+}
+
+func (s *IdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < idempotencySweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for k, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"task-backend/internal/models"
+)
+
+func TestIdempotencyStore_ReserveAndComplete(t *testing.T) {
+	store := NewIdempotencyStore()
+	ctx := context.Background()
+
+	_, reserved, err := store.Reserve(ctx, "user1", "key", "fp", time.Hour)
+	if err != nil || !reserved {
+		t.Fatalf("Expected first reservation to succeed, got reserved=%v err=%v", reserved, err)
+	}
+
+	rec, reserved, _ := store.Reserve(ctx, "user1", "key", "fp", time.Hour)
+	if reserved || rec.Completed {
+		t.Errorf("Expected pending reservation to be returned, got reserved=%v rec=%+v", reserved, rec)
+	}
+
+	if _, reserved, _ := store.Reserve(ctx, "user2", "key", "fp", time.Hour); !reserved {
+		t.Error("Keys must be scoped per user")
+	}
+
+	_ = store.Complete(ctx, "user1", "key", models.IdempotencyRecord{Status: 201, Body: []byte("created")})
+	rec, reserved, _ = store.Reserve(ctx, "user1", "key", "other", time.Hour)
+	if reserved || !rec.Completed || rec.Fingerprint != "fp" || string(rec.Body) != "created" {
+		t.Errorf("Expected completed record with original fingerprint, got reserved=%v rec=%+v", reserved, rec)
+	}
+}
+
+func TestIdempotencyStore_ReleaseAndExpiry(t *testing.T) {
+	store := NewIdempotencyStore()
+	ctx := context.Background()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, _, _ = store.Reserve(ctx, "user1", "key", "fp", time.Hour)
+	_ = store.Release(ctx, "user1", "key")
+	if _, reserved, _ := store.Reserve(ctx, "user1", "key", "fp", time.Hour); !reserved {
+		t.Error("Expected released key to be reservable again")
+	}
+
+	_ = store.Complete(ctx, "user1", "key", models.IdempotencyRecord{Status: 201})
+	now = now.Add(2 * time.Hour)
+	if _, reserved, _ := store.Reserve(ctx, "user1", "key", "fp", time.Hour); !reserved {
+		t.Error("Expected expired key to be reservable again")
+	}
+}
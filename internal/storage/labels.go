@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/query"
+)
+
+// Label errors are reported as apperr errors so that the service does not
+// confuse them with the task errors of the same kind.
+var (
+	errLabelNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeLabelNotFound, "Label not found", "no label with this id exists")
+	errLabelExists   = apperr.New(apperr.ErrConflict, apperr.CodeLabelExists, "Label exists", "a label with this name already exists")
+	errUnknownLabel  = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownLabel, "Unknown label", "one or more labels do not exist")
+)
+
+// labelSet checks that every id names one of the user's labels and returns
+// them sorted and without duplicates. Callers must hold s.mu.
+func (s *TaskStore) labelSet(userID string, ids []uint64) ([]uint64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[uint64]bool, len(ids))
+	set := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if _, exists := s.labels[userID][id]; !exists {
+			return nil, errUnknownLabel
+		}
+		if !seen[id] {
+			seen[id] = true
+			set = append(set, id)
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	return set, nil
+}
+
+// existingLabels drops ids of labels that have been deleted. Callers must
+// hold s.mu.
+func (s *TaskStore) existingLabels(userID string, ids []uint64) []uint64 {
+	var kept []uint64
+	for _, id := range ids {
+		if _, exists := s.labels[userID][id]; exists {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// nameTaken reports whether another label of the user already has name,
+// ignoring case. Callers must hold s.mu.
+func (s *TaskStore) nameTaken(userID string, name string, exceptID uint64) bool {
+	for id, l := range s.labels[userID] {
+		if id != exceptID && strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TaskStore) CreateLabel(ctx context.Context, userID string, label models.Label) (models.Label, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Label{}, err
+	}
+	defer s.mu.Unlock()
+
+	if s.nameTaken(userID, label.Name, 0) {
+		return models.Label{}, errLabelExists
+	}
+
+	s.labelCounter++
+	label.ID = s.labelCounter
+	label.UserID = userID
+	if _, exists := s.labels[userID]; !exists {
+		s.labels[userID] = make(map[uint64]models.Label)
+	}
+	s.labels[userID][label.ID] = label
+	return label, nil
+}
+
+// CreateWithLabels creates a task like Create and adds the labels named in
+// names to it, matching them ignoring case and creating the missing ones.
+// Labels are only created if the task is.
+func (s *TaskStore) CreateWithLabels(ctx context.Context, userID string, task models.Task, names []string) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
+	defer s.mu.Unlock()
+
+	var added []uint64
+	for _, name := range names {
+		id, found := s.labelNamed(userID, name)
+		if !found {
+			s.labelCounter++
+			id = s.labelCounter
+			if _, exists := s.labels[userID]; !exists {
+				s.labels[userID] = make(map[uint64]models.Label)
+			}
+			s.labels[userID][id] = models.Label{ID: id, UserID: userID, Name: name}
+			added = append(added, id)
+		}
+		task.LabelIDs = append(task.LabelIDs, id)
+	}
+
+	created, err := s.create(userID, task)
+	if err != nil {
+		for _, id := range added {
+			delete(s.labels[userID], id)
+		}
+		return models.Task{}, err
+	}
+	return created, nil
+}
+
+// labelNamed returns the id of the user's label called name, ignoring case.
+// Callers must hold s.mu.
+func (s *TaskStore) labelNamed(userID, name string) (uint64, bool) {
+	for id, l := range s.labels[userID] {
+		if strings.EqualFold(l.Name, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// GetLabels returns the user's labels sorted by name.
+func (s *TaskStore) GetLabels(ctx context.Context, userID string) ([]models.Label, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	labels := make([]models.Label, 0, len(s.labels[userID]))
+	for _, l := range s.labels[userID] {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+func (s *TaskStore) GetLabel(ctx context.Context, userID string, labelID uint64) (models.Label, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Label{}, err
+	}
+	defer s.mu.RUnlock()
+
+	label, exists := s.labels[userID][labelID]
+	if !exists {
+		return models.Label{}, errLabelNotFound
+	}
+	return label, nil
+}
+
+func (s *TaskStore) UpdateLabel(ctx context.Context, userID string, labelID uint64, updated models.Label) (models.Label, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Label{}, err
+	}
+	defer s.mu.Unlock()
+
+	current, exists := s.labels[userID][labelID]
+	if !exists {
+		return models.Label{}, errLabelNotFound
+	}
+	if s.nameTaken(userID, updated.Name, labelID) {
+		return models.Label{}, errLabelExists
+	}
+	s.renameInViews(userID, query.FieldLabel, current.Name, updated.Name)
+
+	updated.ID = labelID
+	updated.UserID = userID
+	s.labels[userID][labelID] = updated
+	return updated, nil
+}
+
+// DeleteLabel deletes a label and removes it from every task, template and
+// view of the user, trashed tasks included, in the same transaction. Tasks
+// that lose the label move to a new version.
+func (s *TaskStore) DeleteLabel(ctx context.Context, userID string, labelID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.labels[userID][labelID]; !exists {
+		return errLabelNotFound
+	}
+	delete(s.labels[userID], labelID)
+	s.dropTemplateLabel(userID, labelID)
+	s.dropViewLabel(userID, labelID)
+
+	for id, task := range s.userTasks[userID] {
+		if !task.HasLabel(labelID) {
+			continue
+		}
+		kept := make([]uint64, 0, len(task.LabelIDs)-1)
+		for _, l := range task.LabelIDs {
+			if l != labelID {
+				kept = append(kept, l)
+			}
+		}
+		task.LabelIDs = kept
+		task.Version++
+		s.userTasks[userID][id] = task
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// rwMutex is a readers-writer lock whose waits can be abandoned. Waiting
+// writers keep new readers out so that a steady stream of reads cannot
+// starve them. The zero value is unlocked.
+type rwMutex struct {
+	mu      sync.Mutex
+	readers int
+	writer  bool
+	waiting int // writers waiting for the lock
+	// released is closed and replaced whenever the state changes in a way
+	// that may let a waiter in.
+	released chan struct{}
+}
+
+// Lock acquires the lock for writing, unless ctx is done first.
+func (l *rwMutex) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	l.waiting++
+	for l.writer || l.readers > 0 {
+		if err := l.wait(ctx); err != nil {
+			l.waiting--
+			l.wake()
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.waiting--
+	l.writer = true
+	l.mu.Unlock()
+	return nil
+}
+
+// RLock acquires the lock for reading, unless ctx is done first.
+func (l *rwMutex) RLock(ctx context.Context) error {
+	l.mu.Lock()
+	for l.writer || l.waiting > 0 {
+		if err := l.wait(ctx); err != nil {
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.readers++
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *rwMutex) Unlock() {
+	l.mu.Lock()
+	l.writer = false
+	l.wake()
+	l.mu.Unlock()
+}
+
+func (l *rwMutex) RUnlock() {
+	l.mu.Lock()
+	l.readers--
+	if l.readers == 0 {
+		l.wake()
+	}
+	l.mu.Unlock()
+}
+
+// wait releases l.mu until the state changes or ctx is done, and holds it
+// again when it returns.
+func (l *rwMutex) wait(ctx context.Context) error {
+	if l.released == nil {
+		l.released = make(chan struct{})
+	}
+	released := l.released
+	l.mu.Unlock()
+	select {
+	case <-released:
+		l.mu.Lock()
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		return ctx.Err()
+	}
+}
+
+// wake lets all waiters check the state again. Callers must hold l.mu.
+func (l *rwMutex) wake() {
+	if l.released != nil {
+		close(l.released)
+		l.released = nil
+	}
+}
@@ -1,98 +1,601 @@
 package storage
 
 import (
-	"sync"
+	"context"
+	"fmt"
+	"time"
 
+	"task-backend/internal/apperr"
 	"task-backend/internal/models"
 )
 
 type TaskStore struct {
-	mu        sync.RWMutex
+	// CompletionPolicy decides how completing a task with open subtasks is
+	// handled; the zero value blocks it.
+	CompletionPolicy models.CompletionPolicy
+
+	mu        rwMutex
 	userTasks map[string]map[uint64]models.Task
 	counter   uint64
+	now       func() time.Time
+
+	revisions     map[uint64][]models.Revision
+	revisionLimit int
+
+	undo          map[string]models.UndoEntry
+	lastUndoSweep time.Time
+
+	labels       map[string]map[uint64]models.Label
+	labelCounter uint64
+
+	projects       map[string]map[uint64]models.Project
+	inboxes        map[string]uint64
+	projectCounter uint64
+
+	comments       map[uint64][]models.Comment
+	commentCounter uint64
+
+	attachments       map[uint64][]models.Attachment
+	attachmentCounter uint64
+	orphanedBlobs     []string
+
+	customFields       map[string]map[uint64]models.CustomField
+	customFieldCounter uint64
+
+	templates       map[string]map[uint64]models.Template
+	templateCounter uint64
+
+	views       map[string]map[uint64]models.View
+	viewCounter uint64
+
+	boards       map[string]map[uint64]models.Board
+	boardCounter uint64
 }
 
 func NewTaskStore() *TaskStore {
 	return &TaskStore{
 		userTasks: make(map[string]map[uint64]models.Task),
 		counter:   0,
+		now:       time.Now,
+
+		revisions:     make(map[uint64][]models.Revision),
+		revisionLimit: DefaultRevisionLimit,
+
+		undo:   make(map[string]models.UndoEntry),
+		labels: make(map[string]map[uint64]models.Label),
+
+		projects: make(map[string]map[uint64]models.Project),
+		inboxes:  make(map[string]uint64),
+
+		comments:    make(map[uint64][]models.Comment),
+		attachments: make(map[uint64][]models.Attachment),
+
+		customFields: make(map[string]map[uint64]models.CustomField),
+		templates:    make(map[string]map[uint64]models.Template),
+		views:        make(map[string]map[uint64]models.View),
+		boards:       make(map[string]map[uint64]models.Board),
 	}
 }
 
-func (s *TaskStore) GetAll(userID string) []models.Task {
-	s.mu.RLock()
+// lock and rlock acquire the store mutex, giving up if ctx is already done or
+// finishes while waiting for it.
+func (s *TaskStore) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.mu.Lock(ctx)
+}
+
+func (s *TaskStore) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.mu.RLock(ctx)
+}
+
+func errTaskNotFound(taskID uint64) error {
+	return fmt.Errorf("task %d: %w", taskID, apperr.ErrNotFound)
+}
+
+func errVersionMismatch(taskID, version uint64) error {
+	return fmt.Errorf("task %d is at version %d: %w", taskID, version, apperr.ErrConflict)
+}
+
+func (s *TaskStore) GetAll(ctx context.Context, userID string) ([]models.Task, error) {
+	return s.Query(ctx, userID, func(models.Task) bool { return true })
+}
+
+// Query returns the live tasks that match reports true for, in list order.
+// match runs under the store lock, so it must not call back into the store.
+func (s *TaskStore) Query(ctx context.Context, userID string, match func(models.Task) bool) ([]models.Task, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer s.mu.RUnlock()
 
 	tasksMap, exists := s.userTasks[userID]
 	if !exists {
-		return []models.Task{}
+		return []models.Task{}, nil
 	}
 
 	tasks := make([]models.Task, 0, len(tasksMap))
 	for _, task := range tasksMap {
-		tasks = append(tasks, task)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if task.Trashed() {
+			continue
+		}
+		if task = annotate(tasksMap, task); match(task) {
+			tasks = append(tasks, task)
+		}
 	}
-	return tasks
+	sortByRank(tasks)
+	return tasks, nil
 }
 
-func (s *TaskStore) GetByID(userID string, taskID uint64) (models.Task, bool) {
-	s.mu.RLock()
+func (s *TaskStore) GetByID(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Task{}, err
+	}
 	defer s.mu.RUnlock()
 
 	tasksMap, exists := s.userTasks[userID]
 	if !exists {
-		return models.Task{}, false
+		return models.Task{}, errTaskNotFound(taskID)
 	}
 	task, ok := tasksMap[taskID]
-	return task, ok
+	if !ok || task.Trashed() {
+		return models.Task{}, errTaskNotFound(taskID)
+	}
+	return annotate(tasksMap, task), nil
 }
 
-func (s *TaskStore) Create(userID string, task models.Task) models.Task {
-	s.mu.Lock()
+func (s *TaskStore) Create(ctx context.Context, userID string, task models.Task) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
+	defer s.mu.Unlock()
+	return s.create(userID, task)
+}
+
+// CreateOccurrence creates task, an occurrence of a recurring series, unless
+// the series already has an occurrence with the same index, live or in the
+// trash. It reports whether the task was created.
+func (s *TaskStore) CreateOccurrence(ctx context.Context, userID string, task models.Task) (models.Task, bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, false, err
+	}
 	defer s.mu.Unlock()
 
+	rec := task.Recurrence
+	for _, existing := range s.userTasks[userID] {
+		if existing.Recurrence == nil || existing.Recurrence.Index != rec.Index {
+			continue
+		}
+		if existing.ID == rec.SeriesID || existing.Recurrence.SeriesID == rec.SeriesID {
+			return models.Task{}, false, nil
+		}
+	}
+	created, err := s.create(userID, task)
+	if err != nil {
+		return models.Task{}, false, err
+	}
+	return created, true, nil
+}
+
+// create implements Create. Callers must hold s.mu for writing.
+func (s *TaskStore) create(userID string, task models.Task) (models.Task, error) {
+	labelIDs, err := s.labelSet(userID, task.LabelIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+	fields, err := s.fieldValues(userID, task.CustomFields)
+	if err != nil {
+		return models.Task{}, err
+	}
+	projectID, err := s.resolveProject(userID, task.ProjectID, 0)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkParent(s.userTasks[userID], 0, task.ParentID); err != nil {
+		return models.Task{}, err
+	}
+	dependsOn, err := checkDependencies(s.userTasks[userID], 0, task.DependsOn, nil)
+	if err != nil {
+		return models.Task{}, err
+	}
+	_ = s.applyCompletion(s.userTasks[userID], models.Task{}, &task)
+
 	s.counter++
 	task.ID = s.counter
+	task.LabelIDs = labelIDs
+	task.CustomFields = fields
+	task.ProjectID = projectID
+	task.DependsOn = dependsOn
+	task.Priority = priorityOrNone(task.Priority)
+	task.Rank = endRank(s.userTasks[userID], projectID)
 	task.UserID = userID
+	task.Version = 1
 
 	if _, exists := s.userTasks[userID]; !exists {
 		s.userTasks[userID] = make(map[uint64]models.Task)
 	}
 	s.userTasks[userID][task.ID] = task
-	return task
+	s.recordRevision(userID, models.Task{}, task)
+	return annotate(s.userTasks[userID], task), nil
 }
 
-func (s *TaskStore) Update(userID string, taskID uint64, updated models.Task) bool {
-	s.mu.Lock()
+// Update replaces a task and bumps its version. A non-zero expectedVersion
+// must match the stored version, otherwise the update is rejected with a
+// conflict.
+func (s *TaskStore) Update(ctx context.Context, userID string, taskID uint64, updated models.Task, expectedVersion uint64) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
 	defer s.mu.Unlock()
+	return s.update(userID, taskID, updated, expectedVersion)
+}
 
+// update implements Update. Callers must hold s.mu for writing.
+func (s *TaskStore) update(userID string, taskID uint64, updated models.Task, expectedVersion uint64) (models.Task, error) {
 	tasksMap, exists := s.userTasks[userID]
 	if !exists {
-		return false
+		return models.Task{}, errTaskNotFound(taskID)
 	}
 
-	if _, exists := tasksMap[taskID]; !exists {
-		return false
+	current, exists := tasksMap[taskID]
+	if !exists || current.Trashed() {
+		return models.Task{}, errTaskNotFound(taskID)
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return models.Task{}, errVersionMismatch(taskID, current.Version)
+	}
+	labelIDs, err := s.labelSet(userID, updated.LabelIDs)
+	if err != nil {
+		return models.Task{}, err
+	}
+	fields, err := s.fieldValues(userID, updated.CustomFields)
+	if err != nil {
+		return models.Task{}, err
+	}
+	projectID, err := s.resolveProject(userID, updated.ProjectID, current.ProjectID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkParent(tasksMap, taskID, updated.ParentID); err != nil {
+		return models.Task{}, err
+	}
+	dependsOn, err := checkDependencies(tasksMap, taskID, updated.DependsOn, current.DependsOn)
+	if err != nil {
+		return models.Task{}, err
+	}
+	before := snapshotBlockers(tasksMap)
+	if err := s.applyCompletion(tasksMap, current, &updated); err != nil {
+		return models.Task{}, err
 	}
 
 	updated.ID = taskID
+	updated.LabelIDs = labelIDs
+	updated.CustomFields = fields
+	updated.ProjectID = projectID
+	updated.DependsOn = dependsOn
+	updated.Priority = priorityOrNone(updated.Priority)
+	updated.Rank = current.Rank
+	if projectID != current.ProjectID {
+		updated.Rank = endRank(tasksMap, projectID)
+	}
+	updated.ChecklistCounter = max(updated.ChecklistCounter, current.ChecklistCounter)
 	updated.UserID = userID
+	updated.Version = current.Version + 1
 	tasksMap[taskID] = updated
-	return true
+	before.touch(tasksMap)
+	s.recordRevision(userID, current, updated)
+	return annotate(tasksMap, updated), nil
 }
 
-func (s *TaskStore) Delete(userID string, taskID uint64) bool {
-	s.mu.Lock()
+// Delete moves a task and its subtasks to the trash. Trashed tasks are
+// invisible to every other method except GetTrash, Restore and the purge
+// methods.
+func (s *TaskStore) Delete(ctx context.Context, userID string, taskID uint64, expectedVersion uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
 	defer s.mu.Unlock()
 
 	tasksMap, exists := s.userTasks[userID]
 	if !exists {
-		return false
+		return errTaskNotFound(taskID)
+	}
+
+	current, exists := tasksMap[taskID]
+	if !exists || current.Trashed() {
+		return errTaskNotFound(taskID)
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return errVersionMismatch(taskID, current.Version)
+	}
+
+	before := snapshotBlockers(tasksMap)
+	trashSubtree(tasksMap, taskID, s.now())
+	before.touch(tasksMap)
+	return nil
+}
+
+func priorityOrNone(p models.Priority) models.Priority {
+	if p == "" {
+		return models.PriorityNone
+	}
+	return p
+}
+
+func trash(task models.Task, now time.Time) models.Task {
+	deletedAt := now.UTC()
+	task.DeletedAt = &deletedAt
+	task.Version++
+	return task
+}
+
+func (s *TaskStore) GetTrash(ctx context.Context, userID string) ([]models.Task, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	tasks := make([]models.Task, 0)
+	for _, task := range s.userTasks[userID] {
+		if task.Trashed() {
+			tasks = append(tasks, annotate(s.userTasks[userID], task))
+		}
+	}
+	return tasks, nil
+}
+
+func (s *TaskStore) Restore(ctx context.Context, userID string, taskID uint64) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
+	defer s.mu.Unlock()
+
+	tasksMap := s.userTasks[userID]
+	task, exists := tasksMap[taskID]
+	if !exists || !task.Trashed() {
+		return models.Task{}, errTaskNotFound(taskID)
+	}
+
+	before := snapshotBlockers(tasksMap)
+	restoreSubtree(tasksMap, taskID, *task.DeletedAt)
+	task.DeletedAt = nil
+	if projectID := s.existingProject(userID, task.ProjectID); projectID != task.ProjectID {
+		task.ProjectID = projectID
+		task.Rank = endRank(tasksMap, projectID)
+	}
+	if checkParent(tasksMap, taskID, task.ParentID) != nil {
+		task.ParentID = 0
+	}
+	task.Version++
+	tasksMap[taskID] = task
+	before.touch(tasksMap)
+	return annotate(tasksMap, task), nil
+}
+
+// Purge permanently deletes a trashed task along with its trashed subtasks.
+func (s *TaskStore) Purge(ctx context.Context, userID string, taskID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	tasksMap := s.userTasks[userID]
+	task, exists := tasksMap[taskID]
+	if !exists || !task.Trashed() {
+		return errTaskNotFound(taskID)
+	}
+
+	trashed := func(t models.Task) bool { return t.Trashed() }
+	s.removeTasks(tasksMap, append(descendants(tasksMap, taskID, trashed), taskID))
+	return nil
+}
+
+// removeTasks permanently deletes tasks together with their revisions,
+// comments and attachments and drops them from the dependencies of the
+// remaining tasks. Callers must hold s.mu for writing.
+func (s *TaskStore) removeTasks(tasksMap map[uint64]models.Task, ids []uint64) {
+	if len(ids) == 0 {
+		return
 	}
+	removed := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		delete(tasksMap, id)
+		s.forgetTask(id)
+		removed[id] = true
+	}
+	dropDependencies(tasksMap, removed)
+}
+
+// PurgeTrash permanently deletes every task trashed before cutoff and
+// returns how many were removed.
+func (s *TaskStore) PurgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	purged := 0
+	for _, tasksMap := range s.userTasks {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		var removed []uint64
+		for id, task := range tasksMap {
+			if task.Trashed() && task.DeletedAt.Before(cutoff) {
+				removed = append(removed, id)
+			}
+		}
+		s.removeTasks(tasksMap, removed)
+		purged += len(removed)
+	}
+	return purged, nil
+}
+
+// ApplyBatch runs ops against a staged copy of the user's tasks and commits
+// the copy once all ops have run. In atomic mode nothing is committed if any
+// op fails; otherwise failed ops are skipped and the rest are committed.
+func (s *TaskStore) ApplyBatch(ctx context.Context, userID string, ops []models.BatchOp, atomic bool) ([]models.BatchResult, bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, false, err
+	}
+	defer s.mu.Unlock()
+
+	staged := make(map[uint64]models.Task, len(s.userTasks[userID]))
+	for id, task := range s.userTasks[userID] {
+		staged[id] = task
+	}
+	counter := s.counter
+	before := snapshotBlockers(staged)
+
+	type change struct{ before, after models.Task }
+	var changes []change
+
+	results := make([]models.BatchResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if op.Kind == models.BatchCreate && op.ParentOp > 0 {
+			parent := op.ParentOp - 1
+			if parent >= i || ops[parent].Kind != models.BatchCreate {
+				results[i] = models.BatchResult{Err: errUnknownParent}
+				failed = true
+				continue
+			}
+			if results[parent].Err != nil {
+				results[i] = models.BatchResult{Err: results[parent].Err}
+				failed = true
+				continue
+			}
+			op.Task.ParentID = results[parent].Task.ID
+		}
+		results[i] = s.applyBatchOp(staged, &counter, userID, op)
+		if results[i].Err != nil {
+			failed = true
+			continue
+		}
+		switch op.Kind {
+		case models.BatchCreate:
+			changes = append(changes, change{after: results[i].Task})
+		case models.BatchUpdate:
+			changes = append(changes, change{before: results[i].Previous, after: results[i].Task})
+		}
+	}
+
+	before.touch(staged)
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Task = annotate(staged, results[i].Task)
+		}
+	}
+	if atomic && failed {
+		return results, false, nil
+	}
+
+	s.userTasks[userID] = staged
+	s.counter = counter
+	for _, ch := range changes {
+		s.recordRevision(userID, ch.before, ch.after)
+	}
+	return results, true, nil
+}
+
+// applyBatchOp applies op to the staged tasks. Callers must hold s.mu.
+func (s *TaskStore) applyBatchOp(tasks map[uint64]models.Task, counter *uint64, userID string, op models.BatchOp) models.BatchResult {
+	var labelIDs []uint64
+	var fields map[uint64]any
+	if op.Kind != models.BatchDelete {
+		var err error
+		if labelIDs, err = s.labelSet(userID, op.Task.LabelIDs); err != nil {
+			return models.BatchResult{Err: err}
+		}
+		if fields, err = s.fieldValues(userID, op.Task.CustomFields); err != nil {
+			return models.BatchResult{Err: err}
+		}
+	}
+
+	if op.Kind == models.BatchCreate {
+		projectID, err := s.resolveProject(userID, op.Task.ProjectID, 0)
+		if err != nil {
+			return models.BatchResult{Err: err}
+		}
+		if err := checkParent(tasks, 0, op.Task.ParentID); err != nil {
+			return models.BatchResult{Err: err}
+		}
+		dependsOn, err := checkDependencies(tasks, 0, op.Task.DependsOn, nil)
+		if err != nil {
+			return models.BatchResult{Err: err}
+		}
+
+		*counter++
+		task := op.Task
+		_ = s.applyCompletion(tasks, models.Task{}, &task)
+		task.ID = *counter
+		task.LabelIDs = labelIDs
+		task.CustomFields = fields
+		task.ProjectID = projectID
+		task.DependsOn = dependsOn
+		task.Priority = priorityOrNone(task.Priority)
+		task.Rank = endRank(tasks, projectID)
+		task.UserID = userID
+		task.Version = 1
+		tasks[task.ID] = task
+		return models.BatchResult{Task: task}
+	}
+
+	current, exists := tasks[op.TaskID]
+	if !exists || current.Trashed() {
+		return models.BatchResult{Err: errTaskNotFound(op.TaskID)}
+	}
+	if op.ExpectedVersion != 0 && current.Version != op.ExpectedVersion {
+		return models.BatchResult{Err: errVersionMismatch(op.TaskID, current.Version)}
+	}
+
+	switch op.Kind {
+	case models.BatchUpdate:
+		projectID, err := s.resolveProject(userID, op.Task.ProjectID, current.ProjectID)
+		if err != nil {
+			return models.BatchResult{Err: err}
+		}
+		if err := checkParent(tasks, op.TaskID, op.Task.ParentID); err != nil {
+			return models.BatchResult{Err: err}
+		}
+		dependsOn, err := checkDependencies(tasks, op.TaskID, op.Task.DependsOn, current.DependsOn)
+		if err != nil {
+			return models.BatchResult{Err: err}
+		}
 
-	if _, exists := tasksMap[taskID]; !exists {
-		return false
+		updated := op.Task
+		if err := s.applyCompletion(tasks, current, &updated); err != nil {
+			return models.BatchResult{Err: err}
+		}
+		updated.ID = op.TaskID
+		updated.LabelIDs = labelIDs
+		updated.CustomFields = fields
+		updated.ProjectID = projectID
+		updated.DependsOn = dependsOn
+		updated.Priority = priorityOrNone(updated.Priority)
+		updated.Rank = current.Rank
+		if projectID != current.ProjectID {
+			updated.Rank = endRank(tasks, projectID)
+		}
+		updated.ChecklistCounter = max(updated.ChecklistCounter, current.ChecklistCounter)
+		updated.UserID = userID
+		updated.Version = current.Version + 1
+		tasks[op.TaskID] = updated
+		return models.BatchResult{Task: updated, Previous: current}
+	case models.BatchDelete:
+		trashSubtree(tasks, op.TaskID, s.now())
+		return models.BatchResult{Task: tasks[op.TaskID], Previous: current}
+	default:
+		return models.BatchResult{Err: fmt.Errorf("unknown batch operation %q", op.Kind)}
 	}
-	delete(tasksMap, taskID)
-	return true
 }
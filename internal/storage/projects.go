@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/query"
+)
+
+const inboxName = "Inbox"
+
+var (
+	errProjectNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeProjectNotFound, "Project not found", "no project with this id exists")
+	errUnknownProject  = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownProject, "Unknown project", "the project does not exist")
+	errProjectArchived = apperr.New(apperr.ErrConflict, apperr.CodeProjectArchived, "Project archived", "tasks cannot be added to an archived project")
+	errInboxProtected  = apperr.New(apperr.ErrConflict, apperr.CodeInboxProtected, "Inbox protected", "the Inbox cannot be archived or deleted")
+	errProjectExists   = apperr.New(apperr.ErrConflict, apperr.CodeProjectExists, "Project exists", "a project with this name already exists")
+)
+
+// inboxID returns the id of the user's Inbox, creating it on first use.
+// Callers must hold s.mu for writing.
+func (s *TaskStore) inboxID(userID string) uint64 {
+	if id, exists := s.inboxes[userID]; exists {
+		return id
+	}
+
+	s.projectCounter++
+	inbox := models.Project{ID: s.projectCounter, UserID: userID, Name: inboxName, Inbox: true}
+	if _, exists := s.projects[userID]; !exists {
+		s.projects[userID] = make(map[uint64]models.Project)
+	}
+	s.projects[userID][inbox.ID] = inbox
+	s.inboxes[userID] = inbox.ID
+	return inbox.ID
+}
+
+// resolveProject returns the project a task with projectID should be stored
+// in: the Inbox for 0, otherwise projectID itself. Tasks may stay in an
+// archived project, so current is accepted even when archived. Callers must
+// hold s.mu for writing.
+func (s *TaskStore) resolveProject(userID string, projectID, current uint64) (uint64, error) {
+	if projectID == 0 {
+		return s.inboxID(userID), nil
+	}
+	project, exists := s.projects[userID][projectID]
+	if !exists {
+		return 0, errUnknownProject
+	}
+	if project.Archived && projectID != current {
+		return 0, errProjectArchived
+	}
+	return projectID, nil
+}
+
+// projectNameTaken reports whether another project of the user already has
+// name, ignoring case. Queries name projects, so names must be unique.
+// Callers must hold s.mu.
+func (s *TaskStore) projectNameTaken(userID string, name string, exceptID uint64) bool {
+	for id, p := range s.projects[userID] {
+		if id != exceptID && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingProject returns projectID, or the Inbox when that project has been
+// deleted since. Callers must hold s.mu for writing.
+func (s *TaskStore) existingProject(userID string, projectID uint64) uint64 {
+	if _, exists := s.projects[userID][projectID]; exists {
+		return projectID
+	}
+	return s.inboxID(userID)
+}
+
+func (s *TaskStore) CreateProject(ctx context.Context, userID string, project models.Project) (models.Project, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Project{}, err
+	}
+	defer s.mu.Unlock()
+
+	s.inboxID(userID)
+	if s.projectNameTaken(userID, project.Name, 0) {
+		return models.Project{}, errProjectExists
+	}
+	s.projectCounter++
+	project.ID = s.projectCounter
+	project.UserID = userID
+	project.Inbox = false
+	project.Archived = false
+	s.projects[userID][project.ID] = project
+	return project, nil
+}
+
+// GetProjects returns the user's projects, Inbox first and the rest sorted
+// by name.
+func (s *TaskStore) GetProjects(ctx context.Context, userID string) ([]models.Project, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	s.inboxID(userID)
+	projects := make([]models.Project, 0, len(s.projects[userID]))
+	for _, p := range s.projects[userID] {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Inbox != projects[j].Inbox {
+			return projects[i].Inbox
+		}
+		return projects[i].Name < projects[j].Name
+	})
+	return projects, nil
+}
+
+func (s *TaskStore) GetProject(ctx context.Context, userID string, projectID uint64) (models.Project, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Project{}, err
+	}
+	defer s.mu.RUnlock()
+
+	project, exists := s.projects[userID][projectID]
+	if !exists {
+		return models.Project{}, errProjectNotFound
+	}
+	return project, nil
+}
+
+func (s *TaskStore) RenameProject(ctx context.Context, userID string, projectID uint64, name string) (models.Project, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Project{}, err
+	}
+	defer s.mu.Unlock()
+
+	project, exists := s.projects[userID][projectID]
+	if !exists {
+		return models.Project{}, errProjectNotFound
+	}
+	if s.projectNameTaken(userID, name, projectID) {
+		return models.Project{}, errProjectExists
+	}
+	s.renameInViews(userID, query.FieldProject, project.Name, name)
+	project.Name = name
+	s.projects[userID][projectID] = project
+	return project, nil
+}
+
+// SetProjectArchived archives or unarchives a project. The project's tasks
+// keep their project and are hidden or shown with it.
+func (s *TaskStore) SetProjectArchived(ctx context.Context, userID string, projectID uint64, archived bool) (models.Project, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Project{}, err
+	}
+	defer s.mu.Unlock()
+
+	project, exists := s.projects[userID][projectID]
+	if !exists {
+		return models.Project{}, errProjectNotFound
+	}
+	if project.Inbox {
+		return models.Project{}, errInboxProtected
+	}
+	project.Archived = archived
+	s.projects[userID][projectID] = project
+	return project, nil
+}
+
+// DeleteProject deletes a project and moves its tasks to the Inbox in the
+// same transaction. With cascade set the tasks are also moved to the trash,
+// so restoring one brings it back into the Inbox.
+func (s *TaskStore) DeleteProject(ctx context.Context, userID string, projectID uint64, cascade bool) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	project, exists := s.projects[userID][projectID]
+	if !exists {
+		return errProjectNotFound
+	}
+	if project.Inbox {
+		return errInboxProtected
+	}
+	delete(s.projects[userID], projectID)
+
+	inbox := s.inboxID(userID)
+	s.moveViews(userID, projectID, inbox)
+	s.moveBoards(userID, projectID, inbox)
+	// Subtasks are trashed along with their parents wherever they live, as
+	// when the parents are deleted one by one.
+	tasksMap, now := s.userTasks[userID], s.now()
+	before := snapshotBlockers(tasksMap)
+	for _, t := range list(tasksMap, projectID) {
+		task := tasksMap[t.ID]
+		task.ProjectID = inbox
+		task.Rank = endRank(tasksMap, inbox)
+		trashing := cascade && !task.Trashed()
+		if !trashing {
+			task.Version++
+		}
+		tasksMap[task.ID] = task
+		if trashing {
+			trashSubtree(tasksMap, task.ID, now)
+		}
+	}
+	before.touch(tasksMap)
+	return nil
+}
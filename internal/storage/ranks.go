@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/rank"
+)
+
+var (
+	errUnknownAnchor = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownAnchor, "Unknown anchor", "the task to move next to does not exist")
+	errOtherList     = apperr.New(apperr.ErrUnprocessable, apperr.CodeOtherList, "Different list", "tasks can only be moved next to tasks of the same project")
+)
+
+// sortByRank orders tasks by project, then by rank within each project.
+func sortByRank(tasks []models.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		return a.ID < b.ID
+	})
+}
+
+// list returns the tasks of a project in rank order, trashed ones included
+// so that restored tasks get their place back.
+func list(tasks map[uint64]models.Task, projectID uint64) []models.Task {
+	var out []models.Task
+	for _, task := range tasks {
+		if task.ProjectID == projectID {
+			out = append(out, task)
+		}
+	}
+	sortByRank(out)
+	return out
+}
+
+// endRank returns a rank that puts a task at the end of a project's list.
+func endRank(tasks map[uint64]models.Task, projectID uint64) string {
+	last := ""
+	for _, task := range tasks {
+		if task.ProjectID == projectID && task.Rank > last {
+			last = task.Rank
+		}
+	}
+	return rank.Between(last, "")
+}
+
+// Move places a task right before beforeID or right after afterID, one of
+// which is 0, by giving it a rank between its new neighbours. No other task
+// is changed.
+func (s *TaskStore) Move(ctx context.Context, userID string, taskID, beforeID, afterID uint64, expectedVersion uint64) (models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Task{}, err
+	}
+	defer s.mu.Unlock()
+
+	tasksMap := s.userTasks[userID]
+	task, exists := tasksMap[taskID]
+	if !exists || task.Trashed() {
+		return models.Task{}, errTaskNotFound(taskID)
+	}
+	if expectedVersion != 0 && task.Version != expectedVersion {
+		return models.Task{}, errVersionMismatch(taskID, task.Version)
+	}
+
+	anchorID := beforeID + afterID
+	anchor, exists := tasksMap[anchorID]
+	if !exists || anchor.Trashed() || anchorID == taskID {
+		return models.Task{}, errUnknownAnchor
+	}
+	if anchor.ProjectID != task.ProjectID {
+		return models.Task{}, errOtherList
+	}
+
+	var others []models.Task
+	for _, t := range list(tasksMap, task.ProjectID) {
+		if t.ID != taskID {
+			others = append(others, t)
+		}
+	}
+	for i, t := range others {
+		if t.ID != anchorID {
+			continue
+		}
+		if beforeID != 0 {
+			prev := ""
+			if i > 0 {
+				prev = others[i-1].Rank
+			}
+			task.Rank = rank.Between(prev, t.Rank)
+		} else {
+			next := ""
+			if i+1 < len(others) {
+				next = others[i+1].Rank
+			}
+			task.Rank = rank.Between(t.Rank, next)
+		}
+		break
+	}
+
+	task.Version++
+	tasksMap[taskID] = task
+	return annotate(tasksMap, task), nil
+}
+
+// Rebalance respaces the ranks of every list that has a rank longer than
+// maxLength or duplicate ranks, keeping the order of its tasks. Ranks are an
+// internal ordering key, so versions are left alone. It returns how many
+// lists were rebalanced.
+func (s *TaskStore) Rebalance(ctx context.Context, maxLength int) (int, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	rebalanced := 0
+	for _, tasksMap := range s.userTasks {
+		if err := ctx.Err(); err != nil {
+			return rebalanced, err
+		}
+		projects := make(map[uint64]bool)
+		for _, task := range tasksMap {
+			projects[task.ProjectID] = true
+		}
+		for projectID := range projects {
+			tasks := list(tasksMap, projectID)
+			if !needsRebalance(tasks, maxLength) {
+				continue
+			}
+			for i, r := range rank.Spread(len(tasks)) {
+				task := tasks[i]
+				task.Rank = r
+				tasksMap[task.ID] = task
+			}
+			rebalanced++
+		}
+	}
+	return rebalanced, nil
+}
+
+func needsRebalance(tasks []models.Task, maxLength int) bool {
+	for i, task := range tasks {
+		if len(task.Rank) > maxLength || task.Rank == "" || (i > 0 && tasks[i-1].Rank == task.Rank) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"time"
+)
+
+// DefaultRevisionLimit is the number of revisions kept per task; older ones
+// are dropped as new ones are recorded.
+const DefaultRevisionLimit = 50
+
+func errRevisionNotFound(taskID, rev uint64) error {
+	return fmt.Errorf("task %d revision %d: %w", taskID, rev, apperr.ErrNotFound)
+}
+
+// diffTask lists the changes to the fields clients can write. IDs are
+// listed in their stored form; custom fields are listed per field as
+// custom_fields.<id>.
+func diffTask(before, after models.Task) []models.FieldChange {
+	var changes []models.FieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, models.FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("priority", string(before.Priority), string(after.Priority))
+	add("project", formatIDs(before.ProjectID), formatIDs(after.ProjectID))
+	add("parent", formatIDs(before.ParentID), formatIDs(after.ParentID))
+	add("labels", formatIDs(before.LabelIDs...), formatIDs(after.LabelIDs...))
+	add("depends_on", formatIDs(before.DependsOn...), formatIDs(after.DependsOn...))
+	add("completed", strconv.FormatBool(before.Completed), strconv.FormatBool(after.Completed))
+	add("due_at", formatTime(before.DueAt), formatTime(after.DueAt))
+	add("rrule", before.Recurrence.GetRule(), after.Recurrence.GetRule())
+	add("timezone", before.Recurrence.GetTimeZone(), after.Recurrence.GetTimeZone())
+
+	ids := slices.Collect(maps.Keys(before.CustomFields))
+	for id := range after.CustomFields {
+		if _, exists := before.CustomFields[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		add("custom_fields."+strconv.FormatUint(id, 10), formatValue(before.CustomFields[id]), formatValue(after.CustomFields[id]))
+	}
+	return changes
+}
+
+// formatIDs lists ids separated by commas, leaving out the 0 of an unset
+// reference.
+func formatIDs(ids ...uint64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 {
+			parts = append(parts, strconv.FormatUint(id, 10))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// recordRevision appends a revision for the change from before to after.
+// Writes that change none of the fields clients write are not recorded. Callers must
+// hold s.mu.
+func (s *TaskStore) recordRevision(userID string, before, after models.Task) {
+	changes := diffTask(before, after)
+	if len(changes) == 0 {
+		return
+	}
+
+	revs := s.revisions[after.ID]
+	number := uint64(1)
+	if len(revs) > 0 {
+		number = revs[len(revs)-1].Number + 1
+	}
+
+	revs = append(revs, models.Revision{
+		Number:      number,
+		TaskID:      after.ID,
+		UserID:      userID,
+		Version:     after.Version,
+		CreatedAt:   s.now().UTC(),
+		Changes:     changes,
+		Title:       after.Title,
+		Description: after.Description,
+		Snapshot:    after,
+	})
+	if len(revs) > s.revisionLimit {
+		revs = append([]models.Revision(nil), revs[len(revs)-s.revisionLimit:]...)
+	}
+	s.revisions[after.ID] = revs
+}
+
+// GetRevisions returns the retained revisions of a task, oldest first.
+func (s *TaskStore) GetRevisions(ctx context.Context, userID string, taskID uint64) ([]models.Revision, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	task, exists := s.userTasks[userID][taskID]
+	if !exists || task.Trashed() {
+		return nil, errTaskNotFound(taskID)
+	}
+	return append([]models.Revision{}, s.revisions[taskID]...), nil
+}
+
+func (s *TaskStore) GetRevision(ctx context.Context, userID string, taskID, rev uint64) (models.Revision, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Revision{}, err
+	}
+	defer s.mu.RUnlock()
+
+	task, exists := s.userTasks[userID][taskID]
+	if !exists || task.Trashed() {
+		return models.Revision{}, errTaskNotFound(taskID)
+	}
+	for _, r := range s.revisions[taskID] {
+		if r.Number == rev {
+			return r, nil
+		}
+	}
+	return models.Revision{}, errRevisionNotFound(taskID, rev)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"time"
+)
+
+var (
+	errUnknownParent = apperr.New(apperr.ErrUnprocessable, apperr.CodeUnknownParent, "Unknown parent", "the parent task does not exist")
+	errParentCycle   = apperr.New(apperr.ErrUnprocessable, apperr.CodeParentCycle, "Parent cycle", "a task cannot become a subtask of itself or of one of its subtasks")
+	errOpenSubtasks  = apperr.New(apperr.ErrConflict, apperr.CodeOpenSubtasks, "Open subtasks", "a task cannot be completed while it has open subtasks")
+)
+
+// checkParent verifies that taskID may become a subtask of parentID. taskID
+// is 0 for tasks that don't exist yet.
+func checkParent(tasks map[uint64]models.Task, taskID, parentID uint64) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parent, exists := tasks[parentID]; !exists || parent.Trashed() {
+		return errUnknownParent
+	}
+	for id, depth := parentID, 0; id != 0 && depth <= len(tasks); id, depth = tasks[id].ParentID, depth+1 {
+		if id == taskID {
+			return errParentCycle
+		}
+	}
+	return nil
+}
+
+func children(tasks map[uint64]models.Task, parentID uint64) []models.Task {
+	var out []models.Task
+	for _, task := range tasks {
+		if task.ParentID == parentID {
+			out = append(out, task)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// descendants returns the ids of all subtasks of id, at any depth, that
+// match keep.
+func descendants(tasks map[uint64]models.Task, id uint64, keep func(models.Task) bool) []uint64 {
+	var out []uint64
+	queue := []uint64{id}
+	for len(queue) > 0 {
+		for _, child := range children(tasks, queue[0]) {
+			if keep(child) {
+				out = append(out, child.ID)
+			}
+			queue = append(queue, child.ID)
+		}
+		queue = queue[1:]
+	}
+	return out
+}
+
+func isLive(task models.Task) bool { return !task.Trashed() }
+
+// trashSubtree moves a task and its live subtasks to the trash with the same
+// deletion time, so that they can be restored together.
+func trashSubtree(tasks map[uint64]models.Task, id uint64, now time.Time) {
+	for _, d := range descendants(tasks, id, isLive) {
+		tasks[d] = trash(tasks[d], now)
+	}
+	tasks[id] = trash(tasks[id], now)
+}
+
+// restoreSubtree restores the subtasks of id that were trashed together with
+// it at deletedAt.
+func restoreSubtree(tasks map[uint64]models.Task, id uint64, deletedAt time.Time) {
+	trashedWith := func(t models.Task) bool { return t.Trashed() && t.DeletedAt.Equal(deletedAt) }
+	for _, d := range descendants(tasks, id, trashedWith) {
+		task := tasks[d]
+		task.DeletedAt = nil
+		task.Version++
+		tasks[d] = task
+	}
+}
+
+// applyCompletion sets updated.CompletedAt when current becomes completed
+// and enforces the completion policy for its open subtasks. With cascading
+// completion it completes them in tasks, so callers must not fail after it.
+func (s *TaskStore) applyCompletion(tasks map[uint64]models.Task, current models.Task, updated *models.Task) error {
+	switch {
+	case !updated.Completed:
+		updated.CompletedAt = nil
+		return nil
+	case current.Completed:
+		updated.CompletedAt = current.CompletedAt
+		return nil
+	}
+
+	now := s.now().UTC()
+	updated.CompletedAt = &now
+	if current.ID == 0 {
+		return nil
+	}
+
+	open := descendants(tasks, current.ID, func(t models.Task) bool { return isLive(t) && !t.Completed })
+	if len(open) == 0 {
+		return nil
+	}
+	if s.CompletionPolicy != models.CompleteCascade {
+		return errOpenSubtasks
+	}
+	for _, id := range open {
+		task := tasks[id]
+		task.Completed = true
+		task.CompletedAt = &now
+		task.Version++
+		tasks[id] = task
+	}
+	return nil
+}
+
+func (s *TaskStore) GetChildren(ctx context.Context, userID string, taskID uint64) ([]models.Task, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	tasks := s.userTasks[userID]
+	if task, exists := tasks[taskID]; !exists || task.Trashed() {
+		return nil, errTaskNotFound(taskID)
+	}
+
+	out := make([]models.Task, 0)
+	for _, child := range children(tasks, taskID) {
+		if isLive(child) {
+			out = append(out, annotate(tasks, child))
+		}
+	}
+	return out, nil
+}
+
+// GetSubtree returns a task with all of its live subtasks nested below it.
+func (s *TaskStore) GetSubtree(ctx context.Context, userID string, taskID uint64) (models.TaskNode, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.TaskNode{}, err
+	}
+	defer s.mu.RUnlock()
+
+	tasks := s.userTasks[userID]
+	task, exists := tasks[taskID]
+	if !exists || task.Trashed() {
+		return models.TaskNode{}, errTaskNotFound(taskID)
+	}
+	return subtree(tasks, task), nil
+}
+
+func subtree(tasks map[uint64]models.Task, task models.Task) models.TaskNode {
+	node := models.TaskNode{Task: annotate(tasks, task), Children: make([]models.TaskNode, 0)}
+	for _, child := range children(tasks, task.ID) {
+		if isLive(child) {
+			node.Children = append(node.Children, subtree(tasks, child))
+		}
+	}
+	return node
+}
@@ -1,8 +1,16 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"task-backend/internal/apperr"
 	"task-backend/internal/models"
 )
 
@@ -14,7 +22,10 @@ func TestTaskStore_CreateAndGetByID(t *testing.T) {
 		Description: "Test Description",
 	}
 
-	created := store.Create("user1", task)
+	created, err := store.Create(context.Background(), "user1", task)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
 
 	if created.ID == 0 {
 		t.Error("Expected non-zero ID after creation")
@@ -23,9 +34,9 @@ func TestTaskStore_CreateAndGetByID(t *testing.T) {
 		t.Errorf("Expected UserID to be 'user1', got %s", created.UserID)
 	}
 
-	retrieved, found := store.GetByID("user1", created.ID)
-	if !found {
-		t.Error("Expected to find created task")
+	retrieved, err := store.GetByID(context.Background(), "user1", created.ID)
+	if err != nil {
+		t.Errorf("Expected to find created task, got %v", err)
 	}
 	if retrieved.Title != task.Title || retrieved.Description != task.Description {
 		t.Errorf("Retrieved task doesn't match created task: %+v", retrieved)
@@ -35,80 +46,1203 @@ func TestTaskStore_CreateAndGetByID(t *testing.T) {
 func TestTaskStore_GetAll(t *testing.T) {
 	store := NewTaskStore()
 
-	store.Create("user1", models.Task{Title: "Task 1"})
-	store.Create("user1", models.Task{Title: "Task 2"})
-	store.Create("user2", models.Task{Title: "Task X"})
+	store.Create(context.Background(), "user1", models.Task{Title: "Task 1"})
+	store.Create(context.Background(), "user1", models.Task{Title: "Task 2"})
+	store.Create(context.Background(), "user2", models.Task{Title: "Task X"})
 
-	tasksUser1 := store.GetAll("user1")
+	tasksUser1, _ := store.GetAll(context.Background(), "user1")
 	if len(tasksUser1) != 2 {
 		t.Errorf("Expected 2 tasks for user1, got %d", len(tasksUser1))
 	}
 
-	tasksUser2 := store.GetAll("user2")
+	tasksUser2, _ := store.GetAll(context.Background(), "user2")
 	if len(tasksUser2) != 1 {
 		t.Errorf("Expected 1 task for user2, got %d", len(tasksUser2))
 	}
 
-	tasksUser3 := store.GetAll("user3")
+	tasksUser3, _ := store.GetAll(context.Background(), "user3")
 	if len(tasksUser3) != 0 {
 		t.Errorf("Expected 0 tasks for unknown user, got %d", len(tasksUser3))
 	}
 }
 
+func TestTaskStore_Query(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	first, _ := store.Create(ctx, "user1", models.Task{Title: "First"})
+	second, _ := store.Create(ctx, "user1", models.Task{Title: "Second", DependsOn: []uint64{first.ID}})
+	third, _ := store.Create(ctx, "user1", models.Task{Title: "Third", DependsOn: []uint64{first.ID}})
+	_ = store.Delete(ctx, "user1", third.ID, 0)
+
+	blocked, err := store.Query(ctx, "user1", func(t models.Task) bool { return t.Blocked })
+	if err != nil || len(blocked) != 1 || blocked[0].ID != second.ID {
+		t.Errorf("Expected only the live blocked task, got %+v (%v)", blocked, err)
+	}
+	open, _ := store.Query(ctx, "user1", func(t models.Task) bool { return !t.Completed })
+	if len(open) != 2 || open[0].ID != first.ID || open[1].ID != second.ID {
+		t.Errorf("Expected matches in list order, got %+v", open)
+	}
+}
+
 func TestTaskStore_Update(t *testing.T) {
 	store := NewTaskStore()
 
-	task := store.Create("user1", models.Task{Title: "Old Title", Description: "Old Desc"})
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Old Title", Description: "Old Desc"})
 
 	updated := models.Task{Title: "New Title", Description: "New Desc"}
-	success := store.Update("user1", task.ID, updated)
-	if !success {
-		t.Error("Expected update to succeed")
+	if _, err := store.Update(context.Background(), "user1", task.ID, updated, 0); err != nil {
+		t.Errorf("Expected update to succeed, got %v", err)
 	}
 
-	afterUpdate, found := store.GetByID("user1", task.ID)
-	if !found {
-		t.Fatal("Updated task not found")
+	afterUpdate, err := store.GetByID(context.Background(), "user1", task.ID)
+	if err != nil {
+		t.Fatalf("Updated task not found: %v", err)
 	}
 	if afterUpdate.Title != "New Title" || afterUpdate.Description != "New Desc" {
 		t.Errorf("Task not updated properly: %+v", afterUpdate)
 	}
 
-	failed := store.Update("user1", 9999, updated)
-	if failed {
-		t.Error("Expected update to fail for non-existent task ID")
+	_, err = store.Update(context.Background(), "user1", 9999, updated, 0)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected update to fail for non-existent task ID, got %v", err)
 	}
 
-	failed = store.Update("user2", task.ID, updated)
-	if failed {
-		t.Error("Expected update to fail for wrong user")
+	_, err = store.Update(context.Background(), "user2", task.ID, updated, 0)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected update to fail for wrong user, got %v", err)
 	}
 }
 
 func TestTaskStore_Delete(t *testing.T) {
 	store := NewTaskStore()
 
-	task := store.Create("user1", models.Task{Title: "Task to delete"})
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Task to delete"})
+
+	if err := store.Delete(context.Background(), "user1", task.ID, 0); err != nil {
+		t.Errorf("Expected delete to succeed, got %v", err)
+	}
+
+	_, err := store.GetByID(context.Background(), "user1", task.ID)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected task to be deleted, got %v", err)
+	}
+
+	err = store.Delete(context.Background(), "user1", task.ID, 0)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected second delete to fail, got %v", err)
+	}
+
+	task2, _ := store.Create(context.Background(), "user1", models.Task{Title: "Another task"})
+	err = store.Delete(context.Background(), "user2", task2.ID, 0)
+	if !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected delete to fail for wrong user, got %v", err)
+	}
+}
+
+func TestTaskStore_CanceledContext(t *testing.T) {
+	store := NewTaskStore()
+	task, _ := store.Create(context.Background(), "user1", models.Task{Title: "Task"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.GetAll(ctx, "user1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected GetAll to abort with context.Canceled, got %v", err)
+	}
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Late"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Create to abort with context.Canceled, got %v", err)
+	}
+	if err := store.Delete(ctx, "user1", task.ID, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Delete to abort with context.Canceled, got %v", err)
+	}
+
+	tasks, _ := store.GetAll(context.Background(), "user1")
+	if len(tasks) != 1 {
+		t.Errorf("Canceled calls must not modify the store, got %d tasks", len(tasks))
+	}
+}
+
+func TestTaskStore_DeadlineWhileWaiting(t *testing.T) {
+	store := NewTaskStore()
+	if err := store.rlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Blocked"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the writer to give up waiting, got %v", err)
+	}
+
+	// The writer that gave up must not keep other readers out.
+	if _, err := store.GetAll(context.Background(), "user1"); err != nil {
+		t.Errorf("Expected readers to get in after the writer gave up, got %v", err)
+	}
+	store.mu.RUnlock()
+	if _, err := store.Create(context.Background(), "user1", models.Task{Title: "Later"}); err != nil {
+		t.Errorf("Expected writers to get in once the lock is free, got %v", err)
+	}
+}
+
+func TestTaskStore_VersionCheck(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Task"})
+	if task.Version != 1 {
+		t.Fatalf("Expected new task at version 1, got %d", task.Version)
+	}
+
+	updated, err := store.Update(ctx, "user1", task.ID, models.Task{Title: "First"}, 1)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("Expected update to bump version to 2, got %d (%v)", updated.Version, err)
+	}
+
+	_, err = store.Update(ctx, "user1", task.ID, models.Task{Title: "Second"}, 1)
+	if !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected stale version update to conflict, got %v", err)
+	}
+
+	if err := store.Delete(ctx, "user1", task.ID, 1); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected stale version delete to conflict, got %v", err)
+	}
+
+	stored, _ := store.GetByID(ctx, "user1", task.ID)
+	if stored.Title != "First" || stored.Version != 2 {
+		t.Errorf("Rejected writes must not modify the task: %+v", stored)
+	}
+}
+
+func TestTaskStore_ApplyBatch(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	existing, _ := store.Create(ctx, "user1", models.Task{Title: "Existing"})
+	ops := []models.BatchOp{
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Created"}},
+		{Kind: models.BatchUpdate, TaskID: existing.ID, Task: models.Task{Title: "Updated"}, ExpectedVersion: 1},
+		{Kind: models.BatchDelete, TaskID: 9999},
+	}
 
-	deleted := store.Delete("user1", task.ID)
-	if !deleted {
-		t.Error("Expected delete to succeed")
+	results, committed, err := store.ApplyBatch(ctx, "user1", ops, true)
+	if err != nil {
+		t.Fatalf("ApplyBatch returned error: %v", err)
+	}
+	if committed {
+		t.Error("Expected atomic batch with a failing op not to commit")
+	}
+	if results[0].Err != nil || results[1].Err != nil || !errors.Is(results[2].Err, apperr.ErrNotFound) {
+		t.Errorf("Unexpected per-op results: %+v", results)
+	}
+	tasks, _ := store.GetAll(ctx, "user1")
+	if len(tasks) != 1 || tasks[0].Title != "Existing" {
+		t.Errorf("Aborted atomic batch must not modify the store: %+v", tasks)
 	}
 
-	_, found := store.GetByID("user1", task.ID)
-	if found {
-		t.Error("Expected task to be deleted")
+	results, committed, _ = store.ApplyBatch(ctx, "user1", ops, false)
+	if !committed {
+		t.Error("Expected non-atomic batch to commit")
 	}
+	tasks, _ = store.GetAll(ctx, "user1")
+	if len(tasks) != 2 {
+		t.Errorf("Expected successful ops to be committed, got %d tasks", len(tasks))
+	}
+	updated, _ := store.GetByID(ctx, "user1", existing.ID)
+	if updated.Title != "Updated" || updated.Version != 2 {
+		t.Errorf("Expected update to be committed with a new version: %+v", updated)
+	}
+	created, _ := store.GetByID(ctx, "user1", results[0].Task.ID)
+	if created.Title != "Created" || created.Version != 1 {
+		t.Errorf("Expected create to be committed: %+v", created)
+	}
+}
 
+func TestTaskStore_Trash(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
 
-	deletedAgain := store.Delete("user1", task.ID)
-	if deletedAgain {
-		t.Error("Expected second delete to fail")
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Task"})
+	if err := store.Delete(ctx, "user1", task.ID, 0); err != nil {
+		t.Fatalf("Expected delete to succeed, got %v", err)
 	}
 
-	task2 := store.Create("user1", models.Task{Title: "Another task"})
-	deletedWrongUser := store.Delete("user2", task2.ID)
-	if deletedWrongUser {
-		t.Error("Expected delete to fail for wrong user")
+	if tasks, _ := store.GetAll(ctx, "user1"); len(tasks) != 0 {
+		t.Errorf("Expected trashed task to be excluded from GetAll, got %d tasks", len(tasks))
+	}
+	if _, err := store.Update(ctx, "user1", task.ID, models.Task{Title: "Edit"}, 0); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected update of trashed task to fail, got %v", err)
+	}
+
+	trash, _ := store.GetTrash(ctx, "user1")
+	if len(trash) != 1 || trash[0].DeletedAt == nil || !trash[0].DeletedAt.Equal(now) {
+		t.Fatalf("Expected trashed task with deletion time, got %+v", trash)
+	}
+
+	restored, err := store.Restore(ctx, "user1", task.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Fatalf("Expected restored task at version 3, got %+v (%v)", restored, err)
+	}
+	if _, err := store.Restore(ctx, "user1", task.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected restore of a live task to fail, got %v", err)
+	}
+	if err := store.Purge(ctx, "user1", task.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected purge of a live task to fail, got %v", err)
+	}
+
+	_ = store.Delete(ctx, "user1", task.ID, 0)
+	if err := store.Purge(ctx, "user1", task.ID); err != nil {
+		t.Errorf("Expected purge to succeed, got %v", err)
+	}
+	if trash, _ := store.GetTrash(ctx, "user1"); len(trash) != 0 {
+		t.Errorf("Expected purged task to leave the trash, got %+v", trash)
+	}
+}
+
+func TestTaskStore_PurgeTrash(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	old, _ := store.Create(ctx, "user1", models.Task{Title: "Old"})
+	_ = store.Delete(ctx, "user1", old.ID, 0)
+
+	now = now.Add(48 * time.Hour)
+	recent, _ := store.Create(ctx, "user2", models.Task{Title: "Recent"})
+	_ = store.Delete(ctx, "user2", recent.ID, 0)
+	live, _ := store.Create(ctx, "user2", models.Task{Title: "Live"})
+
+	purged, err := store.PurgeTrash(ctx, now.Add(-24*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("Expected 1 task purged, got %d (%v)", purged, err)
+	}
+	if trash, _ := store.GetTrash(ctx, "user1"); len(trash) != 0 {
+		t.Errorf("Expected old trash to be purged, got %+v", trash)
+	}
+	if trash, _ := store.GetTrash(ctx, "user2"); len(trash) != 1 {
+		t.Errorf("Expected recent trash to be kept, got %+v", trash)
+	}
+	if _, err := store.GetByID(ctx, "user2", live.ID); err != nil {
+		t.Errorf("Expected live task to be kept, got %v", err)
+	}
+}
+
+func TestTaskStore_CreateOccurrence(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	first, _ := store.Create(ctx, "user1", models.Task{
+		Title:       "Water plants",
+		Description: "Every week",
+		Recurrence:  &models.Recurrence{Rule: "FREQ=WEEKLY", Index: 1},
+	})
+	next := models.Task{
+		Title:       "Water plants",
+		Description: "Every week",
+		Recurrence:  &models.Recurrence{Rule: "FREQ=WEEKLY", SeriesID: first.ID, Index: 2},
+	}
+
+	second, ok, err := store.CreateOccurrence(ctx, "user1", next)
+	if err != nil || !ok {
+		t.Fatalf("Expected the next occurrence to be created, got %v, %v", ok, err)
+	}
+	if _, ok, _ := store.CreateOccurrence(ctx, "user1", next); ok {
+		t.Error("Expected a live occurrence not to be created twice")
+	}
+
+	if err := store.Delete(ctx, "user1", second.ID, 0); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := store.CreateOccurrence(ctx, "user1", next); ok {
+		t.Error("Expected a trashed occurrence not to be created again")
+	}
+
+	next.Recurrence = &models.Recurrence{Rule: "FREQ=WEEKLY", SeriesID: first.ID, Index: 3}
+	if _, ok, _ := store.CreateOccurrence(ctx, "user1", next); !ok {
+		t.Error("Expected the following occurrence to be created")
+	}
+}
+
+func TestTaskStore_Revisions(t *testing.T) {
+	store := NewTaskStore()
+	store.revisionLimit = 3
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Title 0", Description: "Description"})
+	for i := 1; i <= 4; i++ {
+		task.Title = fmt.Sprintf("Title %d", i)
+		task, _ = store.Update(ctx, "user1", task.ID, task, 0)
+	}
+	// A write that changes nothing is not recorded.
+	_, _ = store.Update(ctx, "user1", task.ID, task, 0)
+
+	revs, err := store.GetRevisions(ctx, "user1", task.ID)
+	if err != nil {
+		t.Fatalf("Expected revisions, got %v", err)
+	}
+	if len(revs) != 3 || revs[0].Number != 3 || revs[2].Number != 5 {
+		t.Fatalf("Expected revisions 3..5 to be retained, got %+v", revs)
+	}
+	want := []models.FieldChange{{Field: "title", Old: "Title 3", New: "Title 4"}}
+	if !reflect.DeepEqual(revs[2].Changes, want) || revs[2].Version != 5 || revs[2].UserID != "user1" {
+		t.Errorf("Unexpected latest revision %+v", revs[2])
+	}
+
+	if _, err := store.GetRevision(ctx, "user1", task.ID, 1); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected dropped revision to be missing, got %v", err)
+	}
+	if _, err := store.GetRevisions(ctx, "user2", task.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected history of another user's task to be missing, got %v", err)
+	}
+}
+
+func TestTaskStore_ApplyBatch_RecordsRevisions(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Before"})
+	ops := []models.BatchOp{
+		{Kind: models.BatchUpdate, TaskID: task.ID, Task: models.Task{Title: "After"}},
+		{Kind: models.BatchDelete, TaskID: 999},
+	}
+
+	_, _, _ = store.ApplyBatch(ctx, "user1", ops, true)
+	if revs, _ := store.GetRevisions(ctx, "user1", task.ID); len(revs) != 1 {
+		t.Errorf("Expected aborted batch to record nothing, got %+v", revs)
+	}
+
+	_, _, _ = store.ApplyBatch(ctx, "user1", ops, false)
+	revs, _ := store.GetRevisions(ctx, "user1", task.ID)
+	if len(revs) != 2 || revs[1].Changes[0].Old != "Before" || revs[1].Title != "After" {
+		t.Errorf("Expected committed batch update to be recorded, got %+v", revs)
+	}
+}
+
+func TestTaskStore_Undo(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Original"})
+	edited := task
+	edited.Title = "Edited"
+	edited, _ = store.Update(ctx, "user1", task.ID, edited, 0)
+
+	entry := models.UndoEntry{
+		UserID:    "user1",
+		Steps:     []models.UndoStep{{TaskID: task.ID, Version: edited.Version, Previous: task}},
+		ExpiresAt: now.Add(time.Minute),
+	}
+	_ = store.SaveUndo(ctx, "token", entry)
+
+	if _, err := store.Undo(ctx, "user2", "token"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected another user's token to be rejected, got %v", err)
+	}
+	restored, err := store.Undo(ctx, "user1", "token")
+	if err != nil || len(restored) != 1 || restored[0].Title != "Original" || restored[0].Version != 3 {
+		t.Fatalf("Expected task restored at version 3, got %+v (%v)", restored, err)
+	}
+	if _, err := store.Undo(ctx, "user1", "token"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected token to be single use, got %v", err)
+	}
+
+	entry.Steps[0].Version = 3
+	_ = store.SaveUndo(ctx, "stale", entry)
+	_, _ = store.Update(ctx, "user1", task.ID, edited, 0)
+	if _, err := store.Undo(ctx, "user1", "stale"); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected undo after a later edit to conflict, got %v", err)
+	}
+
+	_ = store.SaveUndo(ctx, "expired", entry)
+	now = now.Add(2 * time.Minute)
+	if _, err := store.Undo(ctx, "user1", "expired"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestTaskStore_Undo_Batch(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Original"})
+	results, _, _ := store.ApplyBatch(ctx, "user1", []models.BatchOp{
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Created"}},
+		{Kind: models.BatchUpdate, TaskID: task.ID, Task: models.Task{Title: "First"}},
+		{Kind: models.BatchUpdate, TaskID: task.ID, Task: models.Task{Title: "Second"}},
+		{Kind: models.BatchDelete, TaskID: task.ID},
+	}, true)
+
+	var steps []models.UndoStep
+	for _, r := range results {
+		steps = append(steps, models.UndoStep{TaskID: r.Task.ID, Version: r.Task.Version, Previous: r.Previous})
+	}
+	_ = store.SaveUndo(ctx, "token", models.UndoEntry{UserID: "user1", Steps: steps, ExpiresAt: time.Now().Add(time.Minute)})
+
+	if _, err := store.Undo(ctx, "user1", "token"); err != nil {
+		t.Fatalf("Expected batch undo to succeed, got %v", err)
+	}
+	tasks, _ := store.GetAll(ctx, "user1")
+	if len(tasks) != 1 || tasks[0].Title != "Original" || tasks[0].Trashed() {
+		t.Errorf("Expected only the original task to remain, got %+v", tasks)
+	}
+}
+
+func TestTaskStore_Undo_Ranks(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+	undo := func(step models.UndoStep) {
+		_ = store.SaveUndo(ctx, "token", models.UndoEntry{UserID: "user1", Steps: []models.UndoStep{step}, ExpiresAt: time.Now().Add(time.Minute)})
+		if _, err := store.Undo(ctx, "user1", "token"); err != nil {
+			t.Fatalf("Undo returned %v", err)
+		}
+	}
+	titles := func() []string {
+		tasks, _ := store.GetAll(ctx, "user1")
+		var out []string
+		for _, task := range tasks {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	a, _ := store.Create(ctx, "user1", models.Task{Title: "A"})
+	b, _ := store.Create(ctx, "user1", models.Task{Title: "B"})
+	c, _ := store.Create(ctx, "user1", models.Task{Title: "C"})
+
+	// An edit is undone in place, even after its neighbours moved around it.
+	edited := a
+	edited.Title = "A2"
+	edited, _ = store.Update(ctx, "user1", a.ID, edited, 0)
+	_, _ = store.Move(ctx, "user1", c.ID, a.ID, 0, 0)
+	undo(models.UndoStep{TaskID: a.ID, Version: edited.Version, Previous: a})
+	if got := titles(); !reflect.DeepEqual(got, []string{"C", "A", "B"}) {
+		t.Errorf("Expected the undone edit to keep its place, got %v", got)
+	}
+
+	// A move is undone to where the task was.
+	current, _ := store.GetByID(ctx, "user1", b.ID)
+	moved, _ := store.Move(ctx, "user1", b.ID, c.ID, 0, 0)
+	undo(models.UndoStep{TaskID: b.ID, Version: moved.Version, Previous: current, Reorder: true})
+	if got := titles(); !reflect.DeepEqual(got, []string{"C", "A", "B"}) {
+		t.Errorf("Expected the undone move to put the task back, got %v", got)
+	}
+}
+
+func TestTaskStore_Undo_ChecklistCounter(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Pack"})
+	added := task
+	added.Checklist = []models.ChecklistItem{{ID: 1, Text: "Passport"}}
+	added.ChecklistCounter = 1
+	added, _ = store.Update(ctx, "user1", task.ID, added, 0)
+	_ = store.SaveUndo(ctx, "token", models.UndoEntry{UserID: "user1", Steps: []models.UndoStep{{TaskID: task.ID, Version: added.Version, Previous: task}}, ExpiresAt: time.Now().Add(time.Minute)})
+	if _, err := store.Undo(ctx, "user1", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.GetByID(ctx, "user1", task.ID); len(stored.Checklist) != 0 || stored.ChecklistCounter != 1 {
+		t.Errorf("Expected the undone item to be gone without its ID being freed, got %+v", stored)
+	}
+}
+
+func TestTaskStore_Undo_Create(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	created, _ := store.Create(ctx, "user1", models.Task{Title: "Created"})
+	dependent, _ := store.Create(ctx, "user1", models.Task{Title: "Dependent", DependsOn: []uint64{created.ID}})
+	_ = store.SaveUndo(ctx, "token", models.UndoEntry{UserID: "user1", Steps: []models.UndoStep{{TaskID: created.ID, Version: created.Version}}, ExpiresAt: time.Now().Add(time.Minute)})
+	if _, err := store.Undo(ctx, "user1", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.GetByID(ctx, "user1", dependent.ID); len(stored.DependsOn) != 0 || stored.Version != dependent.Version+1 {
+		t.Errorf("Expected the removed task to be dropped from dependencies, got %+v", stored)
+	}
+
+	parent, _ := store.Create(ctx, "user1", models.Task{Title: "Parent"})
+	_, _ = store.Create(ctx, "user1", models.Task{Title: "Child", ParentID: parent.ID})
+	_ = store.SaveUndo(ctx, "token", models.UndoEntry{UserID: "user1", Steps: []models.UndoStep{{TaskID: parent.ID, Version: parent.Version}}, ExpiresAt: time.Now().Add(time.Minute)})
+	if _, err := store.Undo(ctx, "user1", "token"); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected undoing the creation of a task with subtasks to conflict, got %v", err)
+	}
+}
+
+func TestTaskStore_Labels(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	work, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "Work"})
+	home, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "Home"})
+	if _, err := store.CreateLabel(ctx, "user1", models.Label{Name: "work"}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected duplicate label name to conflict, got %v", err)
+	}
+	if _, err := store.CreateLabel(ctx, "user2", models.Label{Name: "Work"}); err != nil {
+		t.Errorf("Expected label names to be scoped per user, got %v", err)
+	}
+
+	task, err := store.Create(ctx, "user1", models.Task{Title: "Task", LabelIDs: []uint64{home.ID, work.ID, home.ID}})
+	if err != nil || !reflect.DeepEqual(task.LabelIDs, []uint64{work.ID, home.ID}) {
+		t.Fatalf("Expected deduplicated sorted labels, got %v (%v)", task.LabelIDs, err)
+	}
+	if _, err := store.Create(ctx, "user2", models.Task{Title: "Task", LabelIDs: []uint64{home.ID}}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected another user's label to be rejected, got %v", err)
+	}
+
+	if err := store.DeleteLabel(ctx, "user1", work.ID); err != nil {
+		t.Fatalf("Expected label delete to succeed, got %v", err)
+	}
+	stored, _ := store.GetByID(ctx, "user1", task.ID)
+	if !reflect.DeepEqual(stored.LabelIDs, []uint64{home.ID}) || stored.Version != 2 {
+		t.Errorf("Expected label to be detached in a new version, got %+v", stored)
+	}
+	if _, err := store.GetLabel(ctx, "user1", work.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected deleted label to be missing, got %v", err)
+	}
+}
+
+func TestTaskStore_CreateWithLabels(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	work, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "Work"})
+	task, err := store.CreateWithLabels(ctx, "user1", models.Task{Title: "Task"}, []string{"work", "Urgent", "URGENT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels, _ := store.GetLabels(ctx, "user1")
+	if len(labels) != 2 || labels[0].Name != "Urgent" || !reflect.DeepEqual(task.LabelIDs, []uint64{work.ID, labels[0].ID}) {
+		t.Errorf("Expected the existing label to be reused and one new label, got %+v and %v", labels, task.LabelIDs)
+	}
+
+	if _, err := store.CreateWithLabels(ctx, "user1", models.Task{Title: "Orphan", ParentID: 999}, []string{"Later"}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected the unknown parent to be rejected, got %v", err)
+	}
+	if labels, _ := store.GetLabels(ctx, "user1"); len(labels) != 2 {
+		t.Errorf("Expected a failed create to leave no labels behind, got %+v", labels)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.CreateWithLabels(ctx, "user2", models.Task{Title: "Task"}, []string{"Shared"}); err != nil {
+				t.Errorf("Expected concurrent creates to share the label, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if labels, _ := store.GetLabels(ctx, "user2"); len(labels) != 1 {
+		t.Errorf("Expected a single label for concurrent creates, got %+v", labels)
+	}
+}
+
+func TestTaskStore_Projects(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Inbox task"})
+	projects, _ := store.GetProjects(ctx, "user1")
+	if len(projects) != 1 || !projects[0].Inbox || task.ProjectID != projects[0].ID {
+		t.Fatalf("Expected task to land in a new Inbox, got %+v and %+v", task, projects)
+	}
+	inbox := projects[0].ID
+
+	work, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Work"})
+	moved, err := store.Update(ctx, "user1", task.ID, models.Task{Title: "Inbox task", ProjectID: work.ID}, 0)
+	if err != nil || moved.ProjectID != work.ID {
+		t.Fatalf("Expected task to move to the project, got %+v (%v)", moved, err)
+	}
+	if _, err := store.Create(ctx, "user2", models.Task{Title: "Task", ProjectID: work.ID}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected another user's project to be rejected, got %v", err)
+	}
+
+	_, _ = store.SetProjectArchived(ctx, "user1", work.ID, true)
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Task", ProjectID: work.ID}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected adding to an archived project to conflict, got %v", err)
+	}
+	if _, err := store.Update(ctx, "user1", task.ID, moved, 0); err != nil {
+		t.Errorf("Expected tasks to stay editable in an archived project, got %v", err)
+	}
+	if _, err := store.SetProjectArchived(ctx, "user1", inbox, true); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected the Inbox to be protected, got %v", err)
+	}
+
+	if err := store.DeleteProject(ctx, "user1", work.ID, true); err != nil {
+		t.Fatalf("Expected project delete to succeed, got %v", err)
+	}
+	trash, _ := store.GetTrash(ctx, "user1")
+	if len(trash) != 1 || trash[0].ProjectID != inbox {
+		t.Errorf("Expected cascade delete to trash the task into the Inbox, got %+v", trash)
+	}
+}
+
+func TestTaskStore_ProjectNames(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	work, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Work"})
+	home, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Home"})
+	if _, err := store.CreateProject(ctx, "user1", models.Project{Name: "work"}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected duplicate project names to conflict, got %v", err)
+	}
+	if _, err := store.CreateProject(ctx, "user1", models.Project{Name: "INBOX"}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected the Inbox name to be taken, got %v", err)
+	}
+	if _, err := store.RenameProject(ctx, "user1", home.ID, "WORK"); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected renaming onto another project's name to conflict, got %v", err)
+	}
+	if _, err := store.RenameProject(ctx, "user1", work.ID, "work"); err != nil {
+		t.Errorf("Expected a project to keep its own name in another case, got %v", err)
+	}
+	if _, err := store.CreateProject(ctx, "user2", models.Project{Name: "Work"}); err != nil {
+		t.Errorf("Expected project names to be scoped per user, got %v", err)
+	}
+}
+
+func TestTaskStore_DeleteProjectCascadesToSubtasks(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	work, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Work"})
+	home, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Home"})
+	parent, _ := store.Create(ctx, "user1", models.Task{Title: "Parent", ProjectID: work.ID})
+	child, _ := store.Create(ctx, "user1", models.Task{Title: "Child", ProjectID: home.ID, ParentID: parent.ID})
+
+	if err := store.DeleteProject(ctx, "user1", work.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetByID(ctx, "user1", child.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected the subtask in another project to be trashed with its parent, got %v", err)
+	}
+	_, _ = store.Restore(ctx, "user1", parent.ID)
+	if restored, err := store.GetByID(ctx, "user1", child.ID); err != nil || restored.ProjectID != home.ID {
+		t.Errorf("Expected restoring the parent to bring back the subtask in its project, got %+v (%v)", restored, err)
+	}
+}
+
+func TestTaskStore_Subtasks(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	root, _ := store.Create(ctx, "user1", models.Task{Title: "Root"})
+	child, _ := store.Create(ctx, "user1", models.Task{Title: "Child", ParentID: root.ID})
+	grandchild, _ := store.Create(ctx, "user1", models.Task{Title: "Grandchild", ParentID: child.ID})
+
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Orphan", ParentID: 999}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown parent to be rejected, got %v", err)
+	}
+	root.ParentID = grandchild.ID
+	if _, err := store.Update(ctx, "user1", root.ID, root, 0); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected re-parenting under a descendant to be rejected, got %v", err)
+	}
+	root.ParentID = 0
+
+	tree, err := store.GetSubtree(ctx, "user1", root.ID)
+	if err != nil || len(tree.Children) != 1 || tree.Children[0].Children[0].ID != grandchild.ID {
+		t.Fatalf("Expected a three level tree, got %+v (%v)", tree, err)
+	}
+
+	_ = store.Delete(ctx, "user1", root.ID, 0)
+	if trash, _ := store.GetTrash(ctx, "user1"); len(trash) != 3 {
+		t.Errorf("Expected delete to cascade to subtasks, got %d trashed", len(trash))
+	}
+	_, _ = store.Restore(ctx, "user1", root.ID)
+	if tasks, _ := store.GetAll(ctx, "user1"); len(tasks) != 3 {
+		t.Errorf("Expected restore to bring back subtasks, got %d tasks", len(tasks))
+	}
+}
+
+func TestTaskStore_SubtaskCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.CompletionPolicy
+		wantErr error
+	}{
+		{"blocked by default", "", apperr.ErrConflict},
+		{"cascade", models.CompleteCascade, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewTaskStore()
+			store.CompletionPolicy = tt.policy
+			ctx := context.Background()
+
+			parent, _ := store.Create(ctx, "user1", models.Task{Title: "Parent"})
+			child, _ := store.Create(ctx, "user1", models.Task{Title: "Child", ParentID: parent.ID})
+
+			parent.Completed = true
+			_, err := store.Update(ctx, "user1", parent.ID, parent, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := store.GetByID(ctx, "user1", child.ID)
+			if stored.Completed != (tt.wantErr == nil) {
+				t.Errorf("Unexpected child completion state %+v", stored)
+			}
+		})
+	}
+}
+
+func TestTaskStore_Dependencies(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	design, _ := store.Create(ctx, "user1", models.Task{Title: "Design"})
+	build, _ := store.Create(ctx, "user1", models.Task{Title: "Build", DependsOn: []uint64{design.ID}})
+	ship, _ := store.Create(ctx, "user1", models.Task{Title: "Ship", DependsOn: []uint64{build.ID, design.ID}})
+	docs, _ := store.Create(ctx, "user1", models.Task{Title: "Docs"})
+
+	if !ship.Blocked || !reflect.DeepEqual(ship.BlockedBy, []uint64{design.ID, build.ID}) {
+		t.Errorf("Expected ship to be blocked by design and build, got %+v", ship)
+	}
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Lost", DependsOn: []uint64{999}}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown dependency to be rejected, got %v", err)
+	}
+	design.DependsOn = []uint64{ship.ID}
+	if _, err := store.Update(ctx, "user1", design.ID, design, 0); !errors.Is(err, apperr.ErrInvalid) {
+		t.Errorf("Expected dependency cycle to be rejected, got %v", err)
+	}
+	design.DependsOn = nil
+
+	ready, _ := store.GetReady(ctx, "user1")
+	if len(ready) != 2 || ready[0].ID != design.ID || ready[1].ID != docs.ID {
+		t.Errorf("Expected design and docs to be ready, got %+v", ready)
+	}
+
+	design.Completed = true
+	_, _ = store.Update(ctx, "user1", design.ID, design, 0)
+	if stored, _ := store.GetByID(ctx, "user1", ship.ID); !reflect.DeepEqual(stored.BlockedBy, []uint64{build.ID}) {
+		t.Errorf("Expected completed blockers to be dropped, got %+v", stored.BlockedBy)
+	}
+	ready, _ = store.GetReady(ctx, "user1")
+	if len(ready) != 2 || ready[0].ID != build.ID {
+		t.Errorf("Expected build to become ready, got %+v", ready)
+	}
+
+	_ = store.Delete(ctx, "user1", build.ID, 0)
+	_ = store.Purge(ctx, "user1", build.ID)
+	if stored, _ := store.GetByID(ctx, "user1", ship.ID); stored.Blocked || !reflect.DeepEqual(stored.DependsOn, []uint64{design.ID}) {
+		t.Errorf("Expected purged dependency to be removed, got %+v", stored)
+	}
+}
+
+func TestTaskStore_DependentVersions(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	design, _ := store.Create(ctx, "user1", models.Task{Title: "Design"})
+	build, _ := store.Create(ctx, "user1", models.Task{Title: "Build", DependsOn: []uint64{design.ID}})
+	docs, _ := store.Create(ctx, "user1", models.Task{Title: "Docs"})
+	version := func(id uint64) uint64 {
+		task, _ := store.GetByID(ctx, "user1", id)
+		return task.Version
+	}
+
+	v := version(build.ID)
+	design.Title = "Design it"
+	design, _ = store.Update(ctx, "user1", design.ID, design, 0)
+	if version(build.ID) != v {
+		t.Errorf("Expected edits that keep the blocker open to leave dependents alone")
+	}
+
+	design.Completed = true
+	design, _ = store.Update(ctx, "user1", design.ID, design, 0)
+	if version(build.ID) == v {
+		t.Errorf("Expected completing a blocker to bump its dependents")
+	}
+
+	design.Completed = false
+	_, _ = store.Update(ctx, "user1", design.ID, design, 0)
+	v = version(build.ID)
+	_ = store.Delete(ctx, "user1", design.ID, 0)
+	if version(build.ID) == v {
+		t.Errorf("Expected trashing a blocker to bump its dependents")
+	}
+
+	v = version(build.ID)
+	_, _ = store.Restore(ctx, "user1", design.ID)
+	if version(build.ID) == v {
+		t.Errorf("Expected restoring a blocker to bump its dependents")
+	}
+
+	v, d := version(build.ID), version(docs.ID)
+	results, _, _ := store.ApplyBatch(ctx, "user1", []models.BatchOp{
+		{Kind: models.BatchDelete, TaskID: design.ID},
+	}, true)
+	if results[0].Err != nil || version(build.ID) == v || version(docs.ID) != d {
+		t.Errorf("Expected a batch to bump only the dependents of the blockers it changed, got %+v", results)
+	}
+}
+
+func TestTaskStore_Ordering(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	titles := func() []string {
+		tasks, _ := store.GetAll(ctx, "user1")
+		out := make([]string, len(tasks))
+		for i, task := range tasks {
+			out[i] = task.Title
+		}
+		return out
+	}
+
+	a, _ := store.Create(ctx, "user1", models.Task{Title: "A"})
+	b, _ := store.Create(ctx, "user1", models.Task{Title: "B"})
+	c, _ := store.Create(ctx, "user1", models.Task{Title: "C"})
+	if got := titles(); !reflect.DeepEqual(got, []string{"A", "B", "C"}) {
+		t.Fatalf("Expected tasks in creation order, got %v", got)
+	}
+	if a.Priority != models.PriorityNone {
+		t.Errorf("Expected default priority none, got %q", a.Priority)
+	}
+
+	moved, err := store.Move(ctx, "user1", c.ID, a.ID, 0, 0)
+	if err != nil || moved.Version != c.Version+1 {
+		t.Fatalf("Move returned %+v, %v", moved, err)
+	}
+	if got := titles(); !reflect.DeepEqual(got, []string{"C", "A", "B"}) {
+		t.Errorf("Expected C to move to the front, got %v", got)
+	}
+	if stored, _ := store.GetByID(ctx, "user1", a.ID); stored.Version != a.Version || stored.Rank != a.Rank {
+		t.Errorf("Expected neighbours to be untouched, got %+v", stored)
+	}
+
+	_, _ = store.Move(ctx, "user1", a.ID, 0, b.ID, 0)
+	if got := titles(); !reflect.DeepEqual(got, []string{"C", "B", "A"}) {
+		t.Errorf("Expected A to move after B, got %v", got)
+	}
+	if _, err := store.Move(ctx, "user1", a.ID, 999, 0, 0); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown anchor to be rejected, got %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		_, _ = store.Move(ctx, "user1", a.ID, b.ID, 0, 0)
+		_, _ = store.Move(ctx, "user1", b.ID, a.ID, 0, 0)
+	}
+	before := titles()
+	if n, err := store.Rebalance(ctx, 2); err != nil || n != 1 {
+		t.Fatalf("Rebalance = %d, %v; want one list", n, err)
+	}
+	if got := titles(); !reflect.DeepEqual(got, before) {
+		t.Errorf("Expected rebalancing to keep the order %v, got %v", before, got)
+	}
+	tasks, _ := store.GetAll(ctx, "user1")
+	for _, task := range tasks {
+		if len(task.Rank) > 1 {
+			t.Errorf("Expected short ranks after rebalancing, got %q", task.Rank)
+		}
+	}
+}
+
+func TestTaskStore_Comments(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	task, _ := store.Create(ctx, "user1", models.Task{Title: "Review PR"})
+	for _, body := range []string{"first", "second", "third"} {
+		if _, err := store.AddComment(ctx, "user1", task.ID, models.Comment{Body: body}); err != nil {
+			t.Fatalf("AddComment returned %v", err)
+		}
+	}
+	if _, err := store.AddComment(ctx, "user2", task.ID, models.Comment{Body: "hi"}); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected other users' tasks to be hidden, got %v", err)
+	}
+
+	page, more, err := store.GetComments(ctx, "user1", task.ID, 0, 2)
+	if err != nil || len(page) != 2 || !more || page[0].Body != "first" {
+		t.Fatalf("Expected first page of two, got %+v, %v, %v", page, more, err)
+	}
+	page, more, _ = store.GetComments(ctx, "user1", task.ID, page[1].ID, 2)
+	if len(page) != 1 || more || page[0].Body != "third" {
+		t.Errorf("Expected last page with one comment, got %+v, %v", page, more)
+	}
+
+	edited, err := store.UpdateComment(ctx, "user1", task.ID, page[0].ID, "third, edited")
+	if err != nil || edited.EditedAt == nil || edited.Body != "third, edited" {
+		t.Errorf("UpdateComment returned %+v, %v", edited, err)
+	}
+	if err := store.DeleteComment(ctx, "user1", task.ID, 999); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected unknown comment to be rejected, got %v", err)
+	}
+
+	_ = store.Delete(ctx, "user1", task.ID, 0)
+	if _, _, err := store.GetComments(ctx, "user1", task.ID, 0, 10); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected comments of trashed tasks to be hidden, got %v", err)
+	}
+	_, _ = store.Restore(ctx, "user1", task.ID)
+	if page, _, _ := store.GetComments(ctx, "user1", task.ID, 0, 10); len(page) != 3 {
+		t.Errorf("Expected comments to come back with the task, got %+v", page)
+	}
+
+	_ = store.Delete(ctx, "user1", task.ID, 0)
+	_ = store.Purge(ctx, "user1", task.ID)
+	if _, exists := store.comments[task.ID]; exists {
+		t.Error("Expected comments to be removed with the purged task")
+	}
+}
+
+func TestTaskStore_CustomFields(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	points, _ := store.CreateCustomField(ctx, "user1", models.CustomField{Name: "Points", Type: models.FieldNumber})
+	stage, _ := store.CreateCustomField(ctx, "user1", models.CustomField{Name: "Stage", Type: models.FieldSelect, Options: []string{"design", "build"}})
+	if _, err := store.CreateCustomField(ctx, "user1", models.CustomField{Name: "points", Type: models.FieldText}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected duplicate names to be rejected, got %v", err)
+	}
+
+	task, err := store.Create(ctx, "user1", models.Task{Title: "Estimate", CustomFields: map[uint64]any{points.ID: 3.0, stage.ID: "design"}})
+	if err != nil || task.CustomFields[points.ID] != 3.0 {
+		t.Fatalf("Create returned %+v, %v", task, err)
+	}
+	for _, values := range []map[uint64]any{
+		{points.ID: "three"},
+		{stage.ID: "ship"},
+	} {
+		if _, err := store.Create(ctx, "user1", models.Task{Title: "Invalid", CustomFields: values}); !errors.Is(err, apperr.ErrInvalid) {
+			t.Errorf("Expected %v to be rejected, got %v", values, err)
+		}
+	}
+	if _, err := store.Create(ctx, "user1", models.Task{Title: "Unknown", CustomFields: map[uint64]any{999: "x"}}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown fields to be rejected, got %v", err)
+	}
+	if _, err := store.UpdateCustomField(ctx, "user1", points.ID, models.CustomField{Name: "Points", Type: models.FieldText}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected type changes to be rejected, got %v", err)
+	}
+
+	_, _ = store.UpdateCustomField(ctx, "user1", stage.ID, models.CustomField{Name: "Stage", Type: models.FieldSelect, Options: []string{"build"}})
+	stored, _ := store.GetByID(ctx, "user1", task.ID)
+	if _, ok := stored.CustomFields[stage.ID]; ok || stored.Version != task.Version+1 {
+		t.Errorf("Expected the removed option to be cleared, got %+v", stored)
+	}
+
+	_ = store.DeleteCustomField(ctx, "user1", points.ID)
+	stored, _ = store.GetByID(ctx, "user1", task.ID)
+	if stored.CustomFields != nil {
+		t.Errorf("Expected values of deleted fields to be removed, got %v", stored.CustomFields)
+	}
+}
+
+func TestTaskStore_ApplyBatchParentOp(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	results, committed, err := store.ApplyBatch(ctx, "user1", []models.BatchOp{
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Parent"}},
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Child"}, ParentOp: 1},
+	}, true)
+	if err != nil || !committed {
+		t.Fatalf("ApplyBatch returned %v, %v", committed, err)
+	}
+	if results[1].Task.ParentID != results[0].Task.ID {
+		t.Errorf("Expected the child to get parent %d, got %d", results[0].Task.ID, results[1].Task.ParentID)
+	}
+
+	results, committed, _ = store.ApplyBatch(ctx, "user1", []models.BatchOp{
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Unlabeled", LabelIDs: []uint64{999}}},
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Orphan"}, ParentOp: 1},
+		{Kind: models.BatchCreate, Task: models.Task{Title: "Forward"}, ParentOp: 4},
+	}, true)
+	if committed {
+		t.Fatal("Expected the batch to be rejected")
+	}
+	if !errors.Is(results[1].Err, apperr.ErrUnprocessable) || !errors.Is(results[2].Err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected children of failed or later ops to fail, got %v and %v", results[1].Err, results[2].Err)
+	}
+}
+
+func TestTaskStore_Templates(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	label, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "work"})
+	template, err := store.CreateTemplate(ctx, "user1", models.Template{
+		Name:         "Release",
+		TemplateTask: models.TemplateTask{Title: "Ship release", LabelIDs: []uint64{label.ID, label.ID}},
+		Subtasks:     []models.TemplateTask{{Title: "Tag build", LabelIDs: []uint64{label.ID}}},
+	})
+	if err != nil || len(template.LabelIDs) != 1 {
+		t.Fatalf("CreateTemplate returned %+v, %v", template, err)
+	}
+	if _, err := store.CreateTemplate(ctx, "user1", models.Template{Name: "release"}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected duplicate names to be rejected, got %v", err)
+	}
+	if _, err := store.CreateTemplate(ctx, "user1", models.Template{Name: "Other", Subtasks: []models.TemplateTask{{LabelIDs: []uint64{999}}}}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown labels to be rejected, got %v", err)
+	}
+	if _, err := store.GetTemplate(ctx, "user2", template.ID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("Expected templates to be private, got %v", err)
+	}
+
+	_ = store.DeleteLabel(ctx, "user1", label.ID)
+	stored, _ := store.GetTemplate(ctx, "user1", template.ID)
+	if len(stored.LabelIDs) != 0 || len(stored.Subtasks[0].LabelIDs) != 0 {
+		t.Errorf("Expected the deleted label to be dropped, got %+v", stored)
+	}
+	if len(template.Subtasks[0].LabelIDs) != 1 {
+		t.Error("Expected earlier copies of the template to be left alone")
+	}
+
+	if err := store.DeleteTemplate(ctx, "user1", template.ID); err != nil {
+		t.Fatal(err)
+	}
+	if templates, _ := store.GetTemplates(ctx, "user1"); len(templates) != 0 {
+		t.Errorf("Expected no templates, got %+v", templates)
+	}
+}
+
+func TestTaskStore_Views(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	work, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "work"})
+	urgent, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "urgent"})
+	project, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Launch"})
+
+	view, err := store.CreateView(ctx, "user1", models.View{Name: "Launch work", ProjectID: project.ID, LabelIDs: []uint64{urgent.ID, work.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateView(ctx, "user1", models.View{Name: "launch WORK"}); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("Expected duplicate names to be rejected, got %v", err)
+	}
+	if _, err := store.CreateView(ctx, "user1", models.View{Name: "Ghost", ProjectID: 999}); !errors.Is(err, apperr.ErrUnprocessable) {
+		t.Errorf("Expected unknown projects to be rejected, got %v", err)
+	}
+
+	_, _ = store.UpdateLabel(ctx, "user1", work.ID, models.Label{Name: "job"})
+	_, _ = store.RenameProject(ctx, "user1", project.ID, "Relaunch")
+	_ = store.DeleteLabel(ctx, "user1", urgent.ID)
+	stored, _ := store.GetView(ctx, "user1", view.ID)
+	if stored.ProjectID != project.ID || len(stored.LabelIDs) != 1 || stored.LabelIDs[0] != work.ID {
+		t.Errorf("Expected renames to keep the view and deletions to drop the label, got %+v", stored)
+	}
+
+	_ = store.DeleteProject(ctx, "user1", project.ID, false)
+	stored, _ = store.GetView(ctx, "user1", view.ID)
+	if stored.ProjectID != store.inboxes["user1"] {
+		t.Errorf("Expected the view to follow the project's tasks to the Inbox, got project %d", stored.ProjectID)
+	}
+	if _, err := store.UpdateView(ctx, "user1", view.ID, stored); err != nil {
+		t.Errorf("Expected the view to stay valid, got %v", err)
+	}
+}
+
+func TestTaskStore_ViewQueriesFollowRenames(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	label, _ := store.CreateLabel(ctx, "user1", models.Label{Name: "work"})
+	project, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Launch"})
+	view, _ := store.CreateView(ctx, "user1", models.View{Name: "Launch work", Query: "label:Work project:launch -label:home"})
+
+	_, _ = store.UpdateLabel(ctx, "user1", label.ID, models.Label{Name: "day job"})
+	_, _ = store.RenameProject(ctx, "user1", project.ID, "Relaunch")
+	stored, _ := store.GetView(ctx, "user1", view.ID)
+	if want := `label:"day job" project:Relaunch -label:home`; stored.Query != want {
+		t.Errorf("Expected the query to follow the renames, got %q, want %q", stored.Query, want)
+	}
+}
+
+func TestTaskStore_Boards(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	stage, _ := store.CreateCustomField(ctx, "user1", models.CustomField{Name: "Stage", Type: models.FieldSelect, Options: []string{"todo", "doing"}})
+	notes, _ := store.CreateCustomField(ctx, "user1", models.CustomField{Name: "Notes", Type: models.FieldText})
+	columns := []models.BoardColumn{
+		{Name: "To do", Status: models.StatusOpen, State: "todo"},
+		{Name: "Doing", Status: models.StatusOpen, State: "doing"},
+		{Name: "Done", Status: models.StatusCompleted},
+	}
+
+	board, err := store.CreateBoard(ctx, "user1", models.Board{Name: "Sprint", StateField: stage.ID, Columns: columns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Columns[0].ID != 1 || board.Columns[2].ID != 3 {
+		t.Errorf("Expected columns to be numbered from 1, got %+v", board.Columns)
+	}
+	for name, b := range map[string]models.Board{
+		"duplicate name":      {Name: "sprint"},
+		"unknown field":       {Name: "A", StateField: 999},
+		"non-select field":    {Name: "B", StateField: notes.ID},
+		"unknown state":       {Name: "C", StateField: stage.ID, Columns: []models.BoardColumn{{Name: "Review", State: "review"}}},
+		"state without field": {Name: "D", Columns: []models.BoardColumn{{Name: "To do", State: "todo"}}},
+		"unknown project":     {Name: "E", ProjectID: 999},
+	} {
+		if _, err := store.CreateBoard(ctx, "user1", b); err == nil {
+			t.Errorf("Expected a board with %s to be rejected", name)
+		}
+	}
+
+	// Reordering keeps IDs; a column without a known ID gets a new one.
+	board.Columns = []models.BoardColumn{board.Columns[2], {Name: "Review", Status: models.StatusOpen}, board.Columns[0]}
+	updated, err := store.UpdateBoard(ctx, "user1", board.ID, board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for _, c := range updated.Columns {
+		ids = append(ids, c.ID)
+	}
+	if !reflect.DeepEqual(ids, []uint64{3, 4, 1}) {
+		t.Errorf("Expected column IDs [3 4 1], got %v", ids)
+	}
+
+	project, _ := store.CreateProject(ctx, "user1", models.Project{Name: "Launch"})
+	scoped, _ := store.CreateBoard(ctx, "user1", models.Board{Name: "Launch", ProjectID: project.ID})
+	_ = store.DeleteProject(ctx, "user1", project.ID, false)
+	if stored, _ := store.GetBoard(ctx, "user1", scoped.ID); stored.ProjectID != store.inboxes["user1"] {
+		t.Errorf("Expected the board to follow the project's tasks to the Inbox, got project %d", stored.ProjectID)
+	}
+}
+
+func TestTaskStore_UpdateWithinLimit(t *testing.T) {
+	store := NewTaskStore()
+	ctx := context.Background()
+
+	var tasks []models.Task
+	for _, title := range []string{"A", "B", "C"} {
+		task, _ := store.Create(ctx, "user1", models.Task{Title: title})
+		tasks = append(tasks, task)
+	}
+	_, _ = store.Update(ctx, "user1", tasks[0].ID, models.Task{Title: "A", Completed: true}, 0)
+	completed := func(t models.Task) bool { return t.Completed }
+
+	if _, err := store.UpdateWithinLimit(ctx, "user1", tasks[1].ID, models.Task{Title: "B", Completed: true}, 0, completed, 1); !errors.Is(err, apperr.ErrConflict) {
+		t.Fatalf("Expected the update to be rejected at the limit, got %v", err)
+	}
+	if stored, _ := store.GetByID(ctx, "user1", tasks[1].ID); stored.Completed || stored.Version != 1 {
+		t.Errorf("Expected the rejected task to be unchanged, got %+v", stored)
+	}
+	if _, err := store.UpdateWithinLimit(ctx, "user1", tasks[0].ID, models.Task{Title: "A2", Completed: true}, 0, completed, 1); err != nil {
+		t.Errorf("Expected tasks already counted to be updated, got %v", err)
+	}
+
+	// Concurrent updates must not both take the last free place.
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for _, task := range tasks[1:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.UpdateWithinLimit(ctx, "user1", task.ID, models.Task{Title: task.Title, Completed: true}, 0, completed, 2); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded.Load() != 1 {
+		t.Errorf("Expected exactly one update to fit under the limit, got %d", succeeded.Load())
 	}
 }
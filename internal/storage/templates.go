@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+)
+
+var (
+	errTemplateNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeTemplateNotFound, "Template not found", "no template with this id exists")
+	errTemplateExists   = apperr.New(apperr.ErrConflict, apperr.CodeTemplateExists, "Template exists", "a template with this name already exists")
+)
+
+// templateLabels checks and normalizes the labels of a template and its
+// subtasks. Callers must hold s.mu.
+func (s *TaskStore) templateLabels(userID string, template *models.Template) error {
+	var err error
+	if template.LabelIDs, err = s.labelSet(userID, template.LabelIDs); err != nil {
+		return err
+	}
+	for i := range template.Subtasks {
+		if template.Subtasks[i].LabelIDs, err = s.labelSet(userID, template.Subtasks[i].LabelIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateNameTaken reports whether another template of the user already
+// has name, ignoring case. Callers must hold s.mu.
+func (s *TaskStore) templateNameTaken(userID string, name string, exceptID uint64) bool {
+	for id, t := range s.templates[userID] {
+		if id != exceptID && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropTemplateLabel removes a deleted label from the user's templates.
+// Callers must hold s.mu.
+func (s *TaskStore) dropTemplateLabel(userID string, labelID uint64) {
+	without := func(ids []uint64) []uint64 {
+		var kept []uint64
+		for _, id := range ids {
+			if id != labelID {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+	for id, t := range s.templates[userID] {
+		t.LabelIDs = without(t.LabelIDs)
+		subtasks := make([]models.TemplateTask, len(t.Subtasks))
+		for i, sub := range t.Subtasks {
+			sub.LabelIDs = without(sub.LabelIDs)
+			subtasks[i] = sub
+		}
+		t.Subtasks = subtasks
+		s.templates[userID][id] = t
+	}
+}
+
+func (s *TaskStore) CreateTemplate(ctx context.Context, userID string, template models.Template) (models.Template, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Template{}, err
+	}
+	defer s.mu.Unlock()
+
+	if s.templateNameTaken(userID, template.Name, 0) {
+		return models.Template{}, errTemplateExists
+	}
+	if err := s.templateLabels(userID, &template); err != nil {
+		return models.Template{}, err
+	}
+
+	s.templateCounter++
+	template.ID = s.templateCounter
+	template.UserID = userID
+	if _, exists := s.templates[userID]; !exists {
+		s.templates[userID] = make(map[uint64]models.Template)
+	}
+	s.templates[userID][template.ID] = template
+	return template, nil
+}
+
+// GetTemplates returns the user's templates sorted by name.
+func (s *TaskStore) GetTemplates(ctx context.Context, userID string) ([]models.Template, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	templates := make([]models.Template, 0, len(s.templates[userID]))
+	for _, t := range s.templates[userID] {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+func (s *TaskStore) GetTemplate(ctx context.Context, userID string, templateID uint64) (models.Template, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.Template{}, err
+	}
+	defer s.mu.RUnlock()
+
+	template, exists := s.templates[userID][templateID]
+	if !exists {
+		return models.Template{}, errTemplateNotFound
+	}
+	return template, nil
+}
+
+func (s *TaskStore) UpdateTemplate(ctx context.Context, userID string, templateID uint64, updated models.Template) (models.Template, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Template{}, err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.templates[userID][templateID]; !exists {
+		return models.Template{}, errTemplateNotFound
+	}
+	if s.templateNameTaken(userID, updated.Name, templateID) {
+		return models.Template{}, errTemplateExists
+	}
+	if err := s.templateLabels(userID, &updated); err != nil {
+		return models.Template{}, err
+	}
+
+	updated.ID = templateID
+	updated.UserID = userID
+	s.templates[userID][templateID] = updated
+	return updated, nil
+}
+
+func (s *TaskStore) DeleteTemplate(ctx context.Context, userID string, templateID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.templates[userID][templateID]; !exists {
+		return errTemplateNotFound
+	}
+	delete(s.templates[userID], templateID)
+	return nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"time"
+)
+
+const undoSweepInterval = time.Minute
+
+func errUndoTokenNotFound() error {
+	return fmt.Errorf("undo token: %w", apperr.ErrNotFound)
+}
+
+func (s *TaskStore) SaveUndo(ctx context.Context, token string, entry models.UndoEntry) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastUndoSweep) >= undoSweepInterval {
+		for t, e := range s.undo {
+			if !now.Before(e.ExpiresAt) {
+				delete(s.undo, t)
+			}
+		}
+		s.lastUndoSweep = now
+	}
+
+	s.undo[token] = entry
+	return nil
+}
+
+// Undo consumes token and applies its steps in reverse order as a single
+// transaction. A step fails with apperr.ErrConflict when its task has been
+// changed since, in which case nothing is applied. Tokens of other users
+// are reported as missing and left untouched. It returns the tasks that
+// were restored; tasks whose creation was undone are removed.
+func (s *TaskStore) Undo(ctx context.Context, userID, token string) ([]models.Task, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	entry, exists := s.undo[token]
+	if !exists || entry.UserID != userID {
+		return nil, errUndoTokenNotFound()
+	}
+	delete(s.undo, token)
+	if !s.now().Before(entry.ExpiresAt) {
+		return nil, errUndoTokenNotFound()
+	}
+
+	staged := make(map[uint64]models.Task, len(s.userTasks[userID]))
+	for id, task := range s.userTasks[userID] {
+		staged[id] = task
+	}
+	before := snapshotBlockers(staged)
+
+	type change struct{ before, after models.Task }
+	var changes []change
+	var removed, touched []uint64
+	seen := make(map[uint64]bool)
+	for i := len(entry.Steps) - 1; i >= 0; i-- {
+		step := entry.Steps[i]
+		current, exists := staged[step.TaskID]
+		if !exists || (!seen[step.TaskID] && current.Version != step.Version) {
+			return nil, errVersionMismatch(step.TaskID, current.Version)
+		}
+		if !seen[step.TaskID] {
+			seen[step.TaskID] = true
+			touched = append(touched, step.TaskID)
+		}
+
+		if step.Previous.ID == 0 {
+			// Subtasks added since would be left without a parent.
+			for _, t := range staged {
+				if t.ParentID == step.TaskID {
+					return nil, errVersionMismatch(step.TaskID, current.Version)
+				}
+			}
+			delete(staged, step.TaskID)
+			removed = append(removed, step.TaskID)
+			continue
+		}
+		restored := step.Previous
+		restored.ID = step.TaskID
+		restored.UserID = userID
+		restored.Version = current.Version + 1
+		restored.LabelIDs = s.existingLabels(userID, restored.LabelIDs)
+		restored.CustomFields = s.existingFieldValues(userID, restored.CustomFields)
+		restored.ProjectID = s.existingProject(userID, restored.ProjectID)
+		restored.Rank = undoneRank(staged, current, restored, step.Reorder)
+		// Checklist item IDs handed out since are not reused.
+		restored.ChecklistCounter = max(restored.ChecklistCounter, current.ChecklistCounter)
+		if current.Trashed() && !restored.Trashed() {
+			restoreSubtree(staged, step.TaskID, *current.DeletedAt)
+		}
+		if checkParent(staged, step.TaskID, restored.ParentID) != nil {
+			restored.ParentID = 0
+		}
+		restored.DependsOn = validDependencies(staged, step.TaskID, restored.DependsOn)
+		staged[step.TaskID] = restored
+		changes = append(changes, change{before: current, after: restored})
+	}
+
+	s.userTasks[userID] = staged
+	for _, ch := range changes {
+		s.recordRevision(userID, ch.before, ch.after)
+	}
+	s.removeTasks(staged, removed)
+	before.touch(staged)
+
+	tasks := make([]models.Task, 0, len(touched))
+	for _, id := range touched {
+		if task, exists := staged[id]; exists {
+			tasks = append(tasks, annotate(staged, task))
+		}
+	}
+	return tasks, nil
+}
+
+// undoneRank returns the rank of a task whose change is undone. Ranks of
+// other tasks may have been respaced or taken since, so the task keeps its
+// current place unless the change moved it, in which case it goes back to
+// its previous rank if that is still free and to the end of its list
+// otherwise.
+func undoneRank(tasks map[uint64]models.Task, current, restored models.Task, reorder bool) string {
+	if !reorder && restored.ProjectID == current.ProjectID {
+		return current.Rank
+	}
+	for _, t := range tasks {
+		if t.ID != current.ID && t.ProjectID == restored.ProjectID && t.Rank == restored.Rank {
+			return endRank(tasks, restored.ProjectID)
+		}
+	}
+	if restored.Rank == "" {
+		return endRank(tasks, restored.ProjectID)
+	}
+	return restored.Rank
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"task-backend/internal/apperr"
+	"task-backend/internal/models"
+	"task-backend/internal/query"
+)
+
+var (
+	errViewNotFound = apperr.New(apperr.ErrNotFound, apperr.CodeViewNotFound, "View not found", "no view with this id exists")
+	errViewExists   = apperr.New(apperr.ErrConflict, apperr.CodeViewExists, "View exists", "a view with this name already exists")
+)
+
+// checkView verifies the labels and project a view refers to and
+// normalizes its labels. Callers must hold s.mu.
+func (s *TaskStore) checkView(userID string, view *models.View) error {
+	if view.ProjectID != 0 {
+		if _, exists := s.projects[userID][view.ProjectID]; !exists {
+			return errUnknownProject
+		}
+	}
+	labelIDs, err := s.labelSet(userID, view.LabelIDs)
+	if err != nil {
+		return err
+	}
+	view.LabelIDs = labelIDs
+	return nil
+}
+
+// viewNameTaken reports whether another view of the user already has name,
+// ignoring case. Callers must hold s.mu.
+func (s *TaskStore) viewNameTaken(userID string, name string, exceptID uint64) bool {
+	for id, v := range s.views[userID] {
+		if id != exceptID && strings.EqualFold(v.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropViewLabel removes a deleted label from the user's views. Callers must
+// hold s.mu.
+func (s *TaskStore) dropViewLabel(userID string, labelID uint64) {
+	for id, v := range s.views[userID] {
+		var kept []uint64
+		for _, l := range v.LabelIDs {
+			if l != labelID {
+				kept = append(kept, l)
+			}
+		}
+		v.LabelIDs = kept
+		s.views[userID][id] = v
+	}
+}
+
+// moveViews points the views of a deleted project at the Inbox, which
+// received the project's tasks. Callers must hold s.mu for writing.
+func (s *TaskStore) moveViews(userID string, projectID, inbox uint64) {
+	for id, v := range s.views[userID] {
+		if v.ProjectID == projectID {
+			v.ProjectID = inbox
+			s.views[userID][id] = v
+		}
+	}
+}
+
+// renameInViews rewrites the queries of the user's views that name a label
+// or project by its old name. Callers must hold s.mu for writing.
+func (s *TaskStore) renameInViews(userID string, field query.Field, old, new string) {
+	if old == new {
+		return
+	}
+	for id, v := range s.views[userID] {
+		v.Query = query.ReplaceValues(v.Query, field, old, new)
+		s.views[userID][id] = v
+	}
+}
+
+func (s *TaskStore) CreateView(ctx context.Context, userID string, view models.View) (models.View, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.View{}, err
+	}
+	defer s.mu.Unlock()
+
+	if s.viewNameTaken(userID, view.Name, 0) {
+		return models.View{}, errViewExists
+	}
+	if err := s.checkView(userID, &view); err != nil {
+		return models.View{}, err
+	}
+
+	s.viewCounter++
+	view.ID = s.viewCounter
+	view.UserID = userID
+	if _, exists := s.views[userID]; !exists {
+		s.views[userID] = make(map[uint64]models.View)
+	}
+	s.views[userID][view.ID] = view
+	return view, nil
+}
+
+// GetViews returns the user's views sorted by name.
+func (s *TaskStore) GetViews(ctx context.Context, userID string) ([]models.View, error) {
+	if err := s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.RUnlock()
+
+	views := make([]models.View, 0, len(s.views[userID]))
+	for _, v := range s.views[userID] {
+		views = append(views, v)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
+
+func (s *TaskStore) GetView(ctx context.Context, userID string, viewID uint64) (models.View, error) {
+	if err := s.rlock(ctx); err != nil {
+		return models.View{}, err
+	}
+	defer s.mu.RUnlock()
+
+	view, exists := s.views[userID][viewID]
+	if !exists {
+		return models.View{}, errViewNotFound
+	}
+	return view, nil
+}
+
+func (s *TaskStore) UpdateView(ctx context.Context, userID string, viewID uint64, updated models.View) (models.View, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.View{}, err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.views[userID][viewID]; !exists {
+		return models.View{}, errViewNotFound
+	}
+	if s.viewNameTaken(userID, updated.Name, viewID) {
+		return models.View{}, errViewExists
+	}
+	if err := s.checkView(userID, &updated); err != nil {
+		return models.View{}, err
+	}
+
+	updated.ID = viewID
+	updated.UserID = userID
+	s.views[userID][viewID] = updated
+	return updated, nil
+}
+
+func (s *TaskStore) DeleteView(ctx context.Context, userID string, viewID uint64) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, exists := s.views[userID][viewID]; !exists {
+		return errViewNotFound
+	}
+	delete(s.views[userID], viewID)
+	return nil
+}